@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/pprof"
+	"strings"
+	"text/tabwriter"
 
 	"golang.org/x/tools/go/analysis/singlechecker"
+	"gopkg.in/yaml.v3"
 
 	"github.com/yukia3e/gcpclosecheck/internal/analyzer"
+	"github.com/yukia3e/gcpclosecheck/internal/config"
+	"github.com/yukia3e/gcpclosecheck/internal/driver"
 	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
@@ -21,10 +29,43 @@ var (
 func main() {
 	// バージョンとヘルプフラグの処理（singlecheckerの前に処理）
 	if len(os.Args) > 1 {
+		if ruleID, ok := explainArg(os.Args[1]); ok {
+			os.Exit(printExplanation(ruleID))
+		}
+
 		switch os.Args[1] {
 		case "-V", "--version":
-			printVersion()
+			if len(os.Args) > 2 && (os.Args[2] == "--json" || os.Args[2] == "-json") {
+				printVersionJSON()
+			} else {
+				printVersion()
+			}
+			os.Exit(0)
+		case "-V=json", "--version=json":
+			printVersionJSON()
+			os.Exit(0)
+		case "-gcpconfig-schema", "--gcpconfig-schema":
+			printConfigSchema()
+			os.Exit(0)
+		case "-gcpconfig-init", "--gcpconfig-init":
+			printStarterConfig()
 			os.Exit(0)
+		case "-gcpconfig-migrate", "--gcpconfig-migrate":
+			os.Exit(runConfigMigrate(os.Args[2:]))
+		case "-gcpconfig-test", "--gcpconfig-test":
+			os.Exit(runConfigTest(os.Args[2:]))
+		case "-gcplist-exceptions", "--gcplist-exceptions":
+			os.Exit(runListExceptions(os.Args[2:]))
+		case "-gcpconfig-lint", "--gcpconfig-lint":
+			os.Exit(runConfigLint(os.Args[2:]))
+		case "-gcpdiff", "--gcpdiff":
+			os.Exit(runConfigDiff(os.Args[2:]))
+		case "-gcpexplain", "--gcpexplain":
+			ruleID := ""
+			if len(os.Args) > 2 {
+				ruleID = os.Args[2]
+			}
+			os.Exit(printExplanation(ruleID))
 		case "-h", "--help", "help":
 			usage()
 			os.Exit(0)
@@ -34,9 +75,7 @@ func main() {
 	// フラグを解析する前にヘルプメッセージを設定
 	flag.Usage = usage
 
-	// Analyzerにカスタムフラグを追加（go vetとの競合を避けるため固有の名前を使用）
-	analyzer.Analyzer.Flags.Bool("gcpdebug", false, "enable GCP close check debug mode")
-	analyzer.Analyzer.Flags.String("gcpconfig", "", "path to GCP close check configuration file")
+	// カスタムフラグ（-gcpdebug, -gcpconfig, -gcpstrict, -gcptimeout 等）は analyzer パッケージで登録済み
 
 	// デバッグモードの環境変数チェック
 	if os.Getenv("GCPCLOSECHECK_DEBUG") == "1" {
@@ -44,10 +83,408 @@ func main() {
 		os.Args = append(os.Args, "-gcpdebug")
 	}
 
+	// パッケージ横断の集計が必要な場合は独自ドライバで実行する
+	if hasFlag(os.Args[1:], "gcpsummary") || hasValueFlag(os.Args[1:], "gcpmax-total") || hasFlag(os.Args[1:], "gcpmetrics") ||
+		hasValueFlag(os.Args[1:], "gcpreport") || hasValueFlag(os.Args[1:], "gcpoutput-file") ||
+		hasValueFlag(os.Args[1:], "gcpprofile-cpu") || hasValueFlag(os.Args[1:], "gcpseverity-threshold") ||
+		hasValueFlag(os.Args[1:], "gcpconcurrency") {
+		os.Exit(runWithDriver())
+	}
+
 	// singlechecker パッケージを使用して analysis フレームワークと統合
 	singlechecker.Main(analyzer.Analyzer)
 }
 
+// hasFlag は引数に真偽値フラグ（-name, --name, -name=true）が含まれるかを判定する
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name || arg == name+"=true" || arg == name+"=1" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValueFlag は引数に値付きフラグ（-name N, -name=N）が含まれるかを判定する
+func hasValueFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// runWithDriver は全パッケージを解析し、診断に続けて集計結果・メトリクスやゲート判定を出力する。
+// 診断は -gcpreport の形式で標準エラー（-gcpoutput-file 指定時はそのファイル）に書き出す。
+// 終了コードは singlechecker と同様（エラー: 1、診断あり: 3）だが、
+// -gcpseverity-threshold（既定: error）以上の重大度の診断がある場合のみ 3 を返す。
+// -gcpmax-total を指定した場合は、しきい値以上の診断の数が上限を超えたときのみ 3 を返す
+func runWithDriver() int {
+	analyzer.Analyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	printSummary := flag.Bool("gcpsummary", false, "print a rollup of all analyzed packages after the diagnostics")
+	tests := flag.Bool("test", true, "indicates whether test files should be analyzed, too")
+	topFiles := flag.Int("gcpsummary-top", driver.DefaultTopFiles, "number of files listed in the summary")
+	maxTotal := flag.Int("gcpmax-total", -1, "fail only if the number of diagnostics at or above -gcpseverity-threshold exceeds N (negative disables)")
+	threshold := flag.String("gcpseverity-threshold", analyzer.SeverityError,
+		"exit with a failure only if a diagnostic at or above this severity exists (info, warning, error)")
+	report := flag.String("gcpreport", driver.ReportText, "output format of the diagnostics (text, json)")
+	outputFile := flag.String("gcpoutput-file", "", "write the diagnostics to this file instead of stderr (parent directories are created)")
+	cpuProfile := flag.String("gcpprofile-cpu", "", "write a pprof CPU profile of the analysis run to this file")
+	concurrency := flag.Int("gcpconcurrency", runtime.GOMAXPROCS(0), "maximum number of packages analyzed in parallel")
+	flag.Parse()
+
+	if !driver.IsValidReportFormat(*report) {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: invalid -gcpreport %q (valid values: %s)\n", *report, strings.Join(driver.ReportFormats, ", "))
+		return 2
+	}
+
+	if !analyzer.IsValidSeverity(*threshold) {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: invalid -gcpseverity-threshold %q (valid values: info, warning, error)\n", *threshold)
+		return 2
+	}
+
+	if *concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: invalid -gcpconcurrency %d (must be at least 1)\n", *concurrency)
+		return 2
+	}
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	summary := driver.NewSummary()
+	stopProfile, err := startCPUProfile(*cpuProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 1
+	}
+	results, err := driver.Run(patterns, analyzer.Analyzer, driver.Options{
+		Tests:       *tests,
+		Concurrency: *concurrency,
+		Summary:     summary,
+		FactsOnly:   analyzer.ExportFacts,
+	})
+	if profileErr := stopProfile(); profileErr != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", profileErr)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", result.PkgPath, result.Err)
+			exitCode = 1
+		}
+	}
+	if err := writeReport(*outputFile, *report, results); err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 1
+	}
+
+	if *printSummary {
+		if err := summary.Write(os.Stdout, *topFiles); err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+			return 1
+		}
+	}
+
+	if hasFlag(os.Args[1:], "gcpmetrics") {
+		if err := analyzer.CollectedMetrics().Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+			return 1
+		}
+	}
+
+	// しきい値以上の診断が1つでもあれば失敗とする。-gcpmax-total を指定した場合はその数の上限で判定する
+	failing := driver.Gate{
+		Include: func(d driver.Diagnostic) bool {
+			return analyzer.SeverityAtLeast(analyzer.SeverityOf(d.Category), *threshold)
+		},
+	}
+	if *maxTotal >= 0 {
+		failing.MaxTotal = *maxTotal
+	}
+	count := failing.Count(results)
+	if *maxTotal >= 0 {
+		status := "ok"
+		if failing.Exceeded(count) {
+			status = "exceeded"
+		}
+		fmt.Printf("Total diagnostics (%s and above): %d (max %d, %s)\n", *threshold, count, *maxTotal, status)
+	}
+	if exitCode == 0 && failing.Exceeded(count) {
+		exitCode = 3
+	}
+	return exitCode
+}
+
+// startCPUProfile は path に CPU プロファイルの書き出しを開始し、停止してファイルを閉じる関数を返す。
+// path が空の場合は何もしない
+func startCPUProfile(path string) (func() error, error) {
+	if path == "" {
+		return func() error { return nil }, nil
+	}
+
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		return f.Close()
+	}, nil
+}
+
+// writeReport は診断を format の形式で出力する。
+// path が空の場合は標準エラーに、それ以外は親ディレクトリを作成したうえでファイルに書き出す
+func writeReport(path, format string, results []driver.PackageResult) error {
+	if path == "" {
+		return driver.WriteReport(os.Stderr, format, results)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := driver.WriteReport(f, format, results); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runConfigDiff は2つの設定ファイルで同じパッケージを解析し、増減した診断を出力する。
+// 引数は "old.yaml new.yaml [-path pattern] [-test=false]"
+func runConfigDiff(args []string) int {
+	fs := flag.NewFlagSet("gcpdiff", flag.ContinueOnError)
+	path := fs.String("path", ".", "package pattern of the sample project to analyze")
+	tests := fs.Bool("test", true, "indicates whether test files should be analyzed, too")
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: gcpclosecheck -gcpdiff old.yaml new.yaml [-path ./sample/...]")
+		return 2
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+
+	var results [2][]driver.PackageResult
+	for i, configPath := range args[:2] {
+		// 読み込めない設定でデフォルトにフォールバックすると差分が誤解を招くため、先に検証する
+		cfg, err := config.LoadConfig(configPath)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %s: %v\n", configPath, err)
+			return 2
+		}
+
+		if err := analyzer.Analyzer.Flags.Set("gcpconfig", configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+			return 1
+		}
+		results[i], err = driver.Run([]string{*path}, analyzer.Analyzer, driver.Options{Tests: *tests})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+			return 1
+		}
+		for _, result := range results[i] {
+			if result.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", result.PkgPath, result.Err)
+				return 1
+			}
+		}
+	}
+
+	fmt.Printf("gcpclosecheck diff: %s -> %s\n", args[0], args[1])
+	if err := driver.Diff(results[0], results[1]).Write(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfigMigrate は古いスキーマの設定ファイルを現在のスキーマに変換して書き出す。
+// 引数は "old.yaml new.yaml"
+func runConfigMigrate(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gcpclosecheck -gcpconfig-migrate old.yaml new.yaml")
+		return 2
+	}
+	if err := config.MigrateConfigFile(args[0], args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %s: %v\n", args[0], err)
+		return 1
+	}
+	fmt.Printf("gcpclosecheck: migrated %s to schema_version %d: %s\n", args[0], config.CurrentSchemaVersion, args[1])
+	return 0
+}
+
+// configTestCase は -gcpconfig-test のテストファイルに並べる1件のケース
+type configTestCase struct {
+	Name              string `yaml:"name"`
+	Code              string `yaml:"code"`
+	ExpectDiagnostics int    `yaml:"expect_diagnostics"`
+}
+
+// runConfigTest はテストファイルの各コードスニペットを設定付きで解析し、診断数が期待どおりかを出力する。
+// 引数は "tests.yaml [-gcpconfig file]"。スニペットの import はカレントディレクトリのモジュールから解決する
+func runConfigTest(args []string) int {
+	fs := flag.NewFlagSet("gcpconfig-test", flag.ContinueOnError)
+	configPath := fs.String("gcpconfig", "", "configuration file to test (default: built-in rules)")
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: gcpclosecheck -gcpconfig-test tests.yaml [-gcpconfig file]")
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *configPath != "" {
+		cfg, err := config.LoadConfig(*configPath)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gcpclosecheck: %s: %v\n", *configPath, err)
+			return 2
+		}
+	}
+
+	data, err := os.ReadFile(args[0]) // #nosec G304 -- the test file is specified by the user
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 2
+	}
+	var cases []configTestCase
+	if err := yaml.Unmarshal(data, &cases); err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %s: %v\n", args[0], err)
+		return 2
+	}
+
+	failed := 0
+	for i, tc := range cases {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+		diagnostics, err := analyzer.AnalyzeSourceWithConfig(fmt.Sprintf("case%d.go", i+1), []byte(tc.Code),
+			analyzer.AnalyzerConfig{Options: analyzer.Options{ConfigPath: *configPath}})
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			continue
+		}
+		if len(diagnostics) != tc.ExpectDiagnostics {
+			failed++
+			fmt.Printf("FAIL %s: got %d diagnostics, want %d\n", name, len(diagnostics), tc.ExpectDiagnostics)
+			for _, d := range diagnostics {
+				fmt.Printf("    %s: %s\n", d.Category, d.Message)
+			}
+			continue
+		}
+		fmt.Printf("PASS %s (%d diagnostics)\n", name, len(diagnostics))
+	}
+
+	fmt.Printf("%d passed, %d failed\n", len(cases)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runListExceptions は設定されたパッケージ例外（名前、パターン、条件タイプ、有効/無効、説明）を一覧表示する。
+// 引数は "[-gcpconfig file]"（省略時はデフォルト設定）
+func runListExceptions(args []string) int {
+	fs := flag.NewFlagSet("gcplist-exceptions", flag.ContinueOnError)
+	configPath := fs.String("gcpconfig", "", "configuration file to read (default: built-in rules)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var cfg *config.Config
+	var err error
+	if *configPath == "" {
+		cfg, err = config.DefaultConfig()
+	} else if cfg, err = config.LoadConfig(*configPath); err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 2
+	}
+
+	if len(cfg.PackageExceptions) == 0 {
+		fmt.Println("No package exceptions configured")
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATTERN\tTYPE\tENABLED\tDESCRIPTION")
+	for _, exception := range cfg.PackageExceptions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", exception.Name, exception.Pattern,
+			exception.Condition.Type, exception.Condition.Enabled, exception.Condition.Description)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfigLint は設定として有効だが適用されることのないルール（重複したサービス、包含された例外パターン）を警告する。
+// 引数は "[-gcpconfig file]"（省略時はデフォルト設定）。警告がある場合は 1 を返す
+func runConfigLint(args []string) int {
+	fs := flag.NewFlagSet("gcpconfig-lint", flag.ContinueOnError)
+	configPath := fs.String("gcpconfig", "", "configuration file to lint (default: built-in rules)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var cfg *config.Config
+	var err error
+	if *configPath == "" {
+		cfg, err = config.DefaultConfig()
+	} else if cfg, err = config.LoadConfig(*configPath); err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gcpclosecheck: %v\n", err)
+		return 2
+	}
+
+	warnings := cfg.Lint()
+	for _, warning := range warnings {
+		fmt.Printf("warning: %s\n", warning)
+	}
+	if len(warnings) > 0 {
+		return 1
+	}
+	fmt.Println("gcpclosecheck: no configuration lint warnings")
+	return 0
+}
+
 func usage() {
 	fmt.Fprintf(os.Stderr, `gcpclosecheck - %s
 
@@ -59,6 +496,32 @@ Flags:
 `, messages.ToolDescription, messages.UsageExamples, messages.RecommendedPractices)
 	flag.PrintDefaults()
 	fmt.Fprintf(os.Stderr, `
+Commands:
+  -gcpconfig-schema  Print the JSON Schema of the configuration file
+  -gcpconfig-init    Print the default configuration as a starting rules.yaml
+  -gcpconfig-migrate old.yaml new.yaml
+                     Upgrade a configuration file to the current schema_version
+  -gcpconfig-test tests.yaml [-gcpconfig file]
+                     Analyze the code snippets in tests.yaml and check their diagnostic counts
+  -gcplist-exceptions [-gcpconfig file]
+                     List the package exceptions with their pattern, type and enabled state
+  -gcpconfig-lint [-gcpconfig file]
+                     Warn about duplicate services and package exceptions shadowed by earlier patterns
+  -gcpdiff old.yaml new.yaml [-path pattern]
+                     Show diagnostics added or removed by a config change on a sample project
+  -gcpexplain <rule> Explain a diagnostic category (rule ID) with a fix example
+  -gcpsummary        Print a rollup (files, diagnostics, top files) after analyzing all packages
+  -gcpmax-total N    Fail only if the total number of diagnostics across packages exceeds N
+                     (counts diagnostics at or above -gcpseverity-threshold)
+  -gcpmetrics        Print how many resources each false-positive filter excluded
+  -gcpreport FORMAT  Output format of the diagnostics (text, json)
+  -gcpoutput-file F  Write the diagnostics to F instead of stderr
+  -gcpprofile-cpu F  Write a pprof CPU profile of the analysis run to F
+  -gcpconcurrency N  Analyze at most N packages in parallel (default GOMAXPROCS)
+  -gcpseverity-threshold LEVEL
+                     Exit with a failure only for diagnostics at or above LEVEL
+                     (info, warning, error; default error)
+
 Environment Variables:
   GCPCLOSECHECK_DEBUG=1  Enable debug mode
 
@@ -73,6 +536,68 @@ func printVersion() {
 	fmt.Printf("Go Version: %s\n", getGoVersion())
 }
 
+// versionInfo はJSON形式のバージョン出力
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildDate string `json:"buildDate"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+func printVersionJSON() {
+	info := versionInfo{
+		Version:   version,
+		BuildDate: buildDate,
+		Commit:    commitHash,
+		GoVersion: getGoVersion(),
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode version: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// explainArg は -gcpexplain=<ruleID> 形式の引数からルールIDを取り出す
+func explainArg(arg string) (string, bool) {
+	for _, prefix := range []string{"-gcpexplain=", "--gcpexplain="} {
+		if strings.HasPrefix(arg, prefix) {
+			return strings.TrimPrefix(arg, prefix), true
+		}
+	}
+	return "", false
+}
+
+// printExplanation はルールIDの説明と修正例を出力し、終了コードを返す
+func printExplanation(ruleID string) int {
+	explanation, ok := messages.Explain(ruleID)
+	if !ok {
+		fmt.Fprintf(os.Stderr, messages.UnknownRuleID+"\n", ruleID, strings.Join(messages.ExplainedRules(), ", "))
+		return 2
+	}
+	fmt.Print(explanation)
+	return 0
+}
+
+// printConfigSchema は設定ファイルの JSON Schema を出力する
+func printConfigSchema() {
+	schema, err := config.Schema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate config schema: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(schema))
+}
+
+// printStarterConfig はデフォルト設定を編集用の雛形として出力する
+func printStarterConfig() {
+	data, err := config.StarterConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate starter config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(data))
+}
+
 func getGoVersion() string {
 	return runtime.Version()
 }
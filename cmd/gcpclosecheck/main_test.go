@@ -2,12 +2,17 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/yukia3e/gcpclosecheck/internal/analyzer"
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
 // TestCLIBasicExecution tests basic CLI execution
@@ -79,6 +84,922 @@ func TestCLIVersionFlag(t *testing.T) {
 	// Whether version information is displayed depends on implementation
 }
 
+// TestCLIVersionJSON tests the machine-readable version output
+func TestCLIVersionJSON(t *testing.T) {
+	binPath, _ := buildCLI(t)
+
+	for _, args := range [][]string{{"-V=json"}, {"--version", "--json"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			out, err := exec.Command(binPath, args...).Output() // #nosec G204 -- binPath is controlled temp directory for testing
+			if err != nil {
+				t.Fatalf("Version command failed: %v", err)
+			}
+
+			var info map[string]string
+			if err := json.Unmarshal(out, &info); err != nil {
+				t.Fatalf("Version output is not valid JSON: %v\nOutput: %s", err, out)
+			}
+
+			for _, key := range []string{"version", "buildDate", "commit", "goVersion"} {
+				if info[key] == "" {
+					t.Errorf("Version JSON should contain non-empty %q, got: %s", key, out)
+				}
+			}
+			if len(info) != 4 {
+				t.Errorf("Version JSON should contain exactly 4 fields, got: %s", out)
+			}
+		})
+	}
+}
+
+// TestCLIConfigSchema tests that the config schema command emits valid JSON
+func TestCLIConfigSchema(t *testing.T) {
+	binPath, _ := buildCLI(t)
+
+	out, err := exec.Command(binPath, "-gcpconfig-schema").Output() // #nosec G204 -- binPath is controlled temp directory for testing
+	if err != nil {
+		t.Fatalf("Schema command failed: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(out, &schema); err != nil {
+		t.Fatalf("Schema output is not valid JSON: %v\nOutput: %s", err, out)
+	}
+	if !strings.Contains(string(out), "cleanup_methods") {
+		t.Errorf("Schema should describe cleanup_methods, got: %s", out)
+	}
+}
+
+// TestCLIConfigInit tests that the config init command emits a loadable configuration
+func TestCLIConfigInit(t *testing.T) {
+	binPath, _ := buildCLI(t)
+
+	out, err := exec.Command(binPath, "-gcpconfig-init").Output() // #nosec G204 -- binPath is controlled temp directory for testing
+	if err != nil {
+		t.Fatalf("Config init command failed: %v", err)
+	}
+	if !strings.Contains(string(out), "service_name: spanner") {
+		t.Errorf("Starter config should contain the default services, got: %s", out)
+	}
+}
+
+// TestCLIConfigMigrate tests that -gcpconfig-migrate writes an upgraded configuration
+func TestCLIConfigMigrate(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+
+	oldPath := filepath.Join(tmpDir, "old.yaml")
+	newPath := filepath.Join(tmpDir, "new.yaml")
+	old := `services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+`
+	if err := os.WriteFile(oldPath, []byte(old), 0644); err != nil {
+		t.Fatalf("Failed to write old config: %v", err)
+	}
+
+	if out, err := exec.Command(binPath, "-gcpconfig-migrate", oldPath, newPath).CombinedOutput(); err != nil { // #nosec G204 -- binPath is controlled temp directory for testing
+		t.Fatalf("Config migrate command failed: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(newPath) // #nosec G304 -- newPath is in the test temp directory
+	if err != nil {
+		t.Fatalf("Migrated config was not written: %v", err)
+	}
+	if !strings.Contains(string(data), "schema_version: 2") {
+		t.Errorf("Migrated config should declare the current schema_version, got: %s", data)
+	}
+
+	if err := exec.Command(binPath, "-gcpconfig-migrate", oldPath).Run(); err == nil { // #nosec G204 -- binPath is controlled temp directory for testing
+		t.Error("Expected a usage error without the output path")
+	}
+}
+
+// TestCLIListExceptions tests that -gcplist-exceptions prints the configured package exceptions
+func TestCLIListExceptions(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+
+	out, err := exec.Command(binPath, "-gcplist-exceptions").CombinedOutput() // #nosec G204 -- binPath is controlled temp directory for testing
+	if err != nil {
+		t.Fatalf("List exceptions command failed: %v\n%s", err, out)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("Expected a header and the 3 default exceptions, got:\n%s", out)
+	}
+	for i, want := range [][]string{
+		{"cmd_short_lived", "*/cmd/*", "short_lived", "true"},
+		{"cloud_functions", "**/function/**", "cloud_function", "true"},
+		{"test_files", "**/*_test.go", "test", "false"},
+	} {
+		if fields := strings.Fields(lines[i+1]); len(fields) < 4 || strings.Join(fields[:4], " ") != strings.Join(want, " ") {
+			t.Errorf("Line %d = %q, want fields %v", i+1, lines[i+1], want)
+		}
+	}
+
+	configPath := filepath.Join(tmpDir, "custom.yaml")
+	custom := `services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+package_exceptions:
+  - name: migrations
+    pattern: "**/migrations/**"
+    condition:
+      type: short_lived
+      description: One-off migrations
+      enabled: true
+`
+	if err := os.WriteFile(configPath, []byte(custom), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	out, err = exec.Command(binPath, "-gcplist-exceptions", "-gcpconfig", configPath).CombinedOutput() // #nosec G204 -- binPath is controlled temp directory for testing
+	if err != nil {
+		t.Fatalf("List exceptions command failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "migrations") || !strings.Contains(string(out), "One-off migrations") ||
+		strings.Contains(string(out), "cmd_short_lived") {
+		t.Errorf("Expected only the custom exception, got:\n%s", out)
+	}
+}
+
+// TestCLIConfigLint tests that -gcpconfig-lint warns about rules that are never applied
+func TestCLIConfigLint(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+
+	out, err := exec.Command(binPath, "-gcpconfig-lint").CombinedOutput() // #nosec G204 -- binPath is controlled temp directory for testing
+	if err != nil {
+		t.Fatalf("Default configuration should have no lint warnings: %v\n%s", err, out)
+	}
+
+	configPath := filepath.Join(tmpDir, "shadowed.yaml")
+	shadowed := `services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+  - service_name: spanner-admin
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewDatabaseAdminClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+package_exceptions:
+  - name: internal
+    pattern: "**/internal/**"
+    condition:
+      type: short_lived
+      enabled: true
+  - name: internal_functions
+    pattern: "**/internal/function/**"
+    condition:
+      type: cloud_function
+      enabled: true
+`
+	if err := os.WriteFile(configPath, []byte(shadowed), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	out, err = exec.Command(binPath, "-gcpconfig-lint", "-gcpconfig", configPath).CombinedOutput() // #nosec G204 -- binPath is controlled temp directory for testing
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit code 1 for lint warnings, got %v\n%s", err, out)
+	}
+	for _, want := range []string{"service[1](spanner-admin): package path", "package exception[1](internal_functions)"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected warning containing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+// TestCLIOutputFile tests that -gcpoutput-file writes the formatted diagnostics and keeps the exit code
+func TestCLIOutputFile(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"text", []string{"app.go:6:", "'client'"}},
+		{"json", []string{`"package": "example.com/sample/app"`, `"line": 6`, `"category": "resource-leak"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			outputPath := filepath.Join(tmpDir, "results", tt.format, "results.txt")
+			cmd := exec.Command(binPath, "-gcpconfig", configPath, "-gcpreport", tt.format, "-gcpoutput-file", outputPath, "./...") // #nosec G204 -- binPath is controlled temp directory for testing
+			cmd.Dir = sampleDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			out, err := cmd.CombinedOutput()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+				t.Fatalf("Expected exit code 3 for a detected leak, got %v\nOutput: %s", err, out)
+			}
+			if strings.Contains(string(out), "app.go:6:") {
+				t.Errorf("Diagnostics should not be written to stderr, got: %s", out)
+			}
+
+			data, err := os.ReadFile(outputPath) // #nosec G304 -- outputPath is in the test temp directory
+			if err != nil {
+				t.Fatalf("Output file was not written: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Output file should contain %q, got: %s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCLIProfileCPU(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	profilePath := filepath.Join(tmpDir, "cpu.pprof")
+	cmd := exec.Command(binPath, "-gcpconfig", configPath, "-gcpprofile-cpu", profilePath, "./...") // #nosec G204 -- binPath is controlled temp directory for testing
+	cmd.Dir = sampleDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit code 3 for a detected leak, got %v\nOutput: %s", err, out)
+	}
+	if !strings.Contains(string(out), "app.go:6:") {
+		t.Errorf("Diagnostics should still be written to stderr, got: %s", out)
+	}
+
+	info, err := os.Stat(profilePath)
+	if err != nil {
+		t.Fatalf("CPU profile was not written: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Error("CPU profile should not be empty")
+	}
+}
+
+// TestCLIConcurrency tests that analyzing one package at a time reports the same diagnostics as the default
+func TestCLIConcurrency(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	worker := `package worker
+
+import "example.com/sample/db"
+
+func Start() {
+	client, _ := db.NewClient()
+	_ = client
+}
+`
+	if err := os.MkdirAll(filepath.Join(sampleDir, "worker"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sampleDir, "worker", "worker.go"), []byte(worker), 0644); err != nil {
+		t.Fatalf("Failed to write worker.go: %v", err)
+	}
+
+	run := func(t *testing.T, extraArgs ...string) (string, int) {
+		t.Helper()
+		args := append([]string{"-gcpconfig", configPath, "-gcpreport", "text"}, extraArgs...)
+		cmd := exec.Command(binPath, append(args, "./...")...) // #nosec G204 -- binPath is controlled temp directory for testing
+		cmd.Dir = sampleDir
+		cmd.Env = append(os.Environ(), "GOFLAGS=")
+		out, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return string(out), exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatalf("Failed to run CLI: %v", err)
+		}
+		return string(out), 0
+	}
+
+	want, wantExit := run(t)
+	if wantExit != 3 || !strings.Contains(want, "app.go:6:") || !strings.Contains(want, "worker.go:6:") {
+		t.Fatalf("Expected both leaks with exit code 3, got %d\nOutput: %s", wantExit, want)
+	}
+	got, gotExit := run(t, "-gcpconcurrency", "1")
+	if gotExit != wantExit || got != want {
+		t.Errorf("-gcpconcurrency 1 = exit %d:\n%s\nwant exit %d:\n%s", gotExit, got, wantExit, want)
+	}
+
+	if out, exitCode := run(t, "-gcpconcurrency", "0"); exitCode != 2 {
+		t.Errorf("Expected exit code 2 for -gcpconcurrency 0, got %d\nOutput: %s", exitCode, out)
+	}
+}
+
+// TestCLISeverityThreshold tests that only diagnostics at or above -gcpseverity-threshold fail the run
+func TestCLISeverityThreshold(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// A closed client deferred in a loop is a warning (defer-in-loop), the leak in app.go is an error
+	loop := `package batch
+
+import "example.com/sample/db"
+
+func Run() {
+	for i := 0; i < 3; i++ {
+		client, err := db.NewClient()
+		if err != nil {
+			return
+		}
+		defer client.Close()
+	}
+}
+`
+	if err := os.MkdirAll(filepath.Join(sampleDir, "batch"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sampleDir, "batch", "batch.go"), []byte(loop), 0644); err != nil {
+		t.Fatalf("Failed to write batch.go: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		threshold string
+		pattern   string
+		wantExit  int
+	}{
+		{"warnings only, -gcpsummary without threshold", "", "./batch", 0},
+		{"warnings only, error threshold", "error", "./batch", 0},
+		{"warnings only, warning threshold", "warning", "./batch", 3},
+		{"warnings only, info threshold", "info", "./batch", 3},
+		{"warnings and errors, error threshold", "error", "./...", 3},
+		{"invalid threshold", "fatal", "./...", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-gcpconfig", configPath, "-gcpdefer-in-loop"}
+			if tt.threshold != "" {
+				args = append(args, "-gcpseverity-threshold", tt.threshold)
+			} else {
+				args = append(args, "-gcpsummary")
+			}
+			cmd := exec.Command(binPath, append(args, tt.pattern)...) // #nosec G204 -- binPath is controlled temp directory for testing
+			cmd.Dir = sampleDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			out, err := cmd.CombinedOutput()
+			exitCode := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Failed to run CLI: %v", err)
+			}
+			if exitCode != tt.wantExit {
+				t.Errorf("Expected exit code %d, got %d\nOutput: %s", tt.wantExit, exitCode, out)
+			}
+			if tt.wantExit != 2 && !strings.Contains(string(out), "batch.go:") {
+				t.Errorf("Warnings should be reported regardless of the threshold, got: %s", out)
+			}
+		})
+	}
+}
+
+// TestCLIMaxTotalWithThreshold tests that -gcpmax-total counts only diagnostics at or above -gcpseverity-threshold
+func TestCLIMaxTotalWithThreshold(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// app.go has one error (resource-leak), batch.go one warning (defer-in-loop)
+	loop := `package batch
+
+import "example.com/sample/db"
+
+func Run() {
+	for i := 0; i < 3; i++ {
+		client, err := db.NewClient()
+		if err != nil {
+			return
+		}
+		defer client.Close()
+	}
+}
+`
+	if err := os.MkdirAll(filepath.Join(sampleDir, "batch"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sampleDir, "batch", "batch.go"), []byte(loop), 0644); err != nil {
+		t.Fatalf("Failed to write batch.go: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		maxTotal  string
+		threshold string
+		wantExit  int
+		wantTotal string
+	}{
+		{"default threshold counts errors only", "1", "", 0, "Total diagnostics (error and above): 1 (max 1, ok)"},
+		{"errors above limit", "0", "error", 3, "Total diagnostics (error and above): 1 (max 0, exceeded)"},
+		{"info threshold counts warnings too", "1", "info", 3, "Total diagnostics (info and above): 2 (max 1, exceeded)"},
+		{"info threshold at limit", "2", "info", 0, "Total diagnostics (info and above): 2 (max 2, ok)"},
+		{"info threshold below limit", "3", "info", 0, "Total diagnostics (info and above): 2 (max 3, ok)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-gcpconfig", configPath, "-gcpdefer-in-loop", "-gcpmax-total", tt.maxTotal}
+			if tt.threshold != "" {
+				args = append(args, "-gcpseverity-threshold", tt.threshold)
+			}
+			cmd := exec.Command(binPath, append(args, "./...")...) // #nosec G204 -- binPath is controlled temp directory for testing
+			cmd.Dir = sampleDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			out, err := cmd.CombinedOutput()
+			exitCode := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Failed to run CLI: %v", err)
+			}
+			if exitCode != tt.wantExit {
+				t.Errorf("Expected exit code %d, got %d\nOutput: %s", tt.wantExit, exitCode, out)
+			}
+			if !strings.Contains(string(out), tt.wantTotal) {
+				t.Errorf("Expected %q, got: %s", tt.wantTotal, out)
+			}
+		})
+	}
+}
+
+// TestCLICrossPackageConstructor tests that a constructor in one package is recognized when called from another
+func TestCLICrossPackageConstructor(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	files := map[string]string{
+		"repo/repo.go": `package repo
+
+import "example.com/sample/db"
+
+// Open creates a client owned by the caller
+func Open() (*db.Client, error) {
+	return db.NewClient()
+}
+`,
+		"consumer/consumer.go": `package consumer
+
+import "example.com/sample/repo"
+
+func Leak() {
+	leaked, _ := repo.Open()
+	_ = leaked
+}
+
+func Closed() error {
+	closed, err := repo.Open()
+	if err != nil {
+		return err
+	}
+	defer closed.Close()
+	return nil
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(sampleDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"singlechecker", nil},
+		{"driver", []string{"-gcpreport", "text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-gcpconfig", configPath}, tt.args...)
+			cmd := exec.Command(binPath, append(args, "./consumer")...) // #nosec G204 -- binPath is controlled temp directory for testing
+			cmd.Dir = sampleDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			out, err := cmd.CombinedOutput()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+				t.Fatalf("Expected exit code 3 for a detected leak, got %v\nOutput: %s", err, out)
+			}
+			if !strings.Contains(string(out), "consumer.go:6:") || !strings.Contains(string(out), "'leaked'") {
+				t.Errorf("Expected the client from repo.Open to be reported, got: %s", out)
+			}
+			if strings.Contains(string(out), "'closed'") {
+				t.Errorf("The closed client should not be reported, got: %s", out)
+			}
+		})
+	}
+}
+
+// TestCLIMetricsCountOnlyTargets tests that -gcpmetrics counts only the resources of the requested packages,
+// not those of the imported packages analyzed for constructor facts
+func TestCLIMetricsCountOnlyTargets(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	files := map[string]string{
+		"repo/repo.go": `package repo
+
+import "example.com/sample/db"
+
+// Open creates a client owned by the caller
+func Open() (*db.Client, error) {
+	return db.NewClient()
+}
+
+func Leak() {
+	repoLeaked, _ := db.NewClient()
+	_ = repoLeaked
+}
+`,
+		"consumer/consumer.go": `package consumer
+
+import "example.com/sample/repo"
+
+func Leak() {
+	leaked, _ := repo.Open()
+	_ = leaked
+}
+
+func Closed() error {
+	closed, err := repo.Open()
+	if err != nil {
+		return err
+	}
+	defer closed.Close()
+	return nil
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(sampleDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cmd := exec.Command(binPath, "-gcpconfig", configPath, "-gcpmetrics", "./consumer") // #nosec G204 -- binPath is controlled temp directory for testing
+	cmd.Dir = sampleDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit code 3 for a detected leak, got %v\nOutput: %s", err, out)
+	}
+	if !strings.Contains(string(out), "gcpclosecheck metrics: 2 resources tracked") {
+		t.Errorf("Expected only the 2 clients of the consumer package to be counted, got: %s", out)
+	}
+	if strings.Contains(string(out), "repoLeaked") {
+		t.Errorf("The imported repo package should not be reported, got: %s", out)
+	}
+}
+
+// vetJSONDiagnostic is a diagnostic in the JSON tree printed by the go/analysis drivers with -json
+type vetJSONDiagnostic struct {
+	Posn           string            `json:"posn"`
+	End            string            `json:"end"`
+	Message        string            `json:"message"`
+	Category       string            `json:"category"`
+	SuggestedFixes []json.RawMessage `json:"suggested_fixes"`
+	Related        []json.RawMessage `json:"related"`
+}
+
+// decodeVetJSONTree decodes the JSON trees ({package: {analyzer: diagnostics | {"error": ...}}}) in the output,
+// skipping the "# package" header lines that go vet prints before each package
+func decodeVetJSONTree(t *testing.T, out []byte) map[string]map[string]json.RawMessage {
+	t.Helper()
+
+	var body bytes.Buffer
+	for _, line := range strings.SplitAfter(string(out), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			body.WriteString(line)
+		}
+	}
+
+	tree := make(map[string]map[string]json.RawMessage)
+	decoder := json.NewDecoder(&body)
+	for decoder.More() {
+		var packages map[string]map[string]json.RawMessage
+		if err := decoder.Decode(&packages); err != nil {
+			t.Fatalf("Output is not a go/analysis JSON tree: %v\nOutput: %s", err, out)
+		}
+		for pkg, analyzers := range packages {
+			tree[pkg] = analyzers
+		}
+	}
+	return tree
+}
+
+// TestCLIVetJSON tests that diagnostics keep the JSON envelope of go vet -json, including the rule category
+func TestCLIVetJSON(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+	sampleDir := writeSampleModule(t, tmpDir)
+	configPath := filepath.Join(tmpDir, "db.yaml")
+	if err := os.WriteFile(configPath, []byte("services:"+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+	}{
+		{"singlechecker", binPath, []string{"-json", "-gcpconfig", configPath, "./..."}},
+		{"go vet", "go", []string{"vet", "-vettool=" + binPath, "-json", "-gcpconfig=" + configPath, "./..."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(tt.command, tt.args...) // #nosec G204 -- binPath is controlled temp directory for testing
+			cmd.Dir = sampleDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			out, err := cmd.CombinedOutput()
+			// JSON 出力では診断があっても終了コードは 0
+			if err != nil {
+				t.Fatalf("Expected exit code 0 in JSON mode, got %v\nOutput: %s", err, out)
+			}
+
+			tree := decodeVetJSONTree(t, out)
+			analyzers, ok := tree["example.com/sample/app"]
+			if !ok {
+				t.Fatalf("Expected an entry for example.com/sample/app, got: %s", out)
+			}
+			raw, ok := analyzers[analyzer.Analyzer.Name]
+			if !ok {
+				t.Fatalf("Expected an entry for analyzer %q, got: %s", analyzer.Analyzer.Name, out)
+			}
+			var diagnostics []vetJSONDiagnostic
+			if err := json.Unmarshal(raw, &diagnostics); err != nil {
+				t.Fatalf("Expected a list of diagnostics for %q, got %s: %v", analyzer.Analyzer.Name, raw, err)
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %s", len(diagnostics), raw)
+			}
+
+			d := diagnostics[0]
+			if !strings.Contains(d.Posn, "app.go:6:") {
+				t.Errorf("posn = %q, want the position of the leaked client in app.go", d.Posn)
+			}
+			if !strings.Contains(d.Message, "'client'") {
+				t.Errorf("message = %q, want it to name the leaked client", d.Message)
+			}
+			// 重大度は JSON の形式に含まれないため、カテゴリから復元できることを確認する
+			if d.Category != analyzer.CategoryResourceLeak {
+				t.Errorf("category = %q, want %q", d.Category, analyzer.CategoryResourceLeak)
+			}
+			if severity := analyzer.SeverityOf(d.Category); severity != analyzer.SeverityError {
+				t.Errorf("severity of %q = %q, want %q", d.Category, severity, analyzer.SeverityError)
+			}
+		})
+	}
+}
+
+// writeSampleModule writes a self-contained module whose app package leaks a client of the local db package
+func writeSampleModule(t *testing.T, tmpDir string) string {
+	t.Helper()
+
+	sampleDir := filepath.Join(tmpDir, "sample")
+	files := map[string]string{
+		"go.mod": "module example.com/sample\n\ngo 1.21\n",
+		"db/db.go": `package db
+
+type Client struct{}
+
+func NewClient() (*Client, error) { return &Client{}, nil }
+
+func (c *Client) Close() error { return nil }
+`,
+		"app/app.go": `package app
+
+import "example.com/sample/db"
+
+func Run() {
+	client, _ := db.NewClient()
+	_ = client
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(sampleDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return sampleDir
+}
+
+// sampleDBRule is a service rule for the db package of writeSampleModule
+const sampleDBRule = `
+  - service_name: db
+    package_path: example.com/sample/db
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+`
+
+// TestCLIConfigDiff tests that -gcpdiff reports leaks detected only by the new configuration
+func TestCLIConfigDiff(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+
+	// A self-contained sample module whose client package is added as a service by new.yaml
+	sampleDir := writeSampleModule(t, tmpDir)
+
+	spannerRule := `
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+`
+	oldConfig := filepath.Join(tmpDir, "old.yaml")
+	newConfig := filepath.Join(tmpDir, "new.yaml")
+	if err := os.WriteFile(oldConfig, []byte("services:"+spannerRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(newConfig, []byte("services:"+spannerRule+sampleDBRule), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cmd := exec.Command(binPath, "-gcpdiff", oldConfig, newConfig, "-path", "./...") // #nosec G204 -- binPath is controlled temp directory for testing
+	cmd.Dir = sampleDir
+	// The sample module has no dependencies; do not inherit module flags from the caller
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Diff command failed: %v\nOutput: %s", err, out)
+	}
+	if !strings.Contains(string(out), "+ ") || !strings.Contains(string(out), "app.go:6:") {
+		t.Errorf("Diff should show the newly detected leak in app.go, got: %s", out)
+	}
+	if !strings.Contains(string(out), "1 added, 0 removed") {
+		t.Errorf("Diff should count one added diagnostic, got: %s", out)
+	}
+}
+
+// TestCLIExplain tests rule explanations printed by -gcpexplain
+func TestCLIConfigTest(t *testing.T) {
+	binPath, tmpDir := buildCLI(t)
+
+	// スニペットの import はカレントディレクトリのモジュールから解決されるため、spanner のスタブを replace で用意する
+	moduleDir := filepath.Join(tmpDir, "rules")
+	files := map[string]string{
+		"go.mod": `module example.com/rules
+
+go 1.24
+
+require cloud.google.com/go/spanner v0.0.0
+
+replace cloud.google.com/go/spanner => ./spanner
+`,
+		"spanner/go.mod": `module cloud.google.com/go/spanner
+
+go 1.24
+`,
+		"spanner/spanner.go": `package spanner
+
+import "context"
+
+type Client struct{}
+
+func NewClient(ctx context.Context, database string) (*Client, error) { return &Client{}, nil }
+
+func (c *Client) Close() {}
+`,
+		"tests.yaml": `- name: spanner leak
+  code: |
+    package app
+
+    import (
+    	"context"
+
+    	"cloud.google.com/go/spanner"
+    )
+
+    func run(ctx context.Context) {
+    	client, _ := spanner.NewClient(ctx, "db")
+    	_ = client
+    }
+  expect_diagnostics: 1
+- code: |
+    package app
+
+    import (
+    	"context"
+
+    	"cloud.google.com/go/spanner"
+    )
+
+    func run(ctx context.Context) {
+    	client, _ := spanner.NewClient(ctx, "db")
+    	defer client.Close()
+    }
+  expect_diagnostics: 1
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(moduleDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cmd := exec.Command(binPath, "-gcpconfig-test", "tests.yaml") // #nosec G204 -- binPath is controlled temp directory for testing
+	cmd.Dir = moduleDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit code 1 for a failing case, got %v\nOutput: %s", err, out)
+	}
+	for _, want := range []string{
+		"PASS spanner leak (1 diagnostics)",
+		"FAIL case 2: got 0 diagnostics, want 1",
+		"1 passed, 1 failed",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Output should contain %q, got: %s", want, out)
+		}
+	}
+}
+
+func TestCLIExplain(t *testing.T) {
+	binPath, _ := buildCLI(t)
+
+	for _, rule := range messages.ExplainedRules() {
+		for _, args := range [][]string{{"-gcpexplain", rule}, {"-gcpexplain=" + rule}} {
+			t.Run(strings.Join(args, " "), func(t *testing.T) {
+				out, err := exec.Command(binPath, args...).Output() // #nosec G204 -- binPath is controlled temp directory for testing
+				if err != nil {
+					t.Fatalf("Explain command failed: %v", err)
+				}
+				if !strings.HasPrefix(string(out), rule+":") {
+					t.Errorf("Explanation should start with %q, got: %s", rule, out)
+				}
+			})
+		}
+	}
+
+	cmd := exec.Command(binPath, "-gcpexplain", "no-such-rule") // #nosec G204 -- binPath is controlled temp directory for testing
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Unknown rule should fail, got: %s", out)
+	}
+	if !strings.Contains(string(out), "resource-leak") {
+		t.Errorf("Unknown rule error should list known rules, got: %s", out)
+	}
+}
+
 // TestCLIAnalysisExecution tests actual analysis execution
 func TestCLIAnalysisExecution(t *testing.T) {
 	binPath, tmpDir := buildCLI(t)
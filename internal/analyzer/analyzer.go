@@ -1,21 +1,41 @@
 package analyzer
 
 import (
+	"context"
+	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/config"
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
+// IgnoreDirective は関数の doc コメントに書くと、その関数の診断をすべて抑制するディレクティブ
+const IgnoreDirective = "//gcpclosecheck:ignore"
+
 // Analyzer は GCP リソースの解放漏れを検出する静的解析ツール
 var Analyzer = &analysis.Analyzer{
 	Name: "gcpclosecheck",
 	Doc:  "detect missing Close/Stop/Cancel calls for GCP resources",
 	Run:  run,
+	// 依存パッケージのコンストラクタ（リソースを生成して返す公開関数）をパッケージを跨いで伝える
+	FactTypes: []analysis.Fact{new(ReturnsResourceFact)},
 }
 
 // run は解析のメイン実行関数
 func run(pass *analysis.Pass) (interface{}, error) {
+	return runWithOptions(pass, analyzerOptions)
+}
+
+// runWithOptions は指定されたオプションで解析を実行する
+func runWithOptions(pass *analysis.Pass, opts Options) (interface{}, error) {
 	// 型チェックエラーの確認
 	if len(pass.TypeErrors) > 0 {
 		// 型エラーがある場合は警告を出力して解析をスキップ
@@ -37,74 +57,613 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 	}
 
-	// 各コンポーネントを初期化
+	// 各コンポーネントを初期化（設定ファイル未指定時はデフォルトルール）
 	serviceRuleEngine := NewServiceRuleEngine()
-	if err := serviceRuleEngine.LoadDefaultRules(); err != nil {
+	if err := serviceRuleEngine.LoadRules(opts.ConfigPath); err != nil {
+		return nil, err
+	}
+	if err := validateIncludedServices(opts.Include, serviceRuleEngine); err != nil {
 		return nil, err
 	}
 
-	// パッケージ例外判定を実行
-	packagePath := getPackagePath(pass)
-	shouldExempt, exemptReason := serviceRuleEngine.ShouldExemptPackage(packagePath)
+	// 他パッケージの解析で使うため、例外対象のパッケージでもコンストラクタの Fact は付与する
+	resourceTracker := NewResourceTracker(pass.TypesInfo, serviceRuleEngine)
+	resourceTracker.SetFactImporter(pass.ImportObjectFact)
+	exportResourceFacts(pass, resourceTracker)
+
+	// パッケージ例外判定を実行（strict モードでは例外を一切適用しない）
+	shouldExempt, exemptReason := false, ""
+	if !opts.Strict {
+		packagePath := getPackagePath(pass)
+		shouldExempt, exemptReason = serviceRuleEngine.ShouldExemptPackage(packagePath)
+
+		// パッケージが例外対象でない場合、ファイルパスベースの例外判定も実行
+		if !shouldExempt {
+			shouldExempt, exemptReason = checkFileBasedExemptions(pass, serviceRuleEngine)
+		}
+	}
 
-	// パッケージが例外対象でない場合、ファイルパスベースの例外判定も実行
-	if !shouldExempt {
-		shouldExempt, exemptReason = checkFileBasedExemptions(pass, serviceRuleEngine)
+	// 短命プログラム例外（cmd 等）でも、長時間動く関数で生成した管理クライアントは検査する
+	// （マイグレーションツールなどが接続を使い果たすおそれがある）
+	longRunningOnly := false
+	if shouldExempt && serviceRuleEngine.ExemptionTypeForPackage(getPackagePath(pass)) == config.ExceptionTypeShortLived {
+		shouldExempt, longRunningOnly = false, true
 	}
 
 	// パッケージまたはファイルが例外対象の場合は診断を生成せずに終了
 	if shouldExempt {
 		// デバッグログ出力（将来的にログレベル制御可能にする）
 		_ = exemptReason // 例外理由を記録（後でログ出力に使用）
+		if opts.Metrics != nil {
+			recordExemptedResources(pass, serviceRuleEngine, opts)
+		}
 		return nil, nil
 	}
 
-	resourceTracker := NewResourceTracker(pass.TypesInfo, serviceRuleEngine)
+	// 解析時間の上限（トップレベル宣言の間で確認する）
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
 	deferAnalyzer := NewDeferAnalyzer(resourceTracker)
+	// 解放漏れの診断には、スコープ内の context を渡す defer 文追加の修正提案を付ける
+	diagnosticGenerator := NewDiagnosticGenerator(pass.Fset)
+	diagnosticGenerator.SetPackage(pass.Pkg)
+	diagnosticGenerator.SetFiles(pass.Files)
+	deferAnalyzer.SetDiagnosticGenerator(diagnosticGenerator)
+	if opts.Finalizer {
+		deferAnalyzer.EnableFinalizerCheck()
+	}
+	if opts.CleanupOrder {
+		deferAnalyzer.EnableCleanupOrderCheck()
+	}
+	if opts.DeferInLoop {
+		deferAnalyzer.EnableDeferInLoopCheck()
+	}
+	if opts.ExitSkipsDefer {
+		deferAnalyzer.EnableExitCallCheck()
+	}
+	if opts.CreationError {
+		deferAnalyzer.EnableCreationErrorCheck()
+	}
+	if opts.ConditionalDefer {
+		deferAnalyzer.EnableConditionalDeferCheck()
+	}
+	if opts.UnusedResource {
+		deferAnalyzer.EnableUnusedResourceCheck()
+	}
+	if opts.CleanupError {
+		deferAnalyzer.EnableCleanupErrorCheck()
+	}
 	contextAnalyzer := NewContextAnalyzer()
+	if opts.DuplicateCancel {
+		contextAnalyzer.EnableDuplicateDeferCheck()
+	}
+	if opts.ContextPropagation {
+		contextAnalyzer.EnablePropagationCheck()
+	}
+	if opts.AfterFuncStop {
+		contextAnalyzer.EnableAfterFuncCheck()
+	}
+	sliceAnalyzer := NewResourceSliceAnalyzer(resourceTracker, pass.TypesInfo)
 	escapeAnalyzer := NewEscapeAnalyzer()
+	escapeAnalyzer.SetClosureManagedHelpers(serviceRuleEngine.ClosureManagedHelpers())
+	escapeAnalyzer.SetFieldAssignmentCheckedServices(serviceRuleEngine.FieldAssignmentCheckedServices())
+	escapeAnalyzer.SetAppLifetimeServices(serviceRuleEngine.AppLifetimeServices())
+	escapeAnalyzer.SetTypesInfo(pass.TypesInfo)
 
 	// ResourceTracker でリソース生成を検出
-	resources := resourceTracker.FindResourceCreation(pass)
+	resources, err := resourceTracker.FindResourceCreationContext(ctx, pass)
+	if err != nil {
+		reportAnalysisTimeout(pass, opts.Timeout)
+		return nil, nil
+	}
+	resources = filterResourcesByService(resources, opts.Include)
+	if longRunningOnly {
+		resources = filterLongRunningAdminClients(resources, serviceRuleEngine, pass, opts.Metrics)
+	}
+
+	// pure_packages のパッケージは所有権が常に外部にあるため、生成は追跡するが報告しない（strict モードでは適用しない）
+	if !opts.Strict && serviceRuleEngine.IsPurePackage(getPackagePath(pass)) {
+		if opts.Metrics != nil {
+			for _, resource := range resources {
+				opts.Metrics.record(pass.Fset.Position(resource.CreationPos), decisionExempted)
+			}
+		}
+		return nil, nil
+	}
+
+	// 生成コード（モック等）は generated_file_policy に従う（strict モードでは適用しない）
+	generatedPolicy := ""
+	if !opts.Strict {
+		generatedPolicy = serviceRuleEngine.GeneratedFilePolicy()
+	}
+	skippedFiles := generatedFilesToSkip(pass, generatedPolicy)
+	// //gcpclosecheck:ignore を doc コメントに持つ関数は報告しない（strict モードでは適用しない）
+	var ignoredFuncs []*ast.FuncDecl
+	if !opts.Strict {
+		ignoredFuncs = ignoredFunctions(pass)
+	}
+
+	// サービスルールのない GCP パッケージの import を通知（オプション、短命プログラム例外のパッケージでは行わない）
+	if opts.WarnUnknown && !longRunningOnly {
+		for _, diagnostic := range unknownGCPPackageDiagnostics(pass, resourceTracker) {
+			if !skippedFiles[pass.Fset.File(diagnostic.Pos)] {
+				pass.Report(diagnostic)
+			}
+		}
+	}
+
+	// リソースを保持するフィールドを解放しない構造体を警告（オプション、短命プログラム例外のパッケージでは行わない）
+	if opts.StructLifecycle && !longRunningOnly {
+		for _, diagnostic := range NewStructLifecycleAnalyzer(resourceTracker).FindUnclosedFields(pass) {
+			if !skippedFiles[pass.Fset.File(diagnostic.Pos)] {
+				pass.Report(diagnostic)
+			}
+		}
+	}
+
+	// ContextAnalyzer でコンテキストキャンセレーション問題を検出（短命プログラム例外のパッケージでは行わない）
+	var contextDiagnostics []analysis.Diagnostic
+	if !longRunningOnly {
+		contextDiagnostics = contextAnalyzer.FindMissingCancels(pass)
+	}
 
-	// ContextAnalyzer でコンテキストキャンセレーション問題を検出
-	contextDiagnostics := contextAnalyzer.FindMissingCancels(pass)
+	// Pub/Sub の Receive ハンドラで Ack/Nack されない経路を検出（オプション）
+	if opts.PubSubAck && !longRunningOnly {
+		contextDiagnostics = append(contextDiagnostics, NewMessageAckAnalyzer().FindUnackedMessages(pass)...)
+	}
 
 	// 診断レポート
 	for _, diagnostic := range contextDiagnostics {
+		if skippedFiles[pass.Fset.File(diagnostic.Pos)] || isInFunctions(ignoredFuncs, diagnostic.Pos) {
+			continue
+		}
 		pass.Report(diagnostic)
 	}
 
 	// 各ファイルを解析
 	for _, file := range pass.Files {
+		generated := generatedPolicy != "" && ast.IsGenerated(file)
+		testFile := !opts.Strict && strings.HasSuffix(pass.Fset.Position(file.Pos()).Filename, "_test.go")
+
 		// 各関数を解析
-		ast.Inspect(file, func(n ast.Node) bool {
-			if fn, ok := n.(*ast.FuncDecl); ok {
-				if fn.Body != nil {
-					// 関数内のリソースを収集・フィルタリング
-					functionResources := collectAndFilterFunctionResources(
-						resources, fn, pass, escapeAnalyzer)
-
-					// 自動管理リソースの最終フィルタリング
-					functionResources = applyAutoManagedResourceFiltering(
-						functionResources, resourceTracker)
-
-					// DeferAnalyzer で関数全体を検証（リソース情報を渡す）
-					if len(functionResources) > 0 {
-						diagnostics := deferAnalyzer.AnalyzeDefers(fn, functionResources)
-						for _, diagnostic := range diagnostics {
-							pass.Report(diagnostic)
-						}
+		for _, decl := range file.Decls {
+			if ctx.Err() != nil {
+				reportAnalysisTimeout(pass, opts.Timeout)
+				return nil, nil
+			}
+
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+
+			// 関数内のリソースを収集・フィルタリング
+			functionResources := collectAndFilterFunctionResources(
+				resources, fn, pass, escapeAnalyzer, opts.Strict, opts.Metrics)
+
+			// 自動管理リソースの最終フィルタリング
+			if !opts.Strict {
+				functionResources = applyAutoManagedResourceFiltering(
+					functionResources, resourceTracker)
+			}
+
+			// ファイナライザ任せのリソースはエスケープしていても警告対象に戻す
+			if opts.Finalizer && !opts.Strict {
+				functionResources = appendFinalizerResources(
+					functionResources, resources, fn, pass, deferAnalyzer)
+			}
+
+			// //gcpclosecheck:ignore が付いた関数のリソースはスキップとして記録するだけにする
+			if isInFunctions(ignoredFuncs, fn.Pos()) {
+				for _, resource := range functionResources {
+					opts.Metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonIgnoreDirective)
+				}
+				continue
+			}
+
+			// applies_to_test_files: false のサービスはテストファイル内のリソースを報告しない
+			if testFile {
+				functionResources = filterTestFileResources(functionResources, serviceRuleEngine, pass, opts.Metrics)
+			}
+
+			// context_cancelled_cleanup のサービスは、cancel を defer した context で生成したリソースを解放済みとみなす
+			functionResources = filterContextCancelledResources(functionResources, fn, serviceRuleEngine, contextAnalyzer, pass, opts.Metrics)
+
+			// required_after のある解放メソッド（pubsub の Topic の Stop 等）は、そのメソッドを呼んだリソースのみ検査する
+			functionResources = filterRequiredAfterResources(functionResources, fn, pass, opts.Metrics)
+
+			// 生成コード内のリソースは解析するが報告しない
+			if generated && generatedPolicy == config.GeneratedFilePolicySkip {
+				for _, resource := range functionResources {
+					opts.Metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonGeneratedSkip)
+				}
+				continue
+			}
+
+			// init 関数内のリソースは analyze_init の設定に従う
+			initMode := ""
+			if isInitFunc(fn) {
+				initMode = serviceRuleEngine.InitAnalysisMode()
+				if initMode == config.AnalyzeInitSkip {
+					for _, resource := range functionResources {
+						opts.Metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonInitSkip)
 					}
+					continue
 				}
 			}
-			return true
-		})
+
+			// リソースのスライスを返す呼び出しは要素ごとの解放の有無のみ検査する（オプション、短命プログラム例外のパッケージでは行わない）。
+			// 単独のリソースと同じくサービスの指定・テストファイル・context のキャンセルによる除外を適用する
+			if opts.ResourceSlice && !longRunningOnly {
+				filter := func(candidates []ResourceInfo) []ResourceInfo {
+					candidates = filterResourcesByService(candidates, opts.Include)
+					if testFile {
+						candidates = filterTestFileResources(candidates, serviceRuleEngine, pass, nil)
+					}
+					return filterContextCancelledResources(candidates, fn, serviceRuleEngine, contextAnalyzer, pass, nil)
+				}
+				for _, diagnostic := range sliceAnalyzer.FindUnclosedSlices(fn, filter) {
+					if opts.DiagPos == DiagPosScopeEnd {
+						diagnostic.Pos = scopeEndPos(fn, diagnostic.Pos)
+						diagnostic.End = diagnostic.Pos
+					}
+					pass.Report(diagnostic)
+				}
+			}
+
+			// DeferAnalyzer で関数全体を検証（リソース情報を渡す）
+			if len(functionResources) > 0 {
+				diagnostics := deferAnalyzer.AnalyzeDefers(fn, functionResources)
+				for _, diagnostic := range diagnostics {
+					if opts.DiagPos == DiagPosScopeEnd && diagnostic.Category == CategoryResourceLeak {
+						diagnostic.Pos = scopeEndPos(fn, diagnostic.Pos)
+						diagnostic.End = diagnostic.Pos
+					}
+					if initMode == config.AnalyzeInitWarn && diagnostic.Category == CategoryResourceLeak {
+						diagnostic.Category = CategoryInitResourceLeak
+						diagnostic.Message += messages.InitLeakSuffix
+					}
+					if generated && diagnostic.Category == CategoryResourceLeak {
+						diagnostic.Category = CategoryGeneratedLeak
+						diagnostic.Message += messages.GeneratedLeakSuffix
+					}
+					pass.Report(diagnostic)
+				}
+				recordCheckedResources(opts.Metrics, pass, functionResources, diagnostics)
+			}
+		}
 	}
 
 	return nil, nil
 }
 
+// scopeEndPos は pos を囲むブロックの閉じ括弧の位置（case 節の場合は節の終わり）を返す。
+// 解放漏れの診断を defer を置くべきスコープの終わりに報告するために使う
+func scopeEndPos(fn *ast.FuncDecl, pos token.Pos) token.Pos {
+	switch block := findEnclosingBlock(fn.Body, pos).(type) {
+	case *ast.BlockStmt:
+		return block.Rbrace
+	case *ast.CaseClause, *ast.CommClause:
+		return block.End()
+	}
+	return pos
+}
+
+// recordExemptedResources はパッケージ/ファイル例外で解析しなかったリソースを集計に記録する
+func recordExemptedResources(pass *analysis.Pass, serviceRuleEngine *ServiceRuleEngine, opts Options) {
+	resources, err := NewResourceTracker(pass.TypesInfo, serviceRuleEngine).FindResourceCreationContext(context.Background(), pass)
+	if err != nil {
+		return
+	}
+	for _, resource := range filterResourcesByService(resources, opts.Include) {
+		opts.Metrics.record(pass.Fset.Position(resource.CreationPos), decisionExempted)
+	}
+}
+
+// recordCheckedResources は DeferAnalyzer で検証したリソースを、診断の有無に応じて集計に記録する
+func recordCheckedResources(metrics *Metrics, pass *analysis.Pass, resources []ResourceInfo, diagnostics []analysis.Diagnostic) {
+	if metrics == nil {
+		return
+	}
+	reported := make(map[token.Pos]bool, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		reported[diagnostic.Pos] = true
+	}
+	for _, resource := range resources {
+		decision := decisionReleased
+		if reported[resource.CreationPos] {
+			decision = decisionReported
+		}
+		metrics.record(pass.Fset.Position(resource.CreationPos), decision)
+	}
+}
+
+// generatedFilesToSkip は generated_file_policy: skip の場合に報告しない生成コードのファイルを返す
+func generatedFilesToSkip(pass *analysis.Pass, policy string) map[*token.File]bool {
+	skipped := make(map[*token.File]bool)
+	if policy != config.GeneratedFilePolicySkip {
+		return skipped
+	}
+	for _, file := range pass.Files {
+		if ast.IsGenerated(file) {
+			skipped[pass.Fset.File(file.Pos())] = true
+		}
+	}
+	return skipped
+}
+
+// ignoredFunctions は doc コメントに //gcpclosecheck:ignore ディレクティブを持つ関数を返す
+func ignoredFunctions(pass *analysis.Pass) []*ast.FuncDecl {
+	var ignored []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && hasIgnoreDirective(fn.Doc) {
+				ignored = append(ignored, fn)
+			}
+		}
+	}
+	return ignored
+}
+
+// hasIgnoreDirective はコメントに //gcpclosecheck:ignore（後ろに理由を続けてもよい）があるかを判定する
+func hasIgnoreDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		rest, ok := strings.CutPrefix(comment.Text, IgnoreDirective)
+		if ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// isInFunctions は pos がいずれかの関数宣言の範囲内かを判定する
+func isInFunctions(funcs []*ast.FuncDecl, pos token.Pos) bool {
+	for _, fn := range funcs {
+		if fn.Pos() <= pos && pos < fn.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTestFileResources はテストファイルを検査対象外にしたサービスのリソースを除外する
+func filterTestFileResources(resources []ResourceInfo, serviceRuleEngine *ServiceRuleEngine, pass *analysis.Pass, metrics *Metrics) []ResourceInfo {
+	var filtered []ResourceInfo
+	for _, resource := range resources {
+		if !serviceRuleEngine.AppliesToTestFiles(resource.ServiceType) {
+			metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonTestFileSkip)
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// filterContextCancelledResources は context_cancelled_cleanup のサービスのリソースのうち、
+// 生成時に cancel が defer された派生 context を渡したものを除外し、スキップとして集計に記録する
+func filterContextCancelledResources(resources []ResourceInfo, fn *ast.FuncDecl, serviceRuleEngine *ServiceRuleEngine, contextAnalyzer *ContextAnalyzer, pass *analysis.Pass, metrics *Metrics) []ResourceInfo {
+	var filtered []ResourceInfo
+	for _, resource := range resources {
+		if serviceRuleEngine.IsContextCancelledCleanup(resource.ServiceType) {
+			if ctxVar := creationContextArg(fn, resource, pass.TypesInfo); ctxVar != nil && contextAnalyzer.IsCancelledContextAt(ctxVar, resource.CreationPos) {
+				metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonContextCancel)
+				continue
+			}
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// filterRequiredAfterResources は required_after のメソッド（Topic の Publish 等）を関数内で一度も呼んでいないリソースを除外し、
+// スキップとして集計に記録する。Publish しない Topic はパブリッシャーの goroutine を起動しないため Stop は不要
+func filterRequiredAfterResources(resources []ResourceInfo, fn *ast.FuncDecl, pass *analysis.Pass, metrics *Metrics) []ResourceInfo {
+	var filtered []ResourceInfo
+	for _, resource := range resources {
+		if len(resource.RequiredAfter) > 0 && !callsMethodOn(fn.Body, resource.Variable, resource.RequiredAfter, pass.TypesInfo) {
+			metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonRequiredAfter)
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// callsMethodOn は body 内で variable をレシーバに methods のいずれかを呼んでいるかを判定する
+func callsMethodOn(body *ast.BlockStmt, variable *types.Var, methods []string, typeInfo *types.Info) bool {
+	// 代入先の変数を特定できないリソースは判定できないため、呼んでいるとみなして検査対象に残す
+	if variable == nil || variable.Name() == "" || typeInfo == nil {
+		return true
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok || !slices.Contains(methods, sel.Sel.Name) {
+			return true
+		}
+		if ident, ok := ast.Unparen(sel.X).(*ast.Ident); ok && typeInfo.Uses[ident] == variable {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// creationContextArg はリソースを生成した呼び出しに context.Context として渡された変数を返す
+func creationContextArg(fn *ast.FuncDecl, resource ResourceInfo, typeInfo *types.Info) types.Object {
+	if typeInfo == nil {
+		return nil
+	}
+
+	var ctxVar types.Object
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if ctxVar != nil {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || call.Pos() != resource.CreationPos {
+			return true
+		}
+		for _, arg := range call.Args {
+			ident, ok := ast.Unparen(arg).(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if obj := typeInfo.Uses[ident]; obj != nil && isContextType(obj.Type()) {
+				ctxVar = obj
+				break
+			}
+		}
+		return false
+	})
+	return ctxVar
+}
+
+// longRunningCallThreshold はループがなくても関数を長時間動くとみなす、クライアントの操作回数
+const longRunningCallThreshold = 50
+
+// filterLongRunningAdminClients は短命プログラム例外のパッケージで、管理 API のクライアント（service_type: admin）のうち
+// 生成した関数がクライアントを繰り返し操作するもののみを残す。それ以外のリソースは例外対象として集計に記録する
+func filterLongRunningAdminClients(resources []ResourceInfo, serviceRuleEngine *ServiceRuleEngine, pass *analysis.Pass, metrics *Metrics) []ResourceInfo {
+	var funcs []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+
+	var filtered []ResourceInfo
+	for _, resource := range resources {
+		if !serviceRuleEngine.IsAdminService(resource.ServiceType) ||
+			!operatesRepeatedly(enclosingFuncDecl(funcs, resource.CreationPos), resource.Variable, pass.TypesInfo) {
+			metrics.record(pass.Fset.Position(resource.CreationPos), decisionExempted)
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// enclosingFuncDecl は pos を含む関数宣言を返す
+func enclosingFuncDecl(funcs []*ast.FuncDecl, pos token.Pos) *ast.FuncDecl {
+	for _, fn := range funcs {
+		if fn.Pos() <= pos && pos < fn.End() {
+			return fn
+		}
+	}
+	return nil
+}
+
+// operatesRepeatedly は関数がループの中で variable のメソッドを呼ぶか、
+// variable のメソッドを longRunningCallThreshold 回以上呼ぶかを判定する。
+// クライアントと無関係なループや呼び出しは数えない
+func operatesRepeatedly(fn *ast.FuncDecl, variable *types.Var, typeInfo *types.Info) bool {
+	if fn == nil || variable == nil || typeInfo == nil {
+		return false
+	}
+	if countMethodCallsOn(fn.Body, variable, typeInfo) >= longRunningCallThreshold {
+		return true
+	}
+	inLoop := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.ForStmt:
+			inLoop = countMethodCallsOn(stmt.Body, variable, typeInfo) > 0
+		case *ast.RangeStmt:
+			inLoop = countMethodCallsOn(stmt.Body, variable, typeInfo) > 0
+		}
+		return !inLoop
+	})
+	return inLoop
+}
+
+// countMethodCallsOn は node 内で variable をレシーバにしたメソッド呼び出しの数を返す
+func countMethodCallsOn(node ast.Node, variable *types.Var, typeInfo *types.Info) int {
+	count := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+			if ident, ok := ast.Unparen(sel.X).(*ast.Ident); ok && typeInfo.Uses[ident] == variable {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// gcpPackageRoot は GCP クライアントライブラリのモジュールパス
+const gcpPackageRoot = "cloud.google.com/go/"
+
+// resourceFreeGCPPackages は解放の必要なリソースを持たない GCP パッケージ（-gcpwarn-unknown の対象外）
+var resourceFreeGCPPackages = []string{
+	"cloud.google.com/go/civil",
+	"cloud.google.com/go/compute/metadata",
+}
+
+// unknownGCPPackageDiagnostics はサービスルールに一致しない cloud.google.com/go パッケージの import を情報として返す。
+// protobuf のメッセージ定義（*pb）パッケージはクライアントを持たないため対象外にする
+func unknownGCPPackageDiagnostics(pass *analysis.Pass, resourceTracker *ResourceTracker) []analysis.Diagnostic {
+	var diagnostics []analysis.Diagnostic
+	for _, file := range pass.Files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || !strings.HasPrefix(path, gcpPackageRoot) ||
+				strings.HasSuffix(path, "pb") || slices.Contains(resourceFreeGCPPackages, path) {
+				continue
+			}
+			if known, _ := resourceTracker.GetPackageInfo(path); known {
+				continue
+			}
+			diagnostics = append(diagnostics, analysis.Diagnostic{
+				Pos:      spec.Path.Pos(),
+				End:      spec.Path.End(),
+				Category: CategoryUnknownGCPPackage,
+				Message:  fmt.Sprintf(messages.UnknownGCPPackage, path),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// reportAnalysisTimeout は解析時間の上限を超えたため残りの解析を打ち切ったことを通知する
+func reportAnalysisTimeout(pass *analysis.Pass, timeout time.Duration) {
+	pos := token.NoPos
+	if len(pass.Files) > 0 {
+		pos = pass.Files[0].Pos()
+	}
+	pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		Category: CategoryAnalysisTimeout,
+		Message:  fmt.Sprintf(messages.AnalysisTimeout, timeout, getPackagePath(pass)),
+	})
+}
+
+// isInitFunc は関数がパッケージの init 関数かどうかを判定する
+func isInitFunc(fn *ast.FuncDecl) bool {
+	return fn.Recv == nil && fn.Name != nil && fn.Name.Name == "init"
+}
+
 // isResourceInFunction は指定されたリソースが関数内で生成されたかどうかを判定する
 func isResourceInFunction(resource ResourceInfo, fn *ast.FuncDecl, pass *analysis.Pass) bool {
 	if fn.Body == nil {
@@ -185,11 +744,15 @@ func convertFromPointerSlice(resources []*ResourceInfo) []ResourceInfo {
 }
 
 // collectAndFilterFunctionResources は関数内のリソースを収集しフィルタリングする
+// strict が true の場合はエスケープ判定を行わず、関数内の全リソースを対象とする
+// metrics が nil でない場合はスキップしたリソースを理由とともに記録する
 func collectAndFilterFunctionResources(
 	resources []ResourceInfo,
 	fn *ast.FuncDecl,
 	pass *analysis.Pass,
-	escapeAnalyzer *EscapeAnalyzer) []ResourceInfo {
+	escapeAnalyzer *EscapeAnalyzer,
+	strict bool,
+	metrics *Metrics) []ResourceInfo {
 
 	var functionResources []ResourceInfo
 
@@ -199,9 +762,20 @@ func collectAndFilterFunctionResources(
 			continue
 		}
 
+		if strict {
+			functionResources = append(functionResources, resource)
+			continue
+		}
+
 		// Spannerエスケープ解析統合
 		resource = integrateSpannerEscapeAnalysis(resource, escapeAnalyzer, fn)
 
+		// 設定されたヘルパー関数のクロージャで管理されるリソース
+		if managed, _ := escapeAnalyzer.IsClosureManaged(resource, fn); managed {
+			metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonClosureManaged)
+			continue
+		}
+
 		// エスケープ分析
 		escapeInfo := escapeAnalyzer.AnalyzeEscape(resource.Variable, fn)
 
@@ -209,12 +783,71 @@ func collectAndFilterFunctionResources(
 		shouldSkip, _ := shouldSkipResourceWithSpannerIntegration(resource, escapeInfo, escapeAnalyzer)
 		if !shouldSkip {
 			functionResources = append(functionResources, resource)
+			continue
+		}
+		if escapeSkip, reason := escapeAnalyzer.ShouldSkipResource(resource, escapeInfo); escapeSkip {
+			metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonEscapePrefix+reason)
+		} else {
+			metrics.recordSkip(pass.Fset.Position(resource.CreationPos), skipReasonSpannerManaged)
 		}
 	}
 
 	return functionResources
 }
 
+// validateIncludedServices は -gcpinclude に指定されたサービス名が設定に存在するかを検証する
+func validateIncludedServices(include []string, serviceRuleEngine *ServiceRuleEngine) error {
+	for _, name := range include {
+		if serviceRuleEngine.GetServiceRule(name) == nil {
+			return fmt.Errorf(messages.UnknownIncludedService, name, strings.Join(serviceRuleEngine.ServiceNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// filterResourcesByService は include が空でない場合、指定したサービスのリソースのみを残す
+func filterResourcesByService(resources []ResourceInfo, include []string) []ResourceInfo {
+	if len(include) == 0 {
+		return resources
+	}
+	included := make(map[string]bool, len(include))
+	for _, name := range include {
+		included[name] = true
+	}
+	var filtered []ResourceInfo
+	for _, resource := range resources {
+		if included[resource.ServiceType] {
+			filtered = append(filtered, resource)
+		}
+	}
+	return filtered
+}
+
+// appendFinalizerResources はエスケープ判定で除外されたリソースのうち、
+// runtime.SetFinalizer に登録されているものを解析対象に加える
+func appendFinalizerResources(
+	functionResources []ResourceInfo,
+	resources []ResourceInfo,
+	fn *ast.FuncDecl,
+	pass *analysis.Pass,
+	deferAnalyzer *DeferAnalyzer) []ResourceInfo {
+
+	included := make(map[token.Pos]bool, len(functionResources))
+	for _, resource := range functionResources {
+		included[resource.CreationPos] = true
+	}
+
+	for _, resource := range resources {
+		if included[resource.CreationPos] || !isResourceInFunction(resource, fn, pass) {
+			continue
+		}
+		if deferAnalyzer.IsRegisteredWithFinalizer(fn.Body, resource) {
+			functionResources = append(functionResources, resource)
+		}
+	}
+	return functionResources
+}
+
 // applyAutoManagedResourceFiltering は自動管理リソースのフィルタリングを適用する
 func applyAutoManagedResourceFiltering(
 	resources []ResourceInfo,
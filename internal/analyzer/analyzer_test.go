@@ -1,15 +1,22 @@
 package analyzer
 
 import (
+	"fmt"
 	"go/ast"
 	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
 func TestAnalyzer_Name(t *testing.T) {
@@ -1174,3 +1181,1956 @@ func containsJapaneseText(text string) bool {
 	}
 	return false
 }
+
+func TestAnalyzer_StrictMode(t *testing.T) {
+	src := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func main() {
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, "projects/test/instances/test/databases/test")
+	if err != nil {
+		return
+	}
+	_ = client
+}
+
+func newClient(ctx context.Context) (*spanner.Client, error) {
+	client, err := spanner.NewClient(ctx, "projects/test/instances/test/databases/test")
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+`
+	const packagePath = "github.com/example/project/cmd/server"
+
+	t.Run("cmd package is exempt by default", func(t *testing.T) {
+		diagnostics := runAnalyzerOnSourceWithOptions(t, packagePath, "main.go", src, Options{})
+		if len(diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics, got %d: %v", len(diagnostics), diagnostics)
+		}
+	})
+
+	t.Run("strict mode reports exempt and escaping resources", func(t *testing.T) {
+		diagnostics := runAnalyzerOnSourceWithOptions(t, packagePath, "main.go", src, Options{Strict: true})
+		if len(diagnostics) != 2 {
+			t.Errorf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+		}
+	})
+}
+
+func TestAnalyzer_ConfiguredCleanupMethod(t *testing.T) {
+	configContent := `
+services:
+  - service_name: batcher
+    package_path: example.com/batcher
+    creation_functions:
+      - NewPublisher
+    cleanup_methods:
+      - method: Drain
+        required: true
+        description: Flush pending messages
+`
+	configPath := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		deferStmt     string
+		expectedCount int
+	}{
+		{"Missing Drain", "", 1},
+		{"Deferred Drain", "defer publisher.Drain()", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"example.com/batcher"
+)
+
+func publish(ctx context.Context) error {
+	publisher, err := batcher.NewPublisher(ctx, "orders")
+	if err != nil {
+		return err
+	}
+	` + tt.deferStmt + `
+	publisher.Publish([]byte("hello"))
+	return nil
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ConfigPath: configPath})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if !strings.Contains(d.Message, "(Drain)") || strings.Contains(d.Message, "Close") {
+					t.Errorf("Diagnostic should name the configured Drain method, got %q", d.Message)
+				}
+			}
+		})
+	}
+
+	t.Run("Suggested fix uses configured method", func(t *testing.T) {
+		generator := NewDiagnosticGenerator(token.NewFileSet())
+		resource := ResourceInfo{
+			Variable:      types.NewVar(token.NoPos, nil, "publisher", nil),
+			VariableName:  "publisher",
+			ServiceType:   "batcher",
+			CleanupMethod: "Drain",
+			IsRequired:    true,
+		}
+
+		diagnostic := generator.ReportMissingDefer(resource)
+		if len(diagnostic.SuggestedFixes) != 1 || len(diagnostic.SuggestedFixes[0].TextEdits) != 1 {
+			t.Fatalf("Expected one suggested fix with one edit, got %+v", diagnostic.SuggestedFixes)
+		}
+		if got := string(diagnostic.SuggestedFixes[0].TextEdits[0].NewText); !strings.Contains(got, "defer publisher.Drain()") {
+			t.Errorf("Suggested fix should insert defer publisher.Drain(), got %q", got)
+		}
+		if !strings.Contains(diagnostic.Message, "(Drain)") {
+			t.Errorf("Message should name Drain, got %q", diagnostic.Message)
+		}
+	})
+}
+
+func TestAnalyzer_SuggestedFixWithContext(t *testing.T) {
+	configContent := `
+services:
+  - service_name: batcher
+    package_path: example.com/batcher
+    creation_functions:
+      - NewPublisher
+      - NewDefaultPublisher
+    cleanup_methods:
+      - method: Shutdown
+        required: true
+        requires_context: true
+        description: Flush pending messages
+`
+	configPath := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected []string // 修正を適用したソースに含まれる文字列
+	}{
+		{
+			name: "context in scope, inserted after the error check",
+			src: `package test
+
+import (
+	"context"
+
+	"example.com/batcher"
+)
+
+func publish(ctx context.Context) error {
+	publisher, err := batcher.NewPublisher(ctx, "orders")
+	if err != nil {
+		return err
+	}
+	publisher.Publish(nil)
+	return nil
+}
+`,
+			expected: []string{"return err\n\t}\n\tdefer publisher.Shutdown(ctx)\n\tpublisher.Publish(nil)"},
+		},
+		{
+			name: "no context in scope adds the context import",
+			src: `package test
+
+import "example.com/batcher"
+
+func publish() {
+	publisher, _ := batcher.NewDefaultPublisher("orders")
+	publisher.Publish(nil)
+}
+`,
+			expected: []string{
+				"import \"context\"\nimport \"example.com/batcher\"",
+				"batcher.NewDefaultPublisher(\"orders\")\n\tdefer publisher.Shutdown(context.Background())\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", tt.src, Options{ConfigPath: configPath})
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+			}
+			if len(diagnostics[0].SuggestedFixes) != 1 {
+				t.Fatalf("Expected one suggested fix, got %+v", diagnostics[0].SuggestedFixes)
+			}
+
+			// 単一ファイルの FileSet ではオフセットは Pos - 1
+			edits := append([]analysis.TextEdit{}, diagnostics[0].SuggestedFixes[0].TextEdits...)
+			sort.Slice(edits, func(i, j int) bool { return edits[i].Pos > edits[j].Pos })
+			fixed := tt.src
+			for _, edit := range edits {
+				start, end := int(edit.Pos)-1, int(edit.End)-1
+				fixed = fixed[:start] + string(edit.NewText) + fixed[end:]
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(fixed, expected) {
+					t.Errorf("Fixed source should contain %q, got:\n%s", expected, fixed)
+				}
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "fixed.go", fixed, 0); err != nil {
+				t.Errorf("Fixed source does not parse: %v\n%s", err, fixed)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_InitFunctionMode(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func init() {
+	client, err := spanner.NewClient(context.Background(), "db")
+	if err != nil {
+		panic(err)
+	}
+	_ = client
+}
+`
+
+	writeConfig := func(t *testing.T, mode string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+analyze_init: ` + mode + `
+`
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name             string
+		mode             string // empty uses the default rules
+		expectedCount    int
+		expectedCategory string
+		expectedSeverity string
+	}{
+		{"default is warn", "", 1, CategoryInitResourceLeak, SeverityWarning},
+		{"warn", "warn", 1, CategoryInitResourceLeak, SeverityWarning},
+		{"skip", "skip", 0, "", ""},
+		{"error", "error", 1, CategoryResourceLeak, SeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{}
+			if tt.mode != "" {
+				opts.ConfigPath = writeConfig(t, tt.mode)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, opts)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != tt.expectedCategory {
+					t.Errorf("Category = %q, want %q", d.Category, tt.expectedCategory)
+				}
+				if got := SeverityOf(d.Category); got != tt.expectedSeverity {
+					t.Errorf("Severity = %q, want %q", got, tt.expectedSeverity)
+				}
+			}
+		})
+	}
+
+	t.Run("method named init is a regular function", func(t *testing.T) {
+		methodSrc := strings.Replace(src, "func init() {", "type setup struct{}\n\nfunc (setup) init() {", 1)
+		diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", methodSrc, Options{})
+		if len(diagnostics) != 1 || diagnostics[0].Category != CategoryResourceLeak {
+			t.Errorf("Expected one %s diagnostic, got %v", CategoryResourceLeak, diagnostics)
+		}
+	})
+}
+
+func TestAnalyzer_ServiceLifetime(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+var shared *storage.Client
+
+type server struct {
+	client *storage.Client
+}
+
+func (s *server) init(ctx context.Context) {
+	fieldClient, _ := storage.NewClient(ctx)
+	s.client = fieldClient
+}
+
+func initShared(ctx context.Context) {
+	globalClient, _ := storage.NewClient(ctx)
+	shared = globalClient
+}
+`
+
+	writeConfig := func(t *testing.T, lifetime string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: storage
+    package_path: cloud.google.com/go/storage
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+    lifetime: ` + lifetime + `
+`
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		lifetime string // empty uses the default rules
+		reported []string
+	}{
+		{"default exempts only field assignment", "", []string{"'globalClient'"}},
+		{"app lifetime exempts field and global assignment", "app", nil},
+		{"request lifetime reports field and global assignment", "request", []string{"'fieldClient'", "'globalClient'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			if tt.lifetime != "" {
+				opts.ConfigPath = writeConfig(t, tt.lifetime)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "server.go", src, opts)
+			if len(diagnostics) != len(tt.reported) {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", len(tt.reported), len(diagnostics), diagnostics)
+			}
+			for _, name := range tt.reported {
+				if len(diagnosticsContaining(diagnostics, name)) != 1 {
+					t.Errorf("Expected %s to be reported, got %v", name, diagnostics)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ContextCancelledCleanup(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func cancelled(ctx context.Context, obj *storage.ObjectHandle) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, _ := obj.NewReader(streamCtx)
+	_ = stream
+}
+
+func notCancelled(ctx context.Context, obj *storage.ObjectHandle) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	uncancelled, _ := obj.NewReader(streamCtx)
+	_ = uncancelled
+	_ = cancel
+}
+
+func parentContext(ctx context.Context, obj *storage.ObjectHandle) {
+	_, cancel := context.WithCancel(ctx)
+	defer cancel()
+	parent, _ := obj.NewReader(ctx)
+	_ = parent
+}
+`
+
+	content := `
+services:
+  - service_name: storage
+    package_path: cloud.google.com/go/storage
+    creation_functions:
+      - NewReader
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close reader
+    context_cancelled_cleanup: true
+`
+	configPath := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "", "stream.go", src, Options{ConfigPath: configPath})
+	resourceLeaks := diagnosticsWithCategory(diagnostics, CategoryResourceLeak)
+	if len(resourceLeaks) != 2 {
+		t.Fatalf("Expected 2 resource leaks, got %d: %v", len(resourceLeaks), diagnostics)
+	}
+	for _, name := range []string{"'uncancelled'", "'parent'"} {
+		if len(diagnosticsContaining(resourceLeaks, name)) != 1 {
+			t.Errorf("Expected %s to be reported, got %v", name, diagnostics)
+		}
+	}
+
+	// 設定しないサービスでは cancel の defer を解放とみなさない
+	diagnostics = runAnalyzerOnSource(t, "stream.go", src)
+	if len(diagnosticsContaining(diagnostics, "'stream'")) != 1 {
+		t.Errorf("Expected 'stream' to be reported without context_cancelled_cleanup, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_PurePackages(t *testing.T) {
+	src := `package orchestration
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+	ctx, cancel := context.WithCancel(ctx)
+	_, _ = ctx, cancel
+}
+`
+
+	content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+pure_packages:
+  - "**/orchestration"
+`
+	configPath := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		pkgPath  string
+		strict   bool
+		expected int
+	}{
+		{"pure package", "example.com/app/orchestration", false, 0},
+		{"other package", "example.com/app/handler", false, 2},
+		{"pure package in strict mode", "example.com/app/orchestration", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, tt.pkgPath, "run.go", src, Options{ConfigPath: configPath, Strict: tt.strict})
+			if len(diagnostics) != tt.expected {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expected, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_FieldAssignmentExempt(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+type uploader struct {
+	writer *storage.Writer
+}
+
+func (u *uploader) start(ctx context.Context, bucket *storage.BucketHandle) {
+	w := bucket.Object("x").NewWriter(ctx)
+	u.writer = w
+}
+`
+
+	writeConfig := func(t *testing.T, exempt string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: storage
+    package_path: cloud.google.com/go/storage
+    creation_functions:
+      - NewWriter
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close writer
+    field_assignment_exempt: ` + exempt + `
+`
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name          string
+		exempt        string // empty uses the default rules
+		expectedCount int
+	}{
+		{"default exempts field assignment", "", 0},
+		{"explicitly exempt", "true", 0},
+		{"not exempt", "false", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			if tt.exempt != "" {
+				opts.ConfigPath = writeConfig(t, tt.exempt)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "uploader.go", src, opts)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryResourceLeak {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryResourceLeak)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AppliesToTestFiles(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+)
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+	var bucket *storage.BucketHandle
+	w := bucket.Object("x").NewWriter(ctx)
+	_ = w
+}
+`
+	content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+  - service_name: storage
+    package_path: cloud.google.com/go/storage
+    creation_functions:
+      - NewWriter
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close writer
+    applies_to_test_files: false
+`
+	path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		filename      string
+		strict        bool
+		expectedCount int
+	}{
+		{"test file skips storage only", "upload_test.go", false, 1},
+		{"non-test file checks both", "upload.go", false, 2},
+		{"strict ignores the setting", "upload_test.go", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", tt.filename, src, Options{ConfigPath: path, Strict: tt.strict})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			if len(diagnosticsContaining(diagnostics, "'client'")) != 1 {
+				t.Errorf("Expected the spanner client leak to be reported, got %v", diagnostics)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_IgnoreDirective(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// legacyLoad is scheduled for removal.
+//
+//gcpclosecheck:ignore closed by the caller's shutdown hook
+func legacyLoad(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+	ctx, cancel := context.WithCancel(ctx)
+	_, _ = ctx, cancel
+}
+
+//gcpclosecheck:ignored
+func notIgnored(ctx context.Context) {
+	other, _ := spanner.NewClient(ctx, "db")
+	_ = other
+}
+`
+
+	tests := []struct {
+		name          string
+		strict        bool
+		expectedCount int
+	}{
+		{"directive suppresses the function", false, 1},
+		{"strict ignores the directive", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "legacy.go", src, Options{Strict: tt.strict})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			if len(diagnosticsContaining(diagnostics, "'other'")) != 1 {
+				t.Errorf("Expected the leak in the function without a valid directive to be reported, got %v", diagnostics)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_LongRunningAdminClientInCmd(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "unclosed admin client in a migration loop",
+			body: `admin, _ := database.NewDatabaseAdminClient(ctx)
+	for i := 0; i < 5000; i++ {
+		op, _ := admin.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{Database: "db"})
+		_ = op.Wait(ctx)
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "admin client closed",
+			body: `admin, _ := database.NewDatabaseAdminClient(ctx)
+	defer admin.Close()
+	for i := 0; i < 5000; i++ {
+		_, _ = admin.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{Database: "db"})
+	}`,
+			expectedCount: 0,
+		},
+		{
+			name: "single operation stays exempt",
+			body: `admin, _ := database.NewDatabaseAdminClient(ctx)
+	_, _ = admin.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{Database: "db"})`,
+			expectedCount: 0,
+		},
+		{
+			name: "unclosed admin client with many operations and no loop",
+			body: "admin, _ := database.NewDatabaseAdminClient(ctx)\n" +
+				strings.Repeat("\t_, _ = admin.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{Database: \"db\"})\n", 50),
+			expectedCount: 1,
+		},
+		{
+			name: "admin client beside an unrelated loop stays exempt",
+			body: `admin, _ := database.NewDatabaseAdminClient(ctx)
+	_, _ = admin.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{Database: "db"})
+	for i := 0; i < 5000; i++ {
+		_ = ctx.Err()
+	}`,
+			expectedCount: 0,
+		},
+		{
+			name: "data client in a loop stays exempt",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	for i := 0; i < 5000; i++ {
+		_ = client
+	}
+	_, cancel := context.WithCancel(ctx)
+	_ = cancel`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+)
+
+var _ = spanner.NewClient
+var _ = database.NewDatabaseAdminClient
+
+func main() {
+	ctx := context.Background()
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "example.com/tools/cmd/migrate", "main.go", src, Options{})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			if tt.expectedCount > 0 && len(diagnosticsContaining(diagnostics, "'admin'")) != 1 {
+				t.Errorf("Expected the admin client leak to be reported, got %v", diagnostics)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ShortLivedPackageSkipsOptionalChecks(t *testing.T) {
+	src := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/datastore"
+	"cloud.google.com/go/spanner"
+)
+
+var _ = datastore.NewClient
+
+type tool struct {
+	client *spanner.Client
+}
+
+func main() {
+	client, _ := spanner.NewClient(context.Background(), "db")
+	_ = &tool{client: client}
+}
+`
+
+	opts := Options{WarnUnknown: true, StructLifecycle: true}
+	if diagnostics := runAnalyzerOnSourceWithOptions(t, "example.com/tools/cmd/migrate", "main.go", src, opts); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics in a short-lived package, got %v", diagnostics)
+	}
+
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "example.com/tools/migrate", "main.go", src, opts)
+	if len(diagnosticsWithCategory(diagnostics, CategoryUnknownGCPPackage)) != 1 ||
+		len(diagnosticsWithCategory(diagnostics, CategoryStructResourceNotClosed)) != 1 {
+		t.Errorf("Expected the notices outside the short-lived exception, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_WarnUnknownGCPPackage(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/datastore"
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	ds, _ := datastore.NewClient(ctx, "project")
+	defer ds.Close()
+	client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()
+}
+`
+
+	tests := []struct {
+		name          string
+		opts          Options
+		expectedCount int
+	}{
+		{"disabled by default", Options{}, 0},
+		{"enabled", Options{WarnUnknown: true}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, tt.opts)
+			notices := diagnosticsWithCategory(diagnostics, CategoryUnknownGCPPackage)
+			if len(notices) != tt.expectedCount {
+				t.Fatalf("Expected %d notices, got %d: %v", tt.expectedCount, len(notices), diagnostics)
+			}
+			if tt.expectedCount > 0 && len(diagnosticsContaining(notices, "cloud.google.com/go/datastore")) != 1 {
+				t.Errorf("Expected the notice to name the datastore package, got %v", notices)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_EmbedFS(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"embed"
+
+	"cloud.google.com/go/storage"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+type server struct {
+	assets embed.FS
+}
+
+func (s *server) load(ctx context.Context) ([]byte, error) {
+	f, err := s.assets.Open("testdata/a.json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := fixtures.ReadFile("testdata/b.json")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = client
+	return data, nil
+}
+`
+
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{WarnUnknown: true})
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected only the GCP client leak, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if diagnostics[0].Category != CategoryResourceLeak || len(diagnosticsContaining(diagnostics, "'client'")) != 1 {
+		t.Errorf("Expected the storage client leak, got %v", diagnostics[0])
+	}
+}
+
+func TestAnalyzer_ImmediatelyInvokedFunc(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func leakInside(ctx context.Context) {
+	func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		_ = client
+	}()
+}
+
+func closedInside(ctx context.Context) {
+	func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		defer client.Close()
+	}()
+}
+
+func leakReturned(ctx context.Context) {
+	returned := func() *spanner.Client {
+		c, _ := spanner.NewClient(ctx, "db")
+		return c
+	}()
+	_ = returned
+}
+
+func closedReturned(ctx context.Context) {
+	closed := func() *spanner.Client {
+		c, _ := spanner.NewClient(ctx, "db")
+		return c
+	}()
+	defer closed.Close()
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'client'")) != 1 {
+		t.Errorf("Expected the client leaked inside the IIFE to be reported, got %v", diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'returned'")) != 1 {
+		t.Errorf("Expected the client returned from the IIFE to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_ErrorsJoinDeferredClose(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/storage"
+)
+
+func joined(ctx context.Context) (err error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, client.Close())
+	}()
+	return nil
+}
+
+func joinedWithoutClose(ctx context.Context) (err error) {
+	leaked, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	_ = leaked
+	defer func() {
+		err = errors.Join(err, errors.New("done"))
+	}()
+	return nil
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected only the unclosed client, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'leaked'")) != 1 {
+		t.Errorf("Expected 'leaked' to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_OnceFuncWrappedClose(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+func onceFunc(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	closeFn := sync.OnceFunc(client.Close)
+	defer closeFn()
+}
+
+func onceValue(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	var closeFn = sync.OnceValue(func() error {
+		client.Close()
+		return nil
+	})
+	defer closeFn()
+}
+
+func inlineOnceFunc(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	defer sync.OnceFunc(client.Close)()
+}
+
+func wrappedButNotDeferred(ctx context.Context) {
+	notDeferred, _ := spanner.NewClient(ctx, "db")
+	closeFn := sync.OnceFunc(notDeferred.Close)
+	_ = closeFn
+}
+
+func wrapsOtherClient(ctx context.Context) {
+	other, _ := spanner.NewClient(ctx, "db")
+	defer other.Close()
+	wrongClient, _ := spanner.NewClient(ctx, "db")
+	closeFn := sync.OnceFunc(other.Close)
+	defer closeFn()
+	_ = wrongClient
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	for _, name := range []string{"'notDeferred'", "'wrongClient'"} {
+		if len(diagnosticsContaining(diagnostics, name)) != 1 {
+			t.Errorf("Expected %s to be reported, got %v", name, diagnostics)
+		}
+	}
+}
+
+func TestAnalyzer_UnusedResource(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func neverUsed(ctx context.Context) {
+	unused, _ := storage.NewClient(ctx)
+	_ = unused
+}
+
+func usedButNotClosed(ctx context.Context) {
+	used, _ := storage.NewClient(ctx)
+	used.Bucket("b")
+}
+
+func closed(ctx context.Context) {
+	client, _ := storage.NewClient(ctx)
+	defer client.Close()
+}
+`
+
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{UnusedResource: true})
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	unused := diagnosticsContaining(diagnostics, "never used or closed")
+	if len(unused) != 1 || !strings.Contains(unused[0].Message, "'unused'") {
+		t.Errorf("Expected only 'unused' to be reported as never used, got %v", diagnostics)
+	}
+	if used := diagnosticsContaining(diagnostics, "'used'"); len(used) != 1 || used[0].Category != CategoryResourceLeak {
+		t.Errorf("Expected 'used' to be reported as a plain resource leak, got %v", diagnostics)
+	}
+
+	// 無効時は通常の解放漏れとして報告する
+	diagnostics = runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnosticsContaining(diagnostics, "never used")) != 0 {
+		t.Errorf("Expected no dedicated message without UnusedResource, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_NamedResultReturned(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func open(ctx context.Context) (c *storage.Client, err error) {
+	c, err = storage.NewClient(ctx)
+	return
+}
+
+func openChecked(ctx context.Context) (client *storage.Client, err error) {
+	client, err = storage.NewClient(ctx)
+	if err != nil {
+		return
+	}
+	return client, nil
+}
+
+func openInClosure(ctx context.Context) func() (*storage.Client, error) {
+	return func() (fc *storage.Client, err error) {
+		fc, err = storage.NewClient(ctx)
+		return
+	}
+}
+
+func notReturned(ctx context.Context) (n int, err error) {
+	leaked, err := storage.NewClient(ctx)
+	_ = leaked
+	return
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected only the unreturned client, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'leaked'")) != 1 {
+		t.Errorf("Expected 'leaked' to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_DiagnosticPosition(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func leak(ctx context.Context, enabled bool) {
+	if enabled {
+		client, _ := storage.NewClient(ctx)
+		_ = client
+	}
+}
+`
+
+	// 単一ファイルの FileSet ではオフセット 0 が Pos 1 になる
+	creation := strings.Index(src, "storage.NewClient(ctx)") + 1
+	scopeEnd := strings.Index(src, "\t}\n}") + 2
+
+	tests := []struct {
+		diagPos string
+		want    int
+	}{
+		{"", creation},
+		{DiagPosCreation, creation},
+		{DiagPosScopeEnd, scopeEnd},
+	}
+
+	for _, tt := range tests {
+		t.Run("diagpos="+tt.diagPos, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{DiagPos: tt.diagPos})
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+			}
+			if got := int(diagnostics[0].Pos); got != tt.want {
+				t.Errorf("Pos = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_DependentResourceAfterParentDefer(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func leaksTransaction(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()
+	txn := client.ReadOnlyTransaction()
+	_ = txn
+}
+
+func closesTransaction(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()
+	closedTxn := client.ReadOnlyTransaction()
+	defer closedTxn.Close()
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected only the transaction without its own defer, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'txn'")) != 1 {
+		t.Errorf("Expected 'txn' to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_StorageReaders(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func leakedReader(ctx context.Context, obj *storage.ObjectHandle) {
+	reader, _ := obj.NewReader(ctx)
+	_ = reader
+}
+
+func leakedRangeReader(ctx context.Context, obj *storage.ObjectHandle) {
+	rangeReader, _ := obj.NewRangeReader(ctx, 0, 1024)
+	_ = rangeReader
+}
+
+func closedRangeReader(ctx context.Context, obj *storage.ObjectHandle) error {
+	rc, err := obj.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return nil
+}
+
+func handlesOnly(client *storage.Client) *storage.ObjectHandle {
+	bucket := client.Bucket("b")
+	object := bucket.Object("o")
+	return object
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	for _, name := range []string{"'reader'", "'rangeReader'"} {
+		if len(diagnosticsContaining(diagnostics, name)) != 1 {
+			t.Errorf("Expected %s to be reported, got %v", name, diagnostics)
+		}
+	}
+	for _, name := range []string{"'bucket'", "'object'"} {
+		if len(diagnosticsContaining(diagnostics, name)) != 0 {
+			t.Errorf("Expected handle %s not to be reported, got %v", name, diagnostics)
+		}
+	}
+}
+
+func TestAnalyzer_BranchAssignedResource(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func newA(ctx context.Context) *spanner.Client {
+	client, _ := spanner.NewClient(ctx, "a")
+	return client
+}
+
+func newB(ctx context.Context) *spanner.Client {
+	client, _ := spanner.NewClient(ctx, "b")
+	return client
+}
+
+func leaksBranchClient(ctx context.Context, useA bool) {
+	var branchClient *spanner.Client
+	if useA {
+		branchClient, _ = spanner.NewClient(ctx, "a")
+	} else {
+		branchClient, _ = spanner.NewClient(ctx, "b")
+	}
+	_ = branchClient
+}
+
+func leaksConstructedClient(ctx context.Context, useA bool) {
+	var constructedClient *spanner.Client
+	if useA {
+		constructedClient = newA(ctx)
+	} else {
+		constructedClient = newB(ctx)
+	}
+	_ = constructedClient
+}
+
+func closesInOneBranchClient(ctx context.Context, useA bool) {
+	var oneBranchClient *spanner.Client
+	if useA {
+		oneBranchClient, _ = spanner.NewClient(ctx, "a")
+	} else {
+		oneBranchClient, _ = spanner.NewClient(ctx, "b")
+		defer oneBranchClient.Close()
+	}
+	_ = oneBranchClient
+}
+
+func closesAfterBranches(ctx context.Context, useA bool) {
+	var mergedClient *spanner.Client
+	if useA {
+		mergedClient, _ = spanner.NewClient(ctx, "a")
+	} else {
+		mergedClient = newB(ctx)
+	}
+	defer mergedClient.Close()
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	for _, name := range []string{"'branchClient'", "'constructedClient'", "'oneBranchClient'"} {
+		if got := diagnosticsContaining(diagnostics, name); len(got) != 1 {
+			t.Errorf("Expected one diagnostic for %s, got %v", name, diagnostics)
+		}
+	}
+	if got := diagnosticsContaining(diagnostics, "'mergedClient'"); len(got) != 0 {
+		t.Errorf("A single defer after the branches should close the merged resource, got %v", got)
+	}
+}
+
+func TestAnalyzer_AtomicPointerStored(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"sync/atomic"
+
+	"cloud.google.com/go/spanner"
+)
+
+type registry struct{}
+
+func (registry) Store(client *spanner.Client) {}
+
+var current atomic.Pointer[spanner.Client]
+
+func swap(ctx context.Context, holder *atomic.Pointer[spanner.Client]) error {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	holder.Store(client)
+	return nil
+}
+
+func swapGlobal(ctx context.Context) {
+	storedClient, _ := spanner.NewClient(ctx, "db")
+	current.Store(storedClient)
+}
+
+func register(ctx context.Context, r registry) {
+	registeredClient, _ := spanner.NewClient(ctx, "db")
+	r.Store(registeredClient)
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected only the client passed to a non-atomic Store, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'registeredClient'")) != 1 {
+		t.Errorf("Expected 'registeredClient' to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_InitStatementCreation(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func inSwitch(ctx context.Context) {
+	switch switched, err := storage.NewClient(ctx); {
+	case err != nil:
+		return
+	default:
+		_ = switched
+	}
+}
+
+func inIf(ctx context.Context) {
+	if checked, err := storage.NewClient(ctx); err == nil {
+		_ = checked
+	}
+}
+
+func inFor(ctx context.Context) {
+	for looped, err := storage.NewClient(ctx); err == nil; {
+		_ = looped
+		break
+	}
+}
+
+func closedInSwitch(ctx context.Context) {
+	switch closed, err := storage.NewClient(ctx); {
+	case err != nil:
+		return
+	default:
+		defer closed.Close()
+	}
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	for _, name := range []string{"'switched'", "'checked'", "'looped'"} {
+		if len(diagnosticsContaining(diagnostics, name)) != 1 {
+			t.Errorf("Expected %s created in an init statement to be reported, got %v", name, diagnostics)
+		}
+	}
+}
+
+func TestAnalyzer_PanicSkipsDirectCleanup(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner"
+)
+
+func mustProcess(client *spanner.Client) {
+	if client == nil {
+		panic("nil client")
+	}
+}
+
+func directClose(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+	mustProcess(client)
+	client.Close()
+	return nil
+}
+
+func logPanic(ctx context.Context, logger *log.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	logger.Panicf("client %v", client)
+	client.Close()
+	return nil
+}
+
+func deferredClose(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	mustProcess(client)
+	return nil
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	skipped := diagnosticsWithCategory(diagnostics, CategoryPanicSkipsCleanup)
+	if len(skipped) != 2 || len(diagnosticsContaining(skipped, "direct cleanup may be skipped by a panic in mustProcess; use defer client.Close()")) != 1 ||
+		len(diagnosticsContaining(skipped, "direct cleanup may be skipped by a panic in logger.Panicf; use defer client.Close()")) != 1 {
+		t.Errorf("Expected the direct closes skipped by a panic to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_PanicSkipsDirectCleanupOnlyForPanicSources(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/spanner"
+)
+
+func process(client *spanner.Client) {}
+
+func directClose(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	process(client)
+	log.Printf("processed %v", client)
+	fmt.Println(len(ctx.Value("k").(string)))
+	client.Close()
+	return nil
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if skipped := diagnosticsWithCategory(diagnostics, CategoryPanicSkipsCleanup); len(skipped) != 0 {
+		t.Errorf("Calls that do not panic should not be reported as skipping the cleanup, got %v", skipped)
+	}
+}
+
+func TestAnalyzer_InterfaceMethodReturningResource(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+type ClientFactory interface {
+	NewClient(ctx context.Context) (*storage.Client, error)
+	Shared() *storage.Client
+}
+
+func leak(ctx context.Context, repo ClientFactory) error {
+	leaked, err := repo.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	_ = leaked
+	return nil
+}
+
+func closed(ctx context.Context, repo ClientFactory) error {
+	client, err := repo.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return nil
+}
+
+func shared(repo ClientFactory) {
+	client := repo.Shared()
+	_ = client
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 || len(diagnosticsContaining(diagnostics, "'leaked'")) != 1 {
+		t.Errorf("Expected only the client returned by the interface method and never closed to be reported, got %v", diagnostics)
+	}
+}
+
+func TestAnalyzer_GeneratedFilePolicy(t *testing.T) {
+	src := `// Code generated by mockgen. DO NOT EDIT.
+
+package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func newFakeClient(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+}
+`
+
+	writeConfig := func(t *testing.T, policy string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+generated_file_policy: ` + policy + `
+`
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name             string
+		policy           string // empty uses the default rules
+		strict           bool
+		expectedCount    int
+		expectedCategory string
+	}{
+		{"default reports generated files", "", false, 1, CategoryResourceLeak},
+		{"warn", "warn", false, 1, CategoryGeneratedLeak},
+		{"skip", "skip", false, 0, ""},
+		{"strict ignores skip", "skip", true, 1, CategoryResourceLeak},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Strict: tt.strict}
+			if tt.policy != "" {
+				opts.ConfigPath = writeConfig(t, tt.policy)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "mock_client.go", src, opts)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != tt.expectedCategory {
+					t.Errorf("Category = %q, want %q", d.Category, tt.expectedCategory)
+				}
+			}
+		})
+	}
+
+	t.Run("skip does not affect hand-written files", func(t *testing.T) {
+		handWritten := strings.Replace(src, "// Code generated by mockgen. DO NOT EDIT.\n\n", "", 1)
+		diagnostics := runAnalyzerOnSourceWithOptions(t, "", "client.go", handWritten, Options{ConfigPath: writeConfig(t, "skip")})
+		if len(diagnostics) != 1 || diagnostics[0].Category != CategoryResourceLeak {
+			t.Errorf("Expected one %s diagnostic, got %v", CategoryResourceLeak, diagnostics)
+		}
+	})
+}
+
+func TestAnalyzer_Timeout(t *testing.T) {
+	// 多数の関数を含む大きなファイルを生成する
+	var b strings.Builder
+	b.WriteString(`package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+`)
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&b, `
+func leak%d(ctx context.Context) {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	_ = client
+}
+`, i)
+	}
+	src := b.String()
+
+	t.Run("completes under generous timeout", func(t *testing.T) {
+		diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{Timeout: time.Minute})
+		if len(diagnostics) != 500 {
+			t.Errorf("Expected 500 diagnostics, got %d", len(diagnostics))
+		}
+		for _, d := range diagnostics {
+			if d.Category == CategoryAnalysisTimeout {
+				t.Errorf("Unexpected timeout notice: %s", d.Message)
+			}
+		}
+	})
+
+	t.Run("reports notice when timeout exceeded", func(t *testing.T) {
+		diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{Timeout: time.Nanosecond})
+		if len(diagnostics) != 1 {
+			t.Fatalf("Expected only the timeout notice, got %d: %v", len(diagnostics), diagnostics)
+		}
+		if diagnostics[0].Category != CategoryAnalysisTimeout {
+			t.Errorf("Expected category %q, got %q", CategoryAnalysisTimeout, diagnostics[0].Category)
+		}
+		if SeverityOf(diagnostics[0].Category) != SeverityWarning {
+			t.Errorf("Timeout notice should be a warning")
+		}
+	})
+}
+
+func TestAnalyzeSourceWithConfig_DiagnosticFilter(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+)
+
+func run(ctx context.Context) {
+	spannerClient, _ := spanner.NewClient(ctx, "db")
+	storageClient, _ := storage.NewClient(ctx)
+	_, _ = spannerClient, storageClient
+}
+`
+	imp := &fakeGCPImporter{t: t, packages: make(map[string]*types.Package)}
+
+	unfiltered, err := AnalyzeSourceWithConfig("test.go", []byte(src), AnalyzerConfig{Importer: imp})
+	if err != nil {
+		t.Fatalf("AnalyzeSourceWithConfig failed: %v", err)
+	}
+	if len(unfiltered) != 2 {
+		t.Fatalf("Expected 2 diagnostics without filter, got %d: %v", len(unfiltered), unfiltered)
+	}
+
+	// spanner リソースの診断を抑制する独自ポリシー
+	filtered, err := AnalyzeSourceWithConfig("test.go", []byte(src), AnalyzerConfig{
+		Importer: imp,
+		DiagnosticFilter: func(d analysis.Diagnostic) bool {
+			return !strings.Contains(d.Message, "'spannerClient'")
+		},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeSourceWithConfig failed: %v", err)
+	}
+	if len(filtered) != 1 {
+		t.Fatalf("Expected 1 diagnostic with filter, got %d: %v", len(filtered), filtered)
+	}
+	if !strings.Contains(filtered[0].Message, "'storageClient'") {
+		t.Errorf("Expected storage diagnostic to remain, got: %s", filtered[0].Message)
+	}
+}
+
+func TestAnalyzeSourceWithConfig_ResultCache(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+}
+`
+	imp := &fakeGCPImporter{t: t, packages: make(map[string]*types.Package)}
+	cache := &ResultCache{Dir: t.TempDir()}
+	analyze := func(src string, opts Options) []analysis.Diagnostic {
+		t.Helper()
+		diagnostics, err := AnalyzeSourceWithConfig("test.go", []byte(src), AnalyzerConfig{Options: opts, Importer: imp, Cache: cache})
+		if err != nil {
+			t.Fatalf("AnalyzeSourceWithConfig failed: %v", err)
+		}
+		return diagnostics
+	}
+
+	first := analyze(src, Options{})
+	second := analyze(src, Options{})
+	if cache.Hits() != 1 || cache.Misses() != 1 {
+		t.Fatalf("Expected 1 hit and 1 miss for unchanged content, got %d hits and %d misses", cache.Hits(), cache.Misses())
+	}
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("Expected 1 diagnostic on both runs, got %v and %v", first, second)
+	}
+	if first[0].Pos != second[0].Pos || first[0].Message != second[0].Message || first[0].Category != second[0].Category {
+		t.Errorf("Cached diagnostic differs: %+v vs %+v", first[0], second[0])
+	}
+	if len(second[0].SuggestedFixes) != len(first[0].SuggestedFixes) {
+		t.Errorf("Cached diagnostic should keep suggested fixes: %+v", second[0])
+	}
+
+	// 内容が変われば再解析する
+	fixed := strings.Replace(src, "_ = client", "defer client.Close()", 1)
+	if diagnostics := analyze(fixed, Options{}); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics after fixing the leak, got %v", diagnostics)
+	}
+	if cache.Misses() != 2 {
+		t.Errorf("Changed content should miss the cache, got %d misses", cache.Misses())
+	}
+
+	// 設定が変われば再解析する
+	if diagnostics := analyze(src, Options{Include: []string{"storage"}}); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics with -gcpinclude=storage, got %v", diagnostics)
+	}
+	if cache.Misses() != 3 {
+		t.Errorf("Changed options should miss the cache, got %d misses", cache.Misses())
+	}
+
+	// Metrics を指定した場合はキャッシュを使わない
+	analyze(src, Options{Metrics: NewMetrics()})
+	if cache.Hits() != 1 || cache.Misses() != 3 {
+		t.Errorf("Metrics runs should bypass the cache, got %d hits and %d misses", cache.Hits(), cache.Misses())
+	}
+}
+
+func TestRuleExplanations(t *testing.T) {
+	// 診断カテゴリ（ルールID）ごとに -gcpexplain の説明が用意されていること
+	for category := range categorySeverities {
+		explanation, ok := messages.Explain(category)
+		if !ok || strings.TrimSpace(explanation) == "" {
+			t.Errorf("rule %q should have a non-empty explanation", category)
+		}
+	}
+}
+
+func TestAnalyzer_IncludeServices(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+)
+
+func leak(ctx context.Context) {
+	spannerClient, _ := spanner.NewClient(ctx, "db")
+	storageClient, _ := storage.NewClient(ctx)
+	_, _ = spannerClient, storageClient
+}
+`
+
+	all := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{})
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 diagnostics without -gcpinclude, got %d: %v", len(all), all)
+	}
+
+	only := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{Include: []string{"spanner"}})
+	if len(only) != 1 || !strings.Contains(only[0].Message, "spannerClient") {
+		t.Errorf("Expected only the spanner leak with -gcpinclude=spanner, got %v", only)
+	}
+
+	imp := &fakeGCPImporter{t: t, packages: make(map[string]*types.Package)}
+	_, err := analyzeSource("", "test.go", []byte(src), AnalyzerConfig{Options: Options{Include: []string{"bigtable"}}, Importer: imp})
+	if err == nil || !strings.Contains(err.Error(), `unknown service "bigtable"`) {
+		t.Errorf("Expected unknown service error, got %v", err)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" spanner, storage,,pubsub ")
+	want := []string{"spanner", "storage", "pubsub"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitList() = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzer_Metrics(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type holder struct {
+	client *spanner.Client
+}
+
+func leak(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+}
+
+func released(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()
+}
+
+func returned(ctx context.Context) *spanner.Client {
+	client, _ := spanner.NewClient(ctx, "db")
+	return client
+}
+
+func (h *holder) init(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	h.client = client
+}
+`
+
+	metrics := NewMetrics()
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "example.com/app/service", "service.go", src, Options{Metrics: metrics})
+	runAnalyzerOnSourceWithOptions(t, "example.com/app/cmd/tool", "tool.go", src, Options{Metrics: metrics})
+
+	skipped := 0
+	for _, n := range metrics.Skipped() {
+		skipped += n
+	}
+	if metrics.Tracked() != 8 {
+		t.Errorf("Tracked() = %d, want 8", metrics.Tracked())
+	}
+	if metrics.Exempted() != 4 {
+		t.Errorf("Exempted() = %d, want 4", metrics.Exempted())
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want 2: %v", skipped, metrics.Skipped())
+	}
+	if metrics.Reported() != len(diagnostics) || metrics.Reported() != 1 {
+		t.Errorf("Reported() = %d, want 1 (diagnostics: %v)", metrics.Reported(), diagnostics)
+	}
+	if total := metrics.Exempted() + skipped + metrics.Released() + metrics.Reported(); total != metrics.Tracked() {
+		t.Errorf("exempted + skipped + released + reported = %d, want tracked %d", total, metrics.Tracked())
+	}
+
+	var out strings.Builder
+	if err := metrics.Write(&out); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"8 resources tracked", "skipped: escape: returned from function", "skipped: escape: assigned to struct field"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, out.String())
+		}
+	}
+}
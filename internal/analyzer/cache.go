@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// resultCacheVersion はキャッシュの形式や解析ロジックの互換性が変わったときに上げる
+const resultCacheVersion = "1"
+
+// ResultCache は単一ファイルの解析結果を、ソースの内容と有効な設定のハッシュをキーにディスクへ保存するキャッシュ。
+// 監視ループ等で同じ内容のファイルを繰り返し解析する場合に再解析を省略する。
+// Importer はキーに含まれないため、同じ Importer で解析する前提で使用する
+type ResultCache struct {
+	// Dir はキャッシュファイルを置くディレクトリ
+	Dir string
+
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+// NewResultCache はユーザーキャッシュディレクトリを使う ResultCache を作成する
+func NewResultCache() (*ResultCache, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &ResultCache{Dir: filepath.Join(userCacheDir, "gcpclosecheck", "results")}, nil
+}
+
+// Hits はキャッシュから結果を返した回数を返す
+func (rc *ResultCache) Hits() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.hits
+}
+
+// Misses はキャッシュになく解析した回数を返す
+func (rc *ResultCache) Misses() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.misses
+}
+
+// cachedDiagnostic は analysis.Diagnostic の保存形式。
+// 位置は解析ごとに作成する FileSet 内の値で、同じファイル名・内容であれば再現する
+type cachedDiagnostic struct {
+	Pos            token.Pos
+	End            token.Pos
+	Category       string
+	Message        string
+	URL            string
+	SuggestedFixes []cachedSuggestedFix
+}
+
+// cachedSuggestedFix は analysis.SuggestedFix の保存形式
+type cachedSuggestedFix struct {
+	Message   string
+	TextEdits []analysis.TextEdit
+}
+
+// key はソースの内容と、解析結果に影響する設定（オプション・有効なルール・パッケージパス・ファイル名）から
+// キャッシュキーを計算する。設定を読み込めない場合はエラーを返す
+func (rc *ResultCache) key(pkgPath, filename string, src []byte, opts Options) (string, error) {
+	serviceRuleEngine := NewServiceRuleEngine()
+	if err := serviceRuleEngine.LoadRules(opts.ConfigPath); err != nil {
+		return "", err
+	}
+	rules, err := json.Marshal(serviceRuleEngine.config)
+	if err != nil {
+		return "", err
+	}
+	opts.Metrics = nil
+	options, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+
+	contentHash := sha256.Sum256(src)
+	configHash := sha256.New()
+	for _, part := range [][]byte{[]byte(resultCacheVersion), []byte(pkgPath), []byte(filename), options, rules} {
+		configHash.Write(part)
+		configHash.Write([]byte{0})
+	}
+	return hex.EncodeToString(contentHash[:]) + "-" + hex.EncodeToString(configHash.Sum(nil)), nil
+}
+
+// load はキーに対応する診断を読み込む（キャッシュにない場合は false）
+func (rc *ResultCache) load(key string) ([]analysis.Diagnostic, bool) {
+	data, err := os.ReadFile(filepath.Join(rc.Dir, key+".json")) // #nosec G304 -- path is derived from a hash
+	if err != nil {
+		rc.record(false)
+		return nil, false
+	}
+	var cached []cachedDiagnostic
+	if err := json.Unmarshal(data, &cached); err != nil {
+		rc.record(false)
+		return nil, false
+	}
+	rc.record(true)
+
+	diagnostics := make([]analysis.Diagnostic, 0, len(cached))
+	for _, c := range cached {
+		diagnostic := analysis.Diagnostic{Pos: c.Pos, End: c.End, Category: c.Category, Message: c.Message, URL: c.URL}
+		for _, fix := range c.SuggestedFixes {
+			diagnostic.SuggestedFixes = append(diagnostic.SuggestedFixes, analysis.SuggestedFix{Message: fix.Message, TextEdits: fix.TextEdits})
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics, true
+}
+
+// store は診断をキャッシュに保存する（書き込み失敗は無視する）
+func (rc *ResultCache) store(key string, diagnostics []analysis.Diagnostic) {
+	cached := make([]cachedDiagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		c := cachedDiagnostic{Pos: d.Pos, End: d.End, Category: d.Category, Message: d.Message, URL: d.URL}
+		for _, fix := range d.SuggestedFixes {
+			c.SuggestedFixes = append(c.SuggestedFixes, cachedSuggestedFix{Message: fix.Message, TextEdits: fix.TextEdits})
+		}
+		cached = append(cached, c)
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(rc.Dir, 0o750); err != nil {
+		return
+	}
+
+	// 並行して同じキーを書き込んでも壊れたファイルを読まないよう、一時ファイルから置き換える
+	tmp, err := os.CreateTemp(rc.Dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(tmp.Name(), filepath.Join(rc.Dir, key+".json")) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// record はキャッシュの参照結果を数える
+func (rc *ResultCache) record(hit bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if hit {
+		rc.hits++
+	} else {
+		rc.misses++
+	}
+}
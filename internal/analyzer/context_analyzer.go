@@ -2,9 +2,13 @@ package analyzer
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
+	"sort"
 
 	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
 // ContextAnalyzer はcontext.WithCancel/WithTimeout検出とキャンセレーション検証を行う
@@ -12,17 +16,48 @@ type ContextAnalyzer struct {
 	contextVars    map[*types.Var]*ContextInfo
 	cancelVarNames map[string]*ContextInfo   // 変数名 -> ContextInfo のマッピング
 	scopeStack     []map[string]*ContextInfo // スコープ境界を跨ぐ変数名解決用
+	// deferredClosureCalls は defer されたクロージャ内で呼び出される変数（後から代入されるキャンセル関数の照合用）
+	deferredClosureCalls map[types.Object]bool
+	// 同一キャンセル関数の重複 defer 検出（オプション）
+	checkDuplicateDefers bool
+	cancelObjects        map[types.Object]bool        // context.WithCancel 等の戻り値が代入されたキャンセル関数
+	cancelDeferPositions map[types.Object][]token.Pos // キャンセル関数ごとの defer 位置
+	// 派生 context が下流に渡されず親 context が使われている箇所の検出（オプション）
+	checkPropagation bool
+	// context.AfterFunc の stop 関数が破棄・未使用の箇所の検出（オプション）
+	checkAfterFunc bool
+	// cancelledContexts は cancel が defer された派生 context 変数と、その生成位置（ファイルをまたいで保持する）
+	cancelledContexts map[types.Object][]token.Pos
 }
 
 // NewContextAnalyzer は新しいContextAnalyzerを作成する
 func NewContextAnalyzer() *ContextAnalyzer {
 	return &ContextAnalyzer{
-		contextVars:    make(map[*types.Var]*ContextInfo),
-		cancelVarNames: make(map[string]*ContextInfo),
-		scopeStack:     make([]map[string]*ContextInfo, 0),
+		contextVars:          make(map[*types.Var]*ContextInfo),
+		cancelVarNames:       make(map[string]*ContextInfo),
+		scopeStack:           make([]map[string]*ContextInfo, 0),
+		deferredClosureCalls: make(map[types.Object]bool),
+		cancelObjects:        make(map[types.Object]bool),
+		cancelDeferPositions: make(map[types.Object][]token.Pos),
+		cancelledContexts:    make(map[types.Object][]token.Pos),
 	}
 }
 
+// EnableDuplicateDeferCheck は同じキャンセル関数が複数回 defer されている箇所の報告を有効にする
+func (ca *ContextAnalyzer) EnableDuplicateDeferCheck() {
+	ca.checkDuplicateDefers = true
+}
+
+// EnablePropagationCheck は派生 context ではなく親 context が下流に渡されている箇所の報告を有効にする
+func (ca *ContextAnalyzer) EnablePropagationCheck() {
+	ca.checkPropagation = true
+}
+
+// EnableAfterFuncCheck は context.AfterFunc が返す stop 関数を破棄・未使用の箇所の報告を有効にする
+func (ca *ContextAnalyzer) EnableAfterFuncCheck() {
+	ca.checkAfterFunc = true
+}
+
 // TrackContextCreation はcontext生成関数を解析してキャンセル関数を追跡する
 func (ca *ContextAnalyzer) TrackContextCreation(call *ast.CallExpr, typeInfo *types.Info) error {
 	if ca == nil || typeInfo == nil {
@@ -81,25 +116,286 @@ func (ca *ContextAnalyzer) FindMissingCancels(pass *analysis.Pass) []analysis.Di
 
 		// 各contextについてdefer文の存在を確認
 		for _, contextInfo := range ca.contextVars {
+			if contextInfo.IsDeferred && contextInfo.Variable != nil {
+				ca.cancelledContexts[contextInfo.Variable] = append(ca.cancelledContexts[contextInfo.Variable], contextInfo.CreationPos)
+			}
 			if !contextInfo.IsDeferred {
 				diag := analysis.Diagnostic{
-					Pos:     contextInfo.CreationPos,
-					End:     contextInfo.CreationPos,
-					Message: "context cancel function should be called with defer",
+					Pos:      contextInfo.CreationPos,
+					End:      contextInfo.CreationPos,
+					Category: CategoryContextLeak,
+					Message:  "context cancel function should be called with defer",
 				}
 				diagnostics = append(diagnostics, diag)
 			}
 		}
 
+		if ca.checkDuplicateDefers {
+			diagnostics = append(diagnostics, ca.duplicateDeferDiagnostics()...)
+		}
+		if ca.checkPropagation {
+			diagnostics = append(diagnostics, ca.unpropagatedContextDiagnostics(file, pass.TypesInfo)...)
+		}
+		if ca.checkAfterFunc {
+			diagnostics = append(diagnostics, ca.afterFuncStopDiagnostics(file, pass.TypesInfo)...)
+		}
+
 		// 次のファイル用にリセット
 		ca.contextVars = make(map[*types.Var]*ContextInfo)
 		ca.cancelVarNames = make(map[string]*ContextInfo)
 		ca.scopeStack = make([]map[string]*ContextInfo, 0)
+		ca.deferredClosureCalls = make(map[types.Object]bool)
+		ca.cancelObjects = make(map[types.Object]bool)
+		ca.cancelDeferPositions = make(map[types.Object][]token.Pos)
+	}
+
+	return diagnostics
+}
+
+// IsCancelledContextAt は pos の時点で obj が cancel を defer した派生 context を保持しているかを返す。
+// FindMissingCancels の実行後に有効
+func (ca *ContextAnalyzer) IsCancelledContextAt(obj types.Object, pos token.Pos) bool {
+	for _, creationPos := range ca.cancelledContexts[obj] {
+		if creationPos < pos {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateDeferDiagnostics は2回目以降の defer cancel() を位置順に報告する
+func (ca *ContextAnalyzer) duplicateDeferDiagnostics() []analysis.Diagnostic {
+	var diagnostics []analysis.Diagnostic
+	for obj, positions := range ca.cancelDeferPositions {
+		if !ca.cancelObjects[obj] || len(positions) < 2 {
+			continue
+		}
+		for _, pos := range positions[1:] {
+			diagnostics = append(diagnostics, analysis.Diagnostic{
+				Pos:      pos,
+				End:      pos,
+				Category: CategoryDuplicateCancel,
+				Message:  messages.DuplicateCancelDefer,
+			})
+		}
+	}
+	// map の反復順に依存しないよう位置順に並べる
+	sort.Slice(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos < diagnostics[j].Pos
+	})
+	return diagnostics
+}
+
+// unpropagatedContextDiagnostics は WithCancel/WithTimeout/WithDeadline で派生させた context が
+// どの呼び出しにも渡されず、代わりに親 context が渡されている箇所を報告する
+func (ca *ContextAnalyzer) unpropagatedContextDiagnostics(file *ast.File, typeInfo *types.Info) []analysis.Diagnostic {
+	if file == nil || typeInfo == nil {
+		return nil
+	}
+
+	var diagnostics []analysis.Diagnostic
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || len(assign.Lhs) != 2 || len(assign.Rhs) != 1 {
+				return true
+			}
+			call, ok := assign.Rhs[0].(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 || !ca.isContextPackageCall(call, typeInfo) {
+				return true
+			}
+
+			parentIdent, ok := call.Args[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			parent := typeInfo.ObjectOf(parentIdent)
+			derivedIdent, ok := assign.Lhs[0].(*ast.Ident)
+			if !ok || parent == nil {
+				return true
+			}
+			// ctx, cancel = context.WithTimeout(ctx, d) のように同じ変数へ再代入する場合は対象外
+			derived := typeInfo.ObjectOf(derivedIdent)
+			if derived == parent {
+				return true
+			}
+
+			after := assign.End()
+			if (derived == nil || !isObjectPropagated(fn.Body, derived, after, typeInfo)) &&
+				isObjectPassedToCall(fn.Body, parent, after, typeInfo) {
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      call.Pos(),
+					End:      call.End(),
+					Category: CategoryContextNotPropagated,
+					Message:  messages.ContextNotPropagated,
+				})
+			}
+			return true
+		})
 	}
+	return diagnostics
+}
 
+// afterFuncStopDiagnostics は context.AfterFunc が返す stop 関数を破棄している箇所
+// （式文や _ への代入）と、代入した stop 関数を呼び出しも受け渡しもしていない箇所を報告する。
+// stop しない場合、ctx がキャンセルされるまで f と ctx の関連付けが解放されない
+func (ca *ContextAnalyzer) afterFuncStopDiagnostics(file *ast.File, typeInfo *types.Info) []analysis.Diagnostic {
+	if file == nil || typeInfo == nil {
+		return nil
+	}
+
+	var diagnostics []analysis.Diagnostic
+	report := func(call *ast.CallExpr, message string) {
+		diagnostics = append(diagnostics, analysis.Diagnostic{
+			Pos:      call.Pos(),
+			End:      call.End(),
+			Category: CategoryAfterFuncNotStopped,
+			Message:  message,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch stmt := n.(type) {
+			case *ast.ExprStmt:
+				if call, ok := stmt.X.(*ast.CallExpr); ok && isAfterFuncCall(call, typeInfo) {
+					report(call, messages.AfterFuncStopDiscarded)
+				}
+			case *ast.AssignStmt:
+				if len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 {
+					return true
+				}
+				call, ok := stmt.Rhs[0].(*ast.CallExpr)
+				if !ok || !isAfterFuncCall(call, typeInfo) {
+					return true
+				}
+				// フィールド等への代入は他の箇所で stop されるものとみなす
+				ident, ok := stmt.Lhs[0].(*ast.Ident)
+				if !ok {
+					return true
+				}
+				if ident.Name == "_" {
+					report(call, messages.AfterFuncStopDiscarded)
+					return true
+				}
+				obj := typeInfo.ObjectOf(ident)
+				if obj != nil && !isObjectCalled(fn.Body, obj, typeInfo) && !isObjectPropagated(fn.Body, obj, call.End(), typeInfo) {
+					report(call, messages.AfterFuncStopNotCalled)
+				}
+			}
+			return true
+		})
+	}
 	return diagnostics
 }
 
+// isAfterFuncCall は呼び出しが context.AfterFunc かを判定する
+func isAfterFuncCall(call *ast.CallExpr, typeInfo *types.Info) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "AfterFunc" {
+		return false
+	}
+	fn, ok := typeInfo.Uses[sel.Sel].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "context"
+}
+
+// isObjectCalled は obj を関数として呼び出している（defer/go を含む）かを判定する
+func isObjectCalled(body *ast.BlockStmt, obj types.Object, typeInfo *types.Info) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if ident, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && typeInfo.Uses[ident] == obj {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isContextPackageCall は呼び出しがキャンセル関数を返す context パッケージの関数かを判定する
+func (ca *ContextAnalyzer) isContextPackageCall(call *ast.CallExpr, typeInfo *types.Info) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !ca.IsContextWithCancel(sel.Sel.Name) {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkg, ok := typeInfo.Uses[ident].(*types.PkgName)
+	return ok && pkg.Imported().Path() == "context"
+}
+
+// isObjectPropagated は pos 以降で obj が呼び出しの引数・レシーバ、return、代入、複合リテラルのいずれかに使われているかを判定する
+func isObjectPropagated(body *ast.BlockStmt, obj types.Object, pos token.Pos, typeInfo *types.Info) bool {
+	refersTo := func(expr ast.Expr) bool {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		return ok && ident.Pos() >= pos && typeInfo.Uses[ident] == obj
+	}
+
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		var exprs []ast.Expr
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			exprs = append(exprs, node.Args...)
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+				exprs = append(exprs, sel.X)
+			}
+		case *ast.ReturnStmt:
+			exprs = node.Results
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); !ok || ident.Name != "_" {
+					exprs = node.Rhs
+					break
+				}
+			}
+		case *ast.CompositeLit:
+			for _, elt := range node.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					elt = kv.Value
+				}
+				exprs = append(exprs, elt)
+			}
+		}
+		for _, expr := range exprs {
+			if refersTo(expr) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isObjectPassedToCall は pos 以降で obj が呼び出しの引数として渡されているかを判定する
+func isObjectPassedToCall(body *ast.BlockStmt, obj types.Object, pos token.Pos, typeInfo *types.Info) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || call.Pos() < pos {
+			return !found
+		}
+		for _, arg := range call.Args {
+			if ident, ok := ast.Unparen(arg).(*ast.Ident); ok && typeInfo.Uses[ident] == obj {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
 // IsContextWithCancel は関数名がキャンセル関数を返すcontext関数かどうかを判定する
 func (ca *ContextAnalyzer) IsContextWithCancel(funcName string) bool {
 	cancelFunctions := []string{
@@ -179,8 +475,9 @@ func (ca *ContextAnalyzer) processStatementWithTracking(stmt ast.Stmt, typeInfo
 	switch node := stmt.(type) {
 	case *ast.AssignStmt:
 		ca.handleImprovedAssignment(node, typeInfo)
+		ca.analyzeClosures(node.Rhs, typeInfo)
 	case *ast.DeferStmt:
-		ca.handleImprovedDefer(node)
+		ca.handleImprovedDefer(node, typeInfo)
 	case *ast.IfStmt:
 		ca.processIfStatementWithTracking(node, typeInfo)
 	case *ast.BlockStmt:
@@ -197,7 +494,25 @@ func (ca *ContextAnalyzer) processStatementWithTracking(stmt ast.Stmt, typeInfo
 			case *ast.AssignStmt:
 				ca.handleImprovedAssignment(nested, typeInfo)
 			case *ast.DeferStmt:
-				ca.handleImprovedDefer(nested)
+				ca.handleImprovedDefer(nested, typeInfo)
+			case *ast.CallExpr:
+				if isTestingCleanupCall(nested, typeInfo) {
+					ca.handleCleanupRegistration(nested, typeInfo)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// analyzeClosures は式に含まれるクロージャ（変数に代入した t.Run のサブテスト関数等）を、
+// それぞれ独立した関数として解析する
+func (ca *ContextAnalyzer) analyzeClosures(exprs []ast.Expr, typeInfo *types.Info) {
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if funcLit, ok := n.(*ast.FuncLit); ok {
+				ca.analyzeFunctionWithImprovedTracking(&ast.FuncDecl{Body: funcLit.Body}, typeInfo)
+				return false
 			}
 			return true
 		})
@@ -235,8 +550,6 @@ func (ca *ContextAnalyzer) processGoStatementWithTracking(goStmt *ast.GoStmt, ty
 
 // handleImprovedAssignment は改良された代入文解析
 func (ca *ContextAnalyzer) handleImprovedAssignment(assign *ast.AssignStmt, typeInfo *types.Info) {
-	_ = typeInfo // 未使用警告回避
-
 	if len(assign.Rhs) != 1 {
 		return
 	}
@@ -263,6 +576,22 @@ func (ca *ContextAnalyzer) handleImprovedAssignment(assign *ast.AssignStmt, type
 				IsDeferred:  false,
 			}
 
+			// 派生 context の変数（生成したリソースとの照合用）
+			if ctxIdent, ok := assign.Lhs[0].(*ast.Ident); ok && typeInfo != nil {
+				contextInfo.Variable, _ = typeInfo.ObjectOf(ctxIdent).(*types.Var)
+			}
+
+			// 先行する defer クロージャが同じ変数を呼び出していれば解放済みとみなす
+			// （クロージャは変数を捕捉するため、defer より後の代入でも実行時には呼び出される）
+			if typeInfo != nil {
+				if obj := typeInfo.ObjectOf(cancelIdent); obj != nil {
+					ca.cancelObjects[obj] = true
+					if ca.deferredClosureCalls[obj] {
+						contextInfo.IsDeferred = true
+					}
+				}
+			}
+
 			// 現在のスコープに変数名を登録
 			ca.registerCancelVar(cancelVarName, contextInfo)
 
@@ -275,6 +604,7 @@ func (ca *ContextAnalyzer) handleImprovedAssignment(assign *ast.AssignStmt, type
 }
 
 // isSimpleContextCall は簡易版のcontext関数判定
+// errgroup.WithContext のようにキャンセル関数を返さない（Wait で内部的にキャンセルされる）派生コンテキストは対象外
 func (ca *ContextAnalyzer) isSimpleContextCall(call *ast.CallExpr) bool {
 	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
 		if ident, ok := sel.X.(*ast.Ident); ok {
@@ -285,15 +615,28 @@ func (ca *ContextAnalyzer) isSimpleContextCall(call *ast.CallExpr) bool {
 }
 
 // handleImprovedDefer は改良されたdefer文解析
-func (ca *ContextAnalyzer) handleImprovedDefer(defer_stmt *ast.DeferStmt) {
+func (ca *ContextAnalyzer) handleImprovedDefer(defer_stmt *ast.DeferStmt, typeInfo *types.Info) {
 	if defer_stmt.Call == nil {
 		return
 	}
 
+	// defer func() { cancel() }() パターン
+	if funcLit, ok := defer_stmt.Call.Fun.(*ast.FuncLit); ok {
+		ca.handleDeferredClosure(funcLit, typeInfo)
+		return
+	}
+
 	// defer call() パターンの識別
 	if ident, ok := defer_stmt.Call.Fun.(*ast.Ident); ok {
 		cancelVarName := ident.Name
 
+		// 重複検出は変数名ではなく束縛されたオブジェクトで数える
+		if typeInfo != nil {
+			if obj := typeInfo.ObjectOf(ident); obj != nil {
+				ca.cancelDeferPositions[obj] = append(ca.cancelDeferPositions[obj], defer_stmt.Pos())
+			}
+		}
+
 		// スコープ境界を跨ぐ変数名解決
 		if contextInfo := ca.resolveCancelVar(cancelVarName); contextInfo != nil {
 			contextInfo.IsDeferred = true
@@ -301,6 +644,67 @@ func (ca *ContextAnalyzer) handleImprovedDefer(defer_stmt *ast.DeferStmt) {
 	}
 }
 
+// handleDeferredClosure は defer されたクロージャ内のキャンセル関数呼び出しを記録する
+func (ca *ContextAnalyzer) handleDeferredClosure(funcLit *ast.FuncLit, typeInfo *types.Info) {
+	if funcLit.Body == nil {
+		return
+	}
+
+	ast.Inspect(funcLit.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := call.Fun.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		// 型情報がある場合は変数の同一性で照合する（defer より後の代入に備えて記録）
+		if typeInfo != nil {
+			obj := typeInfo.ObjectOf(ident)
+			if obj == nil {
+				return true
+			}
+			ca.deferredClosureCalls[obj] = true
+		}
+
+		// defer より前に生成済みのキャンセル関数
+		if contextInfo := ca.resolveCancelVar(ident.Name); contextInfo != nil {
+			contextInfo.IsDeferred = true
+		}
+		return true
+	})
+}
+
+// handleCleanupRegistration は t.Cleanup(cancel) / t.Cleanup(func() { cancel() }) を defer と同様に扱う
+// （登録した関数はテスト終了時に実行される）
+func (ca *ContextAnalyzer) handleCleanupRegistration(call *ast.CallExpr, typeInfo *types.Info) {
+	for _, arg := range call.Args {
+		switch fn := ast.Unparen(arg).(type) {
+		case *ast.FuncLit:
+			ca.handleDeferredClosure(fn, typeInfo)
+		case *ast.Ident:
+			if contextInfo := ca.resolveCancelVar(fn.Name); contextInfo != nil {
+				contextInfo.IsDeferred = true
+			}
+		}
+	}
+}
+
+// isTestingCleanupCall は呼び出しが testing パッケージの Cleanup（t.Cleanup, b.Cleanup 等）かを判定する
+func isTestingCleanupCall(call *ast.CallExpr, typeInfo *types.Info) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Cleanup" {
+		return false
+	}
+	if typeInfo == nil {
+		return true
+	}
+	fn, ok := typeInfo.Uses[sel.Sel].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "testing"
+}
+
 // pushScope は新しいスコープを開始する
 func (ca *ContextAnalyzer) pushScope() {
 	newScope := make(map[string]*ContextInfo)
@@ -5,6 +5,7 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/go/analysis"
@@ -551,6 +552,209 @@ func setupContextPackageInfo(file *ast.File, typeInfo *types.Info) {
 }
 
 // Task 14: 複雑なContextパターンのテストデータ
+func TestContextAnalyzer_ErrgroupWithContext(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "errgroup.WithContext without separate cancel",
+			body: `g, ctx := errgroup.WithContext(parent)
+	g.Go(func() error {
+		return work(ctx)
+	})
+	return g.Wait()`,
+		},
+		{
+			name: "errgroup.WithContext derived from deferred cancel context",
+			body: `parent, cancel := context.WithCancel(parent)
+	defer cancel()
+	g, ctx := errgroup.WithContext(parent)
+	g.Go(func() error {
+		return work(ctx)
+	})
+	return g.Wait()`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func work(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func run(parent context.Context) error {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics for errgroup.WithContext, got %d: %v", len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestContextAnalyzer_DeferredClosureBeforeAssignment(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "pre-declared cancel called from earlier deferred closure",
+			body: `var cancel context.CancelFunc
+	defer func() { cancel() }()
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(parent)
+	_ = ctx`,
+			expectedCount: 0,
+		},
+		{
+			name: "nil-checked cancel in deferred closure",
+			body: `var cancel context.CancelFunc
+	defer func() {
+		if cancel != nil {
+			cancel()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(parent, time.Second)
+	_ = ctx`,
+			expectedCount: 0,
+		},
+		{
+			name: "deferred closure after assignment",
+			body: `ctx, cancel := context.WithCancel(parent)
+	defer func() { cancel() }()
+	_ = ctx`,
+			expectedCount: 0,
+		},
+		{
+			name: "deferred closure calls a different variable",
+			body: `var stop context.CancelFunc = func() {}
+	defer func() { stop() }()
+	ctx, cancel := context.WithCancel(parent)
+	_, _ = ctx, cancel`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"time"
+)
+
+var _ = time.Second
+
+func run(parent context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestContextAnalyzer_DuplicateDeferredCancel(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		enabled       bool
+		expectedCount int
+	}{
+		{
+			name: "same cancel deferred twice",
+			body: `ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	defer cancel()
+	_ = ctx`,
+			enabled:       true,
+			expectedCount: 1,
+		},
+		{
+			name: "check disabled by default",
+			body: `ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	defer cancel()
+	_ = ctx`,
+			enabled:       false,
+			expectedCount: 0,
+		},
+		{
+			name: "shadowed cancel variables deferred once each",
+			body: `ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		_ = ctx
+	}`,
+			enabled:       true,
+			expectedCount: 0,
+		},
+		{
+			name: "several cancels deferred twice are reported in source order",
+			body: `ctx1, cancel1 := context.WithCancel(parent)
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(ctx1)
+	defer cancel2()
+	ctx3, cancel3 := context.WithCancel(ctx2)
+	defer cancel3()
+	defer cancel1()
+	defer cancel2()
+	defer cancel3()
+	_ = ctx3`,
+			enabled:       true,
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import "context"
+
+func run(parent context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{DuplicateCancel: tt.enabled})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryDuplicateCancel || SeverityOf(d.Category) != SeverityInfo {
+					t.Errorf("Expected informational %q diagnostic, got %q", CategoryDuplicateCancel, d.Category)
+				}
+				if !strings.Contains(d.Message, "cancel deferred multiple times") {
+					t.Errorf("Unexpected message: %s", d.Message)
+				}
+			}
+			for i := 1; i < len(diagnostics); i++ {
+				if diagnostics[i-1].Pos > diagnostics[i].Pos {
+					t.Errorf("Expected diagnostics in source order, got %v", diagnostics)
+				}
+			}
+		})
+	}
+}
+
 func TestComplexContextPatterns(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -862,3 +1066,333 @@ func anonymousFunctionLeak() {
 		})
 	}
 }
+
+func TestContextAnalyzer_DerivedContextNotPropagated(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		enabled       bool
+		expectedCount int
+	}{
+		{
+			name: "parent passed instead of derived context",
+			body: `ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	_ = ctx
+	doWork(parent)`,
+			enabled:       true,
+			expectedCount: 1,
+		},
+		{
+			name: "check disabled by default",
+			body: `ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	_ = ctx
+	doWork(parent)`,
+			enabled:       false,
+			expectedCount: 0,
+		},
+		{
+			name: "derived context passed downstream",
+			body: `ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	doWork(ctx)`,
+			enabled:       true,
+			expectedCount: 0,
+		},
+		{
+			name: "derived context waited on via Done",
+			body: `ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	go doWork(parent)
+	<-ctx.Done()`,
+			enabled:       true,
+			expectedCount: 0,
+		},
+		{
+			name: "context reassigned to the same variable",
+			body: `parent, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	doWork(parent)`,
+			enabled:       true,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"time"
+)
+
+func doWork(ctx context.Context) {}
+
+func run(parent context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ContextPropagation: tt.enabled})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryContextNotPropagated || SeverityOf(d.Category) != SeverityWarning {
+					t.Errorf("Expected warning %q diagnostic, got %q", CategoryContextNotPropagated, d.Category)
+				}
+				if d.Message != "derived context not propagated; timeout ineffective" {
+					t.Errorf("Unexpected message: %s", d.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestContextAnalyzer_AfterFuncStop(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		enabled         bool
+		expectedMessage string // empty means no diagnostic
+	}{
+		{
+			name:            "stop discarded by expression statement",
+			body:            `context.AfterFunc(ctx, cleanup)`,
+			enabled:         true,
+			expectedMessage: "context.AfterFunc stop function is discarded",
+		},
+		{
+			name:            "stop assigned to blank identifier",
+			body:            `_ = context.AfterFunc(ctx, cleanup)`,
+			enabled:         true,
+			expectedMessage: "context.AfterFunc stop function is discarded",
+		},
+		{
+			name: "stop never called",
+			body: `stop := context.AfterFunc(ctx, cleanup)
+	_ = stop`,
+			enabled:         true,
+			expectedMessage: "context.AfterFunc stop function is never called",
+		},
+		{
+			name:    "check disabled by default",
+			body:    `context.AfterFunc(ctx, cleanup)`,
+			enabled: false,
+		},
+		{
+			name: "stop deferred",
+			body: `stop := context.AfterFunc(ctx, cleanup)
+	defer stop()`,
+			enabled: true,
+		},
+		{
+			name: "stop called conditionally",
+			body: `stop := context.AfterFunc(ctx, cleanup)
+	if done() {
+		stop()
+	}`,
+			enabled: true,
+		},
+		{
+			name: "stop handed to another function",
+			body: `stop := context.AfterFunc(ctx, cleanup)
+	register(stop)`,
+			enabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import "context"
+
+func cleanup() {}
+
+func done() bool { return true }
+
+func register(stop func() bool) {}
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{AfterFuncStop: tt.enabled})
+			expectedCount := 0
+			if tt.expectedMessage != "" {
+				expectedCount = 1
+			}
+			if len(diagnostics) != expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryAfterFuncNotStopped || SeverityOf(d.Category) != SeverityWarning {
+					t.Errorf("Expected warning %q diagnostic, got %q", CategoryAfterFuncNotStopped, d.Category)
+				}
+				if d.Message != tt.expectedMessage {
+					t.Errorf("Message = %q, want %q", d.Message, tt.expectedMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestContextAnalyzer_SubtestClosures(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "subtest missing defer cancel",
+			body: `t.Run("a", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, _ = ctx, cancel
+	})`,
+			expectedCount: 1,
+		},
+		{
+			name: "table-driven subtests missing defer cancel",
+			body: `for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			_, _ = ctx, cancel
+		})
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "subtest function assigned to a variable",
+			body: `run := func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, _ = ctx, cancel
+	}
+	t.Run("a", run)`,
+			expectedCount: 1,
+		},
+		{
+			name: "subtest with defer cancel",
+			body: `t.Run("a", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_ = ctx
+	})`,
+			expectedCount: 0,
+		},
+		{
+			name: "cancel deferred by the parent test does not cover the subtest",
+			body: `ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t.Run("a", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		_, _ = ctx, cancel
+	})`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var _ = time.Second
+
+func TestSomething(t *testing.T) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "something_test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryContextLeak {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryContextLeak)
+				}
+			}
+		})
+	}
+}
+
+func TestContextAnalyzer_TestingCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		fn            string
+		expectedCount int
+	}{
+		{
+			name: "cancel registered with t.Cleanup closure",
+			fn: `func TestSomething(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() { cancel() })
+	_ = ctx
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "cancel passed to t.Cleanup directly",
+			fn: `func TestSomething(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	_ = ctx
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "cancel registered with b.Cleanup",
+			fn: `func BenchmarkSomething(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+	_ = ctx
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "Cleanup of an unrelated type does not count",
+			fn: `type pool struct{}
+
+func (pool) Cleanup(f func()) {}
+
+func TestSomething(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool{}.Cleanup(cancel)
+	_ = ctx
+}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var _ = time.Second
+
+` + tt.fn + `
+`
+			diagnostics := runAnalyzerOnSource(t, "something_test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryContextLeak {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryContextLeak)
+				}
+			}
+		})
+	}
+}
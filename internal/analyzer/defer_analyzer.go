@@ -1,11 +1,16 @@
 package analyzer
 
 import (
+	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"strings"
+	"unicode"
 
 	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
 // DeferAnalyzer はdefer文を解析してリソースの適切な解放を検証する
@@ -13,6 +18,24 @@ type DeferAnalyzer struct {
 	tracker    *ResourceTracker
 	scopeStack []*types.Scope
 	resources  []ResourceInfo // 検出されたリソース
+	// checkFinalizers は runtime.SetFinalizer に登録されたリソースを専用の警告で報告するか（オプション）
+	checkFinalizers bool
+	// checkCleanupOrder は依存先より後に解放されるべきリソースの defer 順序を検証するか（オプション）
+	checkCleanupOrder bool
+	// checkDeferInLoop はループ内で生成・defer されて関数終了まで解放されないリソースを警告するか（オプション）
+	checkDeferInLoop bool
+	// checkExitCalls は os.Exit/log.Fatal により defer の解放処理が実行されないリソースを警告するか（オプション）
+	checkExitCalls bool
+	// checkCreationErrors は生成時のエラーを確認せずに使用しているリソースを警告するか（オプション）
+	checkCreationErrors bool
+	// checkConditionalDefers は真偽値の条件下でのみ登録される解放の defer を警告するか（オプション）
+	checkConditionalDefers bool
+	// checkUnusedResources は生成後に一度も使用されないリソースを専用のメッセージで報告するか（オプション）
+	checkUnusedResources bool
+	// checkCleanupErrors は error_significant の解放メソッドのエラーを defer で捨てている箇所を警告するか（オプション）
+	checkCleanupErrors bool
+	// generator は解放漏れの診断に defer 文追加の修正提案を付ける（未設定の場合は付けない）
+	generator *DiagnosticGenerator
 }
 
 // NewDeferAnalyzer は新しいDeferAnalyzerを作成する
@@ -24,6 +47,51 @@ func NewDeferAnalyzer(tracker *ResourceTracker) *DeferAnalyzer {
 	}
 }
 
+// SetDiagnosticGenerator は解放漏れの診断に修正提案を付けるための DiagnosticGenerator を設定する
+func (da *DeferAnalyzer) SetDiagnosticGenerator(generator *DiagnosticGenerator) {
+	da.generator = generator
+}
+
+// EnableFinalizerCheck は runtime.SetFinalizer を解放処理の代わりにしているリソースの警告を有効にする
+func (da *DeferAnalyzer) EnableFinalizerCheck() {
+	da.checkFinalizers = true
+}
+
+// EnableCleanupOrderCheck は defer の記述順により依存先が先に解放される箇所の警告を有効にする
+func (da *DeferAnalyzer) EnableCleanupOrderCheck() {
+	da.checkCleanupOrder = true
+}
+
+// EnableDeferInLoopCheck はループ内の defer で解放が関数終了まで遅れるリソースの警告を有効にする
+func (da *DeferAnalyzer) EnableDeferInLoopCheck() {
+	da.checkDeferInLoop = true
+}
+
+// EnableExitCallCheck は defer の登録後に os.Exit/log.Fatal を呼んで解放処理が実行されない箇所の警告を有効にする
+func (da *DeferAnalyzer) EnableExitCallCheck() {
+	da.checkExitCalls = true
+}
+
+// EnableCreationErrorCheck は生成時のエラーを捨てる・確認しないままリソースを使用している箇所の警告を有効にする
+func (da *DeferAnalyzer) EnableCreationErrorCheck() {
+	da.checkCreationErrors = true
+}
+
+// EnableConditionalDeferCheck は if !closed { defer client.Close() } のような条件付きの解放の警告を有効にする
+func (da *DeferAnalyzer) EnableConditionalDeferCheck() {
+	da.checkConditionalDefers = true
+}
+
+// EnableUnusedResourceCheck は生成後に一度も使用・解放されないリソースの専用メッセージでの報告を有効にする
+func (da *DeferAnalyzer) EnableUnusedResourceCheck() {
+	da.checkUnusedResources = true
+}
+
+// EnableCleanupErrorCheck は error_significant の解放メソッドのエラーを defer で捨てている箇所の警告を有効にする
+func (da *DeferAnalyzer) EnableCleanupErrorCheck() {
+	da.checkCleanupErrors = true
+}
+
 // AnalyzeDefers は関数内のdefer文を解析して診断を生成する（外部からリソースリストを受け取る）
 func (da *DeferAnalyzer) AnalyzeDefers(fn *ast.FuncDecl, resources []ResourceInfo) []analysis.Diagnostic {
 	if fn == nil || fn.Body == nil {
@@ -34,49 +102,1120 @@ func (da *DeferAnalyzer) AnalyzeDefers(fn *ast.FuncDecl, resources []ResourceInf
 
 	// defer文を検索
 	defers := da.FindDeferStatements(fn.Body)
+	// TestMain は os.Exit(m.Run()) で終了するため defer が実行されない
+	testMain := isTestMain(fn)
 
 	// デバッグ出力を削除（本番では不要）
 
 	// 各リソースについてdefer文の存在を確認
 	for _, resource := range resources {
+		// 生成時のエラーを確認していないと、nil のリソースに対して Close 等を呼ぶおそれがある
+		if da.checkCreationErrors {
+			if errExpr := da.FindIgnoredCreationError(fn.Body, resource); errExpr != nil {
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      errExpr.Pos(),
+					End:      errExpr.End(),
+					Category: CategoryCreationErrorIgnored,
+					Message: fmt.Sprintf(messages.CreationErrorIgnored,
+						resource.VariableName, resource.CreationFunction),
+				})
+			}
+		}
+
 		if resource.IsRequired {
 			// デバッグコード削除（本番では不要）
 
 			found := false
+			var matchedDefer *ast.DeferStmt
+
+			// 生成位置から到達可能なdefer文のみを候補とする
+			reachableDefers := da.filterReachableDefers(fn.Body, resource, defers)
 
 			// 位置ベースの精密マッチング
-			bestMatchDefer := da.FindBestMatchingDefer(resource, defers)
+			bestMatchDefer := da.FindBestMatchingDefer(resource, reachableDefers)
 			if bestMatchDefer != nil && da.ValidateCleanupPattern(resource, bestMatchDefer) {
 				found = true
+				matchedDefer = bestMatchDefer
 			}
 
 			// 従来の方式による全defer文のチェック（フォールバック）
 			if !found {
-				for _, deferStmt := range defers {
+				for _, deferStmt := range reachableDefers {
 					if da.ValidateCleanupPattern(resource, deferStmt) {
 						found = true
+						matchedDefer = deferStmt
 						break
 					}
 				}
 			}
 
-			// defers配列への追加もチェック
-			if !found {
-				found = da.IsAddedToDeferArray(fn.Body, resource)
-			}
+			// sync.OnceFunc(client.Close) で包んだ関数の defer もチェック
+			if !found {
+				if onceDefer := da.FindOnceWrappedDefer(fn.Body, resource, reachableDefers); onceDefer != nil {
+					found = true
+					matchedDefer = onceDefer
+				}
+			}
+
+			// m["k"] = client; defer m["k"].Close() のように定数インデックスの要素経由で解放する defer もチェック
+			elementName, storePos := resource.VariableName, resource.CreationPos
+			if !found {
+				if elementDefer, name, pos := da.FindElementStoreDefer(fn.Body, resource, reachableDefers); elementDefer != nil {
+					found = true
+					matchedDefer = elementDefer
+					elementName, storePos = name, pos
+				}
+			}
+
+			// 要素経由の解放が評価される前に要素（またはコンテナ）が再代入されると、後から格納した値を解放する
+			if matchedDefer != nil && strings.Contains(elementName, "[") {
+				if reassign := da.FindElementReassignment(fn.Body, elementName, storePos, matchedDefer); reassign != nil {
+					diagnostics = append(diagnostics, analysis.Diagnostic{
+						Pos:      reassign.Pos(),
+						End:      reassign.End(),
+						Category: CategoryReassignedElement,
+						Message:  da.generateReassignedElementMessage(resource, elementName),
+					})
+					continue
+				}
+			}
+
+			// ループ内で再代入され、deferがループ外にある場合は最後の1つしか解放されない
+			if matchedDefer != nil && da.IsOverwrittenInLoop(fn.Body, resource, matchedDefer) {
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      resource.CreationPos,
+					End:      resource.CreationPos,
+					Category: CategoryResourceLeak,
+					Message:  da.generateLoopOverwriteMessage(resource),
+				})
+				continue
+			}
+
+			// ループ内の defer は反復ごとではなく関数終了時にまとめて実行される
+			if matchedDefer != nil && da.checkDeferInLoop && IsDeferredInCreationLoop(fn.Body, resource, matchedDefer) {
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      matchedDefer.Pos(),
+					End:      matchedDefer.End(),
+					Category: CategoryDeferInLoop,
+					Message:  da.generateDeferInLoopMessage(resource),
+				})
+				continue
+			}
+
+			// defer 登録後の os.Exit/log.Fatal ではdeferが実行されない（TestMain では常に検査する）
+			if matchedDefer != nil && (da.checkExitCalls || testMain) {
+				if exitCall, name := da.FindExitCallAfter(fn.Body, matchedDefer); exitCall != nil {
+					message := fmt.Sprintf(messages.ExitSkipsDefer,
+						name, resource.VariableName, resource.CleanupMethod)
+					if testMain {
+						message = fmt.Sprintf(messages.ExitSkipsDeferTestMain,
+							name, resource.VariableName, resource.CleanupMethod, name)
+					}
+					diagnostics = append(diagnostics, analysis.Diagnostic{
+						Pos:      exitCall.Pos(),
+						End:      exitCall.End(),
+						Category: CategoryExitSkipsDefer,
+						Message:  message,
+					})
+					continue
+				}
+			}
+
+			// 冪等な Close ラッパー等で意図的なことが多いため、解放漏れ（エラー）ではなく警告にとどめる
+			if matchedDefer != nil && da.checkConditionalDefers {
+				if guard := FindBooleanGuard(fn.Body, matchedDefer); guard != nil {
+					diagnostics = append(diagnostics, analysis.Diagnostic{
+						Pos:      matchedDefer.Pos(),
+						End:      matchedDefer.End(),
+						Category: CategoryConditionalCleanup,
+						Message: fmt.Sprintf(messages.ConditionalDefer,
+							resource.VariableName, resource.CleanupMethod, types.ExprString(guard)),
+					})
+					continue
+				}
+				// defer func() { if !closed { client.Close() } }() のようにクロージャ内で条件付きで解放する場合
+				if guard := da.FindClosureBooleanGuard(matchedDefer, resource); guard != nil {
+					diagnostics = append(diagnostics, analysis.Diagnostic{
+						Pos:      matchedDefer.Pos(),
+						End:      matchedDefer.End(),
+						Category: CategoryConditionalCleanup,
+						Message: fmt.Sprintf(messages.ConditionalDeferCall,
+							resource.VariableName, resource.CleanupMethod, types.ExprString(guard)),
+					})
+					continue
+				}
+			}
+
+			// error_significant の解放メソッド（storage の Writer.Close 等）のエラーを捨てると書き込みの失敗を見逃す
+			if matchedDefer != nil && da.checkCleanupErrors && resource.ErrorSignificant {
+				if call := da.FindDiscardedCleanupError(matchedDefer, resource); call != nil {
+					diagnostics = append(diagnostics, analysis.Diagnostic{
+						Pos:      call.Pos(),
+						End:      call.End(),
+						Category: CategoryCleanupErrorIgnored,
+						Message:  fmt.Sprintf(messages.CleanupErrorIgnored, resource.VariableName, resource.CleanupMethod),
+					})
+					continue
+				}
+			}
+
+			// defer された recover() で panic を回復する関数では、生成と直接の解放の間で panic すると解放が飛ばされる
+			if !found && hasDeferredRecover(fn.Body) {
+				if release := da.findExplicitRelease(fn.Body, resource); release != nil {
+					if call := da.FindPanickingCallBetween(fn.Body, resource, release); call != nil {
+						diagnostics = append(diagnostics, analysis.Diagnostic{
+							Pos:      release.Pos(),
+							End:      release.End(),
+							Category: CategoryPanicSkipsCleanup,
+							Message: fmt.Sprintf(messages.PanicSkipsCleanup,
+								types.ExprString(call.Fun), resource.VariableName, resource.CleanupMethod),
+						})
+						continue
+					}
+				}
+			}
+
+			// TestMain では os.Exit の前に明示的に解放するのが正しいパターン
+			if !found && testMain {
+				found = da.IsReleasedExplicitly(fn.Body, resource)
+			}
+
+			// defers配列への追加もチェック
+			if !found {
+				found = da.IsAddedToDeferArray(fn.Body, resource)
+			}
+
+			// 設定された登録メソッド（stack.Defer(client.Close) 等）もチェック
+			if !found {
+				found = da.IsRegisteredForCleanup(fn.Body, resource)
+			}
+
+			// ファイナライザ任せの解放は専用の警告として報告する
+			if !found && da.checkFinalizers && da.IsRegisteredWithFinalizer(fn.Body, resource) {
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      resource.CreationPos,
+					End:      resource.CreationPos,
+					Category: CategoryFinalizerCleanup,
+					Message:  fmt.Sprintf(messages.FinalizerCleanup, resource.CleanupMethod),
+				})
+				continue
+			}
+
+			if !found {
+				message := da.generateDiagnosticMessage(resource)
+				if da.checkUnusedResources && da.IsNeverUsed(fn.Body, resource) {
+					message = da.generateUnusedResourceMessage(resource)
+				}
+				diag := analysis.Diagnostic{
+					Pos:      resource.CreationPos,
+					End:      resource.CreationPos,
+					Category: CategoryResourceLeak,
+					Message:  message,
+				}
+				if da.generator != nil {
+					diag.SuggestedFixes = da.generator.MissingDeferFixes(resource, da.deferInsertPos(fn.Body, resource.CreationPos))
+				}
+				diagnostics = append(diagnostics, diag)
+			}
+		}
+	}
+
+	if da.checkCleanupOrder {
+		diagnostics = append(diagnostics, da.cleanupOrderDiagnostics(fn.Body, resources)...)
+	}
+
+	return diagnostics
+}
+
+// cleanupOrderDiagnostics は解析対象のリソースについて、依存先が先に解放される defer の順序を警告する
+func (da *DeferAnalyzer) cleanupOrderDiagnostics(body *ast.BlockStmt, resources []ResourceInfo) []analysis.Diagnostic {
+	tracked := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		tracked[resource.VariableName] = true
+	}
+
+	// 同じ依存先の defer が複数の依存元（txn と iter など）より後にある場合も報告は1件にまとめる
+	reported := make(map[*ast.DeferStmt]bool)
+	var diagnostics []analysis.Diagnostic
+	for _, violation := range da.findCleanupOrderViolations(body) {
+		if !tracked[violation.dependent] || reported[violation.dependencyDefer] {
+			continue
+		}
+		reported[violation.dependencyDefer] = true
+		diagnostics = append(diagnostics, analysis.Diagnostic{
+			Pos:      violation.dependencyDefer.Pos(),
+			End:      violation.dependencyDefer.End(),
+			Category: CategoryCleanupOrder,
+			Message:  generateCleanupOrderMessage(violation),
+		})
+	}
+	return diagnostics
+}
+
+// IsOverwrittenInLoop はループ外で宣言された変数にループ内でリソースを代入し、
+// 解放のdeferがループ外にあるかを判定する（最後の反復以外で生成したリソースがリークする）
+func (da *DeferAnalyzer) IsOverwrittenInLoop(body *ast.BlockStmt, resource ResourceInfo, deferStmt *ast.DeferStmt) bool {
+	if body == nil || deferStmt == nil || resource.VariableName == "" || !resource.CreationPos.IsValid() {
+		return false
+	}
+
+	loopBody := findEnclosingLoopBody(body, resource.CreationPos)
+	if loopBody == nil {
+		return false
+	}
+
+	// deferがループ内にあれば反復ごとに解放される
+	if deferStmt.Pos() >= loopBody.Pos() && deferStmt.End() <= loopBody.End() {
+		return false
+	}
+
+	// := による宣言はループ外から参照できないため、= による再代入のみ対象
+	if !isPlainAssignmentOf(loopBody, resource.CreationPos, resource.VariableName) {
+		return false
+	}
+
+	// 再代入前に明示的に解放している場合や、break/return で抜けるリトライループは除外。
+	// err != nil の確認内の return/break は生成に失敗した経路のため、成功時にループを抜ける根拠にしない
+	var typeInfo *types.Info
+	if da.tracker != nil {
+		typeInfo = da.tracker.typeInfo
+	}
+	leaks := true
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			if isErrorGuard(typeInfo, node.Cond) {
+				if node.Else != nil {
+					ast.Inspect(node.Else, visit)
+				}
+				return false
+			}
+		case *ast.ReturnStmt:
+			leaks = false
+		case *ast.BranchStmt:
+			if node.Tok == token.BREAK || node.Tok == token.GOTO {
+				leaks = false
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && da.isDirectMethodCall(sel, resource) {
+				leaks = false
+			}
+		}
+		return leaks
+	}
+	ast.Inspect(loopBody, visit)
+
+	return leaks
+}
+
+// isErrorGuard は条件が error 型の値と nil の不一致比較（err != nil）かを判定する。
+// 型情報がない場合は err という名前の変数で判定する
+func isErrorGuard(typeInfo *types.Info, cond ast.Expr) bool {
+	binary, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok || binary.Op != token.NEQ {
+		return false
+	}
+	operand := binary.X
+	if ident, ok := binary.X.(*ast.Ident); ok && ident.Name == "nil" {
+		operand = binary.Y
+	} else if ident, ok := binary.Y.(*ast.Ident); !ok || ident.Name != "nil" {
+		return false
+	}
+	if typeInfo != nil {
+		if t := typeInfo.TypeOf(operand); t != nil {
+			return types.Identical(t, types.Universe.Lookup("error").Type())
+		}
+	}
+	ident, ok := operand.(*ast.Ident)
+	return ok && ident.Name == "err"
+}
+
+// FindBooleanGuard は defer 文を直接囲む if 文の条件が真偽値の変数・フィールド（!closed, c.open 等）の場合にその条件を返す。
+// err == nil のような比較による条件は生成結果の確認として扱い、対象外にする
+func FindBooleanGuard(body *ast.BlockStmt, deferStmt *ast.DeferStmt) ast.Expr {
+	if body == nil || deferStmt == nil {
+		return nil
+	}
+
+	var guard ast.Expr
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			if containsStmt(node.Body, deferStmt) || containsStmt(node.Else, deferStmt) {
+				if isBooleanOperand(node.Cond) {
+					guard = node.Cond
+				}
+				return false
+			}
+		}
+		return guard == nil
+	})
+	return guard
+}
+
+// FindClosureBooleanGuard は defer したクロージャの直下で、リソースの解放が真偽値の条件（!closed 等）の
+// if 文の中でのみ呼ばれる場合にその条件を返す。クロージャ直下で無条件に解放している場合は nil
+func (da *DeferAnalyzer) FindClosureBooleanGuard(deferStmt *ast.DeferStmt, resource ResourceInfo) ast.Expr {
+	if deferStmt == nil || deferStmt.Call == nil {
+		return nil
+	}
+	funcLit, ok := deferStmt.Call.Fun.(*ast.FuncLit)
+	if !ok || funcLit.Body == nil {
+		return nil
+	}
+
+	var guard ast.Expr
+	for _, stmt := range funcLit.Body.List {
+		switch s := stmt.(type) {
+		case *ast.ExprStmt:
+			if call, ok := s.X.(*ast.CallExpr); ok && da.isResourceCloseCall(call.Fun, resource) {
+				return nil
+			}
+		case *ast.IfStmt:
+			if guard == nil && isBooleanOperand(s.Cond) && (da.closesDirectly(s.Body, resource) || da.closesDirectly(s.Else, resource)) {
+				guard = s.Cond
+			}
+		}
+	}
+	return guard
+}
+
+// closesDirectly はブロックの直下の式文でリソースを解放しているかを判定する
+func (da *DeferAnalyzer) closesDirectly(node ast.Stmt, resource ResourceInfo) bool {
+	block, ok := node.(*ast.BlockStmt)
+	if !ok {
+		return false
+	}
+	for _, stmt := range block.List {
+		if exprStmt, ok := stmt.(*ast.ExprStmt); ok {
+			if call, ok := exprStmt.X.(*ast.CallExpr); ok && da.isResourceCloseCall(call.Fun, resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsStmt は stmt がブロックの直下にあるかを判定する
+func containsStmt(node ast.Stmt, stmt ast.Stmt) bool {
+	block, ok := node.(*ast.BlockStmt)
+	if !ok {
+		return false
+	}
+	for _, s := range block.List {
+		if s == stmt {
+			return true
+		}
+	}
+	return false
+}
+
+// isBooleanOperand は式が真偽値の変数・フィールドまたはその否定かを判定する
+func isBooleanOperand(expr ast.Expr) bool {
+	expr = ast.Unparen(expr)
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.NOT {
+		expr = ast.Unparen(unary.X)
+	}
+	switch expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// FindExitCallAfter は defer 文より後にある os.Exit/log.Fatal 系の呼び出しと、その名前を返す。
+// プロセスが即座に終了するため、それまでに登録された defer は実行されない
+// （関数リテラル内の呼び出しは実行されるか判断できないため対象外）
+func (da *DeferAnalyzer) FindExitCallAfter(body *ast.BlockStmt, deferStmt *ast.DeferStmt) (*ast.CallExpr, string) {
+	if body == nil || deferStmt == nil {
+		return nil, ""
+	}
+
+	var exitCall *ast.CallExpr
+	var exitName string
+	ast.Inspect(body, func(n ast.Node) bool {
+		if exitCall != nil {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if node.Pos() <= deferStmt.End() {
+				return true
+			}
+			if name := da.exitFunctionName(node); name != "" {
+				exitCall, exitName = node, name
+				return false
+			}
+		}
+		return true
+	})
+	return exitCall, exitName
+}
+
+// isTestMain は関数がテストの TestMain(m *testing.M) かどうかを判定する
+func isTestMain(fn *ast.FuncDecl) bool {
+	if fn == nil || fn.Recv != nil || fn.Name.Name != "TestMain" || fn.Type.Params == nil {
+		return false
+	}
+	params := fn.Type.Params.List
+	return len(params) == 1 && len(params[0].Names) <= 1 && types.ExprString(params[0].Type) == "*testing.M"
+}
+
+// IsNeverUsed はリソース変数が生成後に一度も参照されないかを型情報の Uses で判定する。
+// コンパイルエラーを避けるための _ = client は使用とみなさない。型情報がない場合は false
+func (da *DeferAnalyzer) IsNeverUsed(body *ast.BlockStmt, resource ResourceInfo) bool {
+	if body == nil || resource.Variable == nil || da.tracker == nil || da.tracker.typeInfo == nil {
+		return false
+	}
+
+	// _ = client の右辺の識別子を収集
+	blankUses := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for _, lhs := range assign.Lhs {
+			if ident, ok := lhs.(*ast.Ident); !ok || ident.Name != "_" {
+				return true
+			}
+		}
+		for _, rhs := range assign.Rhs {
+			if ident, ok := rhs.(*ast.Ident); ok {
+				blankUses[ident] = true
+			}
+		}
+		return true
+	})
+
+	for ident, obj := range da.tracker.typeInfo.Uses {
+		if obj == resource.Variable && ident.Pos() > resource.CreationPos && !blankUses[ident] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsReleasedExplicitly はリソースの生成後に、defer ではなく文として解放メソッドを呼んでいるかを判定する
+// （関数リテラル内と select の case 内の呼び出しは実行されるか判断できないため対象外）
+func (da *DeferAnalyzer) IsReleasedExplicitly(body *ast.BlockStmt, resource ResourceInfo) bool {
+	return da.findExplicitRelease(body, resource) != nil
+}
+
+// findExplicitRelease はリソースの生成後に defer ではなく直接呼んでいる最初の解放メソッドの呼び出しを返す
+func (da *DeferAnalyzer) findExplicitRelease(body *ast.BlockStmt, resource ResourceInfo) *ast.CallExpr {
+	if body == nil {
+		return nil
+	}
+
+	var released *ast.CallExpr
+	ast.Inspect(body, func(n ast.Node) bool {
+		if released != nil {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit, *ast.DeferStmt:
+			return false
+		case *ast.SelectStmt:
+			// select { case <-done: client.Close() } は case が選ばれなければ実行されない
+			return false
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && node.Pos() > resource.CreationPos && da.isDirectMethodCall(sel, resource) {
+				released = node
+			}
+		}
+		return true
+	})
+	return released
+}
+
+// FindElementStoreDefer は m["k"] = client のようにリソース変数を定数インデックスの要素に格納し、
+// defer m["k"].Close() または defer func() { m["k"].Close() }() のように要素経由で解放している defer を返す。
+// 格納した要素名（m["k"]）と格納した代入文の終了位置もあわせて返す
+func (da *DeferAnalyzer) FindElementStoreDefer(body *ast.BlockStmt, resource ResourceInfo, defers []*ast.DeferStmt) (*ast.DeferStmt, string, token.Pos) {
+	if body == nil || resource.Variable == nil || da.tracker == nil || da.tracker.typeInfo == nil {
+		return nil, "", token.NoPos
+	}
+	typeInfo := da.tracker.typeInfo
+
+	var matched *ast.DeferStmt
+	var name string
+	var storeEnd token.Pos
+	ast.Inspect(body, func(n ast.Node) bool {
+		if matched != nil {
+			return false
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		assignStmt, ok := n.(*ast.AssignStmt)
+		if !ok || assignStmt.Pos() < resource.CreationPos || len(assignStmt.Lhs) != len(assignStmt.Rhs) {
+			return true
+		}
+		for i, rhs := range assignStmt.Rhs {
+			if ident, ok := ast.Unparen(rhs).(*ast.Ident); !ok || typeInfo.Uses[ident] != resource.Variable {
+				continue
+			}
+			index, ok := assignStmt.Lhs[i].(*ast.IndexExpr)
+			if !ok {
+				continue
+			}
+			element := resource
+			if element.VariableName = constantIndexName(typeInfo, index); element.VariableName == "" {
+				continue
+			}
+			for _, deferStmt := range defers {
+				if deferStmt.Pos() > assignStmt.End() && da.ValidateCleanupPattern(element, deferStmt) {
+					matched, name, storeEnd = deferStmt, element.VariableName, assignStmt.End()
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return matched, name, storeEnd
+}
+
+// FindElementReassignment は要素 name（base[k]）またはコンテナ base 自体が、from より後で解放の評価より前に再代入される代入文を返す。
+// defer base[k].Close() はレシーバを defer 文の実行時に評価するため defer 文までを、
+// defer func() { base[k].Close() }() は関数の終了時に評価するため関数の末尾までを対象にする
+func (da *DeferAnalyzer) FindElementReassignment(body *ast.BlockStmt, name string, from token.Pos, deferStmt *ast.DeferStmt) *ast.AssignStmt {
+	if body == nil || deferStmt == nil || deferStmt.Call == nil || da.tracker == nil || da.tracker.typeInfo == nil {
+		return nil
+	}
+	typeInfo := da.tracker.typeInfo
+
+	// defer 文から要素のインデックス式を探し、コンテナの変数を特定する
+	var base types.Object
+	ast.Inspect(deferStmt.Call, func(n ast.Node) bool {
+		if index, ok := n.(*ast.IndexExpr); ok && base == nil && constantIndexName(typeInfo, index) == name {
+			base = typeInfo.Uses[index.X.(*ast.Ident)]
+		}
+		return base == nil
+	})
+	if base == nil {
+		return nil
+	}
+
+	until := deferStmt.Pos()
+	if _, ok := deferStmt.Call.Fun.(*ast.FuncLit); ok {
+		until = body.End()
+	}
+
+	var reassign *ast.AssignStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		if reassign != nil {
+			return false
+		}
+		assignStmt, ok := n.(*ast.AssignStmt)
+		if !ok || assignStmt.Pos() <= from || assignStmt.Pos() >= until {
+			return true
+		}
+		for _, lhs := range assignStmt.Lhs {
+			switch l := lhs.(type) {
+			case *ast.IndexExpr:
+				if constantIndexName(typeInfo, l) == name {
+					reassign = assignStmt
+				}
+			case *ast.Ident:
+				if typeInfo.Uses[l] == base {
+					reassign = assignStmt
+				}
+			}
+		}
+		return reassign == nil
+	})
+	return reassign
+}
+
+// hasDeferredRecover は関数が defer func() { ... recover() ... }() で panic を回復しているかを判定する。
+// 回復された panic の後も関数（とその呼び出し元）は動き続けるため、直接呼び出しの解放が飛ばされるとリークになる
+func hasDeferredRecover(body *ast.BlockStmt) bool {
+	if body == nil {
+		return false
+	}
+
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		deferStmt, ok := n.(*ast.DeferStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := ast.Unparen(deferStmt.Call.Fun).(*ast.FuncLit)
+		if !ok {
+			return false
+		}
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.CallExpr:
+				if ident, ok := node.Fun.(*ast.Ident); ok && ident.Name == "recover" && len(node.Args) == 0 {
+					found = true
+				}
+			}
+			return !found
+		})
+		return false
+	})
+	return found
+}
+
+// FindPanickingCallBetween はリソースを生成した文の後から解放メソッドの直接呼び出しまでの間にある、
+// panic を起こす呼び出し（panic、log.Panic 系、must ヘルパー）を返す（return 文内の呼び出しはエラー経路として対象外）
+func (da *DeferAnalyzer) FindPanickingCallBetween(body *ast.BlockStmt, resource ResourceInfo, release *ast.CallExpr) *ast.CallExpr {
+	if body == nil || release == nil {
+		return nil
+	}
+
+	// 生成呼び出し自体（client, err := spanner.NewClient(...)）は対象外にするため、生成した文の終端から数える
+	from := resource.CreationPos
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || resource.CreationPos < n.Pos() || n.End() <= resource.CreationPos {
+			return false
+		}
+		switch n.(type) {
+		case *ast.AssignStmt, *ast.DeclStmt, *ast.ExprStmt:
+			from = n.End()
+		}
+		return true
+	})
+
+	var panicking *ast.CallExpr
+	ast.Inspect(body, func(n ast.Node) bool {
+		if panicking != nil {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit, *ast.DeferStmt, *ast.ReturnStmt:
+			return false
+		case *ast.CallExpr:
+			if node.Pos() < from || node.End() > release.Pos() {
+				return true
+			}
+			if da.isPanicSource(node) {
+				panicking = node
+			}
+		}
+		return true
+	})
+	return panicking
+}
+
+// isPanicSource は呼び出しが組み込みの panic、log.Panic/Panicf/Panicln（*log.Logger のメソッドを含む）、
+// または Must/must で始まる名前のヘルパー（template.Must, regexp.MustCompile, mustParse 等）かを判定する。
+// 任意の呼び出しを panic し得るとみなすと、ほぼすべての直接の解放が報告されるため対象を限定する
+func (da *DeferAnalyzer) isPanicSource(call *ast.CallExpr) bool {
+	var typeInfo *types.Info
+	if da.tracker != nil {
+		typeInfo = da.tracker.typeInfo
+	}
+
+	var name *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		if fun.Name == "panic" {
+			if typeInfo == nil {
+				return true
+			}
+			_, builtin := typeInfo.Uses[fun].(*types.Builtin)
+			return builtin
+		}
+		name = fun
+	case *ast.SelectorExpr:
+		name = fun.Sel
+		if name.Name == "Panic" || name.Name == "Panicf" || name.Name == "Panicln" {
+			pkgPath := ""
+			if typeInfo != nil {
+				if fn, ok := typeInfo.Uses[name].(*types.Func); ok && fn.Pkg() != nil {
+					pkgPath = fn.Pkg().Path()
+				}
+			} else if ident, ok := fun.X.(*ast.Ident); ok {
+				// 型情報がない場合はパッケージ名で判定する
+				pkgPath = ident.Name
+			}
+			if pkgPath == "log" {
+				return true
+			}
+		}
+	default:
+		return false
+	}
+	return isMustHelperName(name.Name)
+}
+
+// isMustHelperName は関数名が Must/must そのものか、Must/must に大文字で始まる語が続く名前かを判定する
+func isMustHelperName(name string) bool {
+	for _, prefix := range []string{"Must", "must"} {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if ok && (rest == "" || unicode.IsUpper([]rune(rest)[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+// exitFunctionName は呼び出しが os.Exit または log.Fatal/Fatalf/Fatalln（*log.Logger のメソッドを含む）の場合にその名前を返す
+func (da *DeferAnalyzer) exitFunctionName(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+
+	pkgPath, name := "", sel.Sel.Name
+	if da.tracker != nil && da.tracker.typeInfo != nil {
+		fn, ok := da.tracker.typeInfo.Uses[sel.Sel].(*types.Func)
+		if !ok || fn.Pkg() == nil {
+			return ""
+		}
+		pkgPath = fn.Pkg().Path()
+	} else if ident, ok := sel.X.(*ast.Ident); ok {
+		// 型情報がない場合はパッケージ名で判定する
+		pkgPath = ident.Name
+	}
+
+	switch {
+	case pkgPath == "os" && name == "Exit":
+		return "os.Exit"
+	case pkgPath == "log" && (name == "Fatal" || name == "Fatalf" || name == "Fatalln"):
+		return "log." + name
+	}
+	return ""
+}
+
+// FindIgnoredCreationError は `client, err := NewClient(ctx)` のようにエラーも返す生成について、
+// エラーが _ に捨てられているか、リソースを最初にセレクタ経由で使う（defer client.Close() を含む）までに
+// エラーを一度も参照していない場合に、そのエラーを受ける式を返す。
+// 内側のスコープで同名の err を宣言して確認しているつもりになっているケースも、型情報で別変数として扱われるため検出できる
+func (da *DeferAnalyzer) FindIgnoredCreationError(body *ast.BlockStmt, resource ResourceInfo) ast.Expr {
+	if body == nil || resource.Variable == nil || da.tracker == nil || da.tracker.typeInfo == nil {
+		return nil
+	}
+	typeInfo := da.tracker.typeInfo
+
+	// リソースとエラーを同時に受け取る生成の代入文を探す
+	var creation *ast.AssignStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		if creation != nil {
+			return false
+		}
+		if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == 2 && len(assign.Rhs) == 1 &&
+			assign.Rhs[0].Pos() == resource.CreationPos {
+			if ident, ok := assign.Lhs[0].(*ast.Ident); ok && typeInfo.ObjectOf(ident) == resource.Variable {
+				creation = assign
+			}
+		}
+		return true
+	})
+	if creation == nil || !returnsError(typeInfo, creation.Rhs[0]) {
+		return nil
+	}
+	errIdent, ok := creation.Lhs[1].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	// nil のままだと panic するリソースの最初の使用箇所（メソッド呼び出し・フィールド参照）
+	firstUse := token.NoPos
+	ast.Inspect(body, func(n ast.Node) bool {
+		if firstUse.IsValid() {
+			return false
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.Pos() > creation.End() {
+			if ident, ok := sel.X.(*ast.Ident); ok && typeInfo.Uses[ident] == resource.Variable {
+				firstUse = sel.Pos()
+			}
+		}
+		return true
+	})
+	if !firstUse.IsValid() {
+		return nil
+	}
+	if errIdent.Name == "_" {
+		return errIdent
+	}
+
+	errVar := typeInfo.ObjectOf(errIdent)
+	if errVar == nil {
+		return nil
+	}
+	// 代入の左辺（上書き）は確認として扱わない
+	assigned := make(map[*ast.Ident]bool)
+	checked := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if checked {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					assigned[ident] = true
+				}
+			}
+		case *ast.Ident:
+			if node.Pos() > creation.End() && node.Pos() < firstUse && !assigned[node] && typeInfo.Uses[node] == errVar {
+				checked = true
+			}
+		}
+		return true
+	})
+	if checked {
+		return nil
+	}
+	return errIdent
+}
+
+// returnsError は呼び出しの2番目の戻り値が error 型かを判定する
+func returnsError(typeInfo *types.Info, expr ast.Expr) bool {
+	tuple, ok := typeInfo.TypeOf(expr).(*types.Tuple)
+	if !ok || tuple.Len() != 2 {
+		return false
+	}
+	return types.Identical(tuple.At(1).Type(), types.Universe.Lookup("error").Type())
+}
+
+// IsDeferredInCreationLoop はリソースの生成と解放の defer が同じループ内（関数リテラルを挟まない）にあるかを判定する。
+// その場合、defer は反復ごとではなく関数終了時に実行されるため、ループ中はリソースが開いたまま蓄積される
+func IsDeferredInCreationLoop(body *ast.BlockStmt, resource ResourceInfo, deferStmt *ast.DeferStmt) bool {
+	if body == nil || deferStmt == nil || !resource.CreationPos.IsValid() {
+		return false
+	}
+	loopBody := findEnclosingLoopBody(body, resource.CreationPos)
+	if loopBody == nil || deferStmt.Pos() < loopBody.Pos() || deferStmt.End() > loopBody.End() {
+		return false
+	}
+	return findEnclosingLoopBody(body, deferStmt.Pos()) != nil
+}
+
+// findEnclosingLoopBody は指定位置を含む最も内側の for/range のボディを返す（関数リテラルは越えない）
+func findEnclosingLoopBody(root ast.Node, pos token.Pos) *ast.BlockStmt {
+	var loopBody *ast.BlockStmt
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			loopBody = nil
+		case *ast.ForStmt:
+			if node.Body != nil && pos >= node.Body.Pos() {
+				loopBody = node.Body
+			}
+		case *ast.RangeStmt:
+			if node.Body != nil && pos >= node.Body.Pos() {
+				loopBody = node.Body
+			}
+		}
+		return true
+	})
+	return loopBody
+}
+
+// isPlainAssignmentOf は生成呼び出しが varName への = 代入の右辺かを判定する
+func isPlainAssignmentOf(root ast.Node, creationPos token.Pos, varName string) bool {
+	result := false
+	ast.Inspect(root, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return !result
+		}
+		if assign.Tok != token.ASSIGN || len(assign.Rhs) != 1 || assign.Rhs[0].Pos() != creationPos {
+			return true
+		}
+		for _, lhs := range assign.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && ident.Name == varName {
+				result = true
+			}
+		}
+		return false
+	})
+	return result
+}
+
+// filterReachableDefers はリソース生成位置から到達可能なdefer文のみを返す
+func (da *DeferAnalyzer) filterReachableDefers(body *ast.BlockStmt, resource ResourceInfo, defers []*ast.DeferStmt) []*ast.DeferStmt {
+	var reachable []*ast.DeferStmt
+	for _, deferStmt := range defers {
+		if da.IsDeferReachableFromCreation(body, resource, deferStmt) && da.isReachableFromBranches(body, resource, deferStmt) {
+			reachable = append(reachable, deferStmt)
+		}
+	}
+	return reachable
+}
+
+// isReachableFromBranches は if/else の他の分岐で同じ変数に代入した生成位置からも defer が到達可能かを判定する。
+// 一方の分岐内だけにある defer では、もう一方の分岐で生成したリソースは解放されない
+func (da *DeferAnalyzer) isReachableFromBranches(body *ast.BlockStmt, resource ResourceInfo, deferStmt *ast.DeferStmt) bool {
+	for _, pos := range resource.BranchCreationPos {
+		branch := resource
+		branch.CreationPos = pos
+		if !da.IsDeferReachableFromCreation(body, branch, deferStmt) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDeferReachableFromCreation はdefer文がリソース生成位置から到達可能なスコープにあるかを判定する
+// 生成と同じブロック（ネスト含む）か、生成ブロックを内包する外側ブロックのdeferのみ有効とし、
+// switch/select の別の case 節にあるdeferは解放処理とみなさない
+// また、関数リテラル（defer func(){...}() 等）内で宣言された変数は、その関数リテラルと重ならないdeferでは解放されない
+func (da *DeferAnalyzer) IsDeferReachableFromCreation(body *ast.BlockStmt, resource ResourceInfo, deferStmt *ast.DeferStmt) bool {
+	if body == nil || deferStmt == nil || !resource.CreationPos.IsValid() {
+		return true
+	}
+
+	if funcLit := findEnclosingFuncLit(body, resource.CreationPos); funcLit != nil &&
+		resource.Variable != nil && resource.Variable.Pos() >= funcLit.Pos() && resource.Variable.Pos() < funcLit.End() &&
+		(deferStmt.End() <= funcLit.Pos() || deferStmt.Pos() >= funcLit.End()) {
+		return false
+	}
+
+	creationBlock := findEnclosingBlock(body, resource.CreationPos)
+	if creationBlock == nil {
+		return true
+	}
+
+	// 生成ブロック内（ネストしたブロックを含む）のdefer
+	if deferStmt.Pos() >= creationBlock.Pos() && deferStmt.End() <= creationBlock.End() {
+		return true
+	}
+
+	// 生成ブロックを内包する外側ブロックのdefer
+	deferBlock := findEnclosingBlock(body, deferStmt.Pos())
+	return deferBlock != nil &&
+		creationBlock.Pos() >= deferBlock.Pos() && creationBlock.End() <= deferBlock.End()
+}
+
+// FindDiscardedCleanupError は defer でリソースの解放メソッドを呼び、その戻り値のエラーを捨てている呼び出しを返す。
+// defer x.Close() と、defer したクロージャ直下の x.Close() / _ = x.Close() を対象とし、
+// エラーを変数に受け取る場合は確認しているとみなす
+func (da *DeferAnalyzer) FindDiscardedCleanupError(deferStmt *ast.DeferStmt, resource ResourceInfo) *ast.CallExpr {
+	if deferStmt == nil || deferStmt.Call == nil {
+		return nil
+	}
+	isCleanupCall := func(expr ast.Expr) (*ast.CallExpr, bool) {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		if !ok {
+			return nil, false
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		return call, ok && da.isDirectMethodCall(sel, resource)
+	}
+
+	if call, ok := isCleanupCall(deferStmt.Call); ok {
+		return call
+	}
+	funcLit, ok := deferStmt.Call.Fun.(*ast.FuncLit)
+	if !ok || funcLit.Body == nil {
+		return nil
+	}
+	for _, stmt := range funcLit.Body.List {
+		switch s := stmt.(type) {
+		case *ast.ExprStmt:
+			if call, ok := isCleanupCall(s.X); ok {
+				return call
+			}
+		case *ast.AssignStmt:
+			if len(s.Rhs) != 1 || !isBlankAssignment(s) {
+				continue
+			}
+			if call, ok := isCleanupCall(s.Rhs[0]); ok {
+				return call
+			}
+		}
+	}
+	return nil
+}
+
+// isBlankAssignment は代入の左辺がすべて _ かを判定する
+func isBlankAssignment(assign *ast.AssignStmt) bool {
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); !ok || ident.Name != "_" {
+			return false
+		}
+	}
+	return true
+}
+
+// findEnclosingFuncLit は指定位置を含む最も内側の関数リテラルを返す
+func findEnclosingFuncLit(root ast.Node, pos token.Pos) *ast.FuncLit {
+	var innermost *ast.FuncLit
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		if funcLit, ok := n.(*ast.FuncLit); ok {
+			innermost = funcLit
+		}
+		return true
+	})
+	return innermost
+}
+
+// findEnclosingBlock は指定位置を含む最も内側のブロック（BlockStmt/CaseClause/CommClause）を返す
+func findEnclosingBlock(root ast.Node, pos token.Pos) ast.Node {
+	var innermost ast.Node
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		switch n.(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			innermost = n
+		}
+		return true
+	})
+	return innermost
+}
+
+// deferInsertPos は解放の defer 文を挿入する位置として、生成を含む代入・宣言文の直後
+// （続けて err != nil を確認している場合はその if 文の後）を返す。
+// if/for の初期化文等での生成は変数のスコープが文の中に閉じるため NoPos を返す
+func (da *DeferAnalyzer) deferInsertPos(body *ast.BlockStmt, creationPos token.Pos) token.Pos {
+	var list []ast.Stmt
+	switch block := findEnclosingBlock(body, creationPos).(type) {
+	case *ast.BlockStmt:
+		list = block.List
+	case *ast.CaseClause:
+		list = block.Body
+	case *ast.CommClause:
+		list = block.Body
+	}
 
-			if !found {
-				diag := analysis.Diagnostic{
-					Pos:     resource.CreationPos,
-					End:     resource.CreationPos,
-					Message: da.generateDiagnosticMessage(resource),
-				}
-				diagnostics = append(diagnostics, diag)
+	var typeInfo *types.Info
+	if da.tracker != nil {
+		typeInfo = da.tracker.typeInfo
+	}
+	for i, stmt := range list {
+		if creationPos < stmt.Pos() || creationPos >= stmt.End() {
+			continue
+		}
+		switch stmt.(type) {
+		case *ast.AssignStmt, *ast.DeclStmt:
+		default:
+			return token.NoPos
+		}
+		if i+1 < len(list) {
+			if guard, ok := list[i+1].(*ast.IfStmt); ok && guard.Init == nil && isErrorGuard(typeInfo, guard.Cond) {
+				return guard.End()
 			}
 		}
+		return stmt.End()
 	}
-
-	return diagnostics
+	return token.NoPos
 }
 
 // FindDeferStatements はブロック内のdefer文を再帰的に検索する
@@ -88,9 +1227,7 @@ func (da *DeferAnalyzer) FindDeferStatements(block *ast.BlockStmt) []*ast.DeferS
 	var defers []*ast.DeferStmt
 
 	// ブロック内を走査
-	for _, stmt := range block.List {
-		da.collectDeferStatements(stmt, &defers)
-	}
+	da.collectDefersFromStatementList(block.List, &defers)
 
 	return defers
 }
@@ -104,8 +1241,93 @@ func (da *DeferAnalyzer) ValidateCleanupPattern(resource ResourceInfo, deferStmt
 	// defer文の呼び出しを解析
 	call := deferStmt.Call
 
+	// プールから借りたリソースは返却（pool.Put(x)）を解放処理とみなす
+	if resource.PoolExpr != "" {
+		return da.isPoolReturnCall(call, resource) || da.isClosureWithPoolReturn(call.Fun, resource)
+	}
+
 	// 新しいisResourceCloseCallロジックを使用してクロージャパターンも検出
-	return da.isResourceCloseCall(call.Fun, resource)
+	if da.isResourceCloseCall(call.Fun, resource) {
+		return true
+	}
+
+	// 引数でリソースを渡す即時実行クロージャ defer func(c *T) { c.Close() }(resourceVar)
+	if funcLit, ok := call.Fun.(*ast.FuncLit); ok {
+		for _, paramName := range da.findParamsBoundToResource(funcLit, call.Args, resource) {
+			aliased := resource
+			aliased.VariableName = paramName
+			if da.isClosureWithResourceClose(funcLit, aliased) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isPoolReturnCall は呼び出しがリソースをプールに返却する pool.Put(x)（返却メソッドは pool_return_method）かチェック
+func (da *DeferAnalyzer) isPoolReturnCall(call *ast.CallExpr, resource ResourceInfo) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != resource.CleanupMethod || len(call.Args) != 1 {
+		return false
+	}
+	if types.ExprString(sel.X) != resource.PoolExpr {
+		return false
+	}
+	arg, ok := call.Args[0].(*ast.Ident)
+	return ok && arg.Name == resource.VariableName
+}
+
+// isClosureWithPoolReturn はクロージャ内でリソースがプールに返却されているかチェック
+func (da *DeferAnalyzer) isClosureWithPoolReturn(expr ast.Expr, resource ResourceInfo) bool {
+	funcLit, ok := expr.(*ast.FuncLit)
+	if !ok || funcLit.Body == nil {
+		return false
+	}
+
+	found := false
+	ast.Inspect(funcLit.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && da.isPoolReturnCall(call, resource) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// findParamsBoundToResource はクロージャ呼び出しの引数のうちリソース変数が渡されるパラメータ名を返す
+func (da *DeferAnalyzer) findParamsBoundToResource(funcLit *ast.FuncLit, args []ast.Expr, resource ResourceInfo) []string {
+	if funcLit.Type == nil || funcLit.Type.Params == nil || resource.VariableName == "" {
+		return nil
+	}
+
+	var params []string
+	argIndex := 0
+	for _, field := range funcLit.Type.Params.List {
+		// 可変長引数はパラメータと引数の対応が取れないため対象外
+		if _, isVariadic := field.Type.(*ast.Ellipsis); isVariadic {
+			break
+		}
+
+		// 名前なしパラメータも引数を1つ消費する
+		names := field.Names
+		if len(names) == 0 {
+			argIndex++
+			continue
+		}
+
+		for _, name := range names {
+			if argIndex >= len(args) {
+				return params
+			}
+			if ident, ok := args[argIndex].(*ast.Ident); ok && ident.Name == resource.VariableName && name.Name != "_" {
+				params = append(params, name.Name)
+			}
+			argIndex++
+		}
+	}
+
+	return params
 }
 
 // FindBestMatchingDefer は位置に基づいてリソースに最適なdefer文を見つける
@@ -185,12 +1407,12 @@ func (da *DeferAnalyzer) HasMatchingVariableName(deferStmt *ast.DeferStmt, resou
 		if ident, ok := sel.X.(*ast.Ident); ok {
 			varName := ident.Name
 
-			// 1. 完全一致を最優先
-			if resource.VariableName != "" && varName == resource.VariableName {
-				return true
+			// 変数名が分かっている場合は完全一致のみ（別のクライアントの defer otherClient.Close() で代用しない）
+			if resource.VariableName != "" {
+				return varName == resource.VariableName
 			}
 
-			// 2. パターンマッチング
+			// 変数名が不明な場合のみパターンマッチング
 			if da.isValidVariableNamePattern(resource.CreationFunction, varName) {
 				return true
 			}
@@ -232,43 +1454,183 @@ func (da *DeferAnalyzer) IsAddedToDeferArray(block *ast.BlockStmt, resource Reso
 	return found
 }
 
-// isAppendToDeferArray は代入文がdefers配列への追加かチェック
-func (da *DeferAnalyzer) isAppendToDeferArray(assignStmt *ast.AssignStmt, resource ResourceInfo) bool {
-	// defers = append(defers, resourceVar.Close) の形式をチェック
-	if len(assignStmt.Lhs) != 1 || len(assignStmt.Rhs) != 1 {
+// IsRegisteredWithFinalizer はリソース変数が runtime.SetFinalizer の対象として登録されているかチェック
+func (da *DeferAnalyzer) IsRegisteredWithFinalizer(block *ast.BlockStmt, resource ResourceInfo) bool {
+	if block == nil || resource.VariableName == "" {
+		return false
+	}
+
+	found := false
+	ast.Inspect(block, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 || !da.isRuntimeSetFinalizer(call) {
+			return !found
+		}
+		if ident, ok := call.Args[0].(*ast.Ident); ok && ident.Name == resource.VariableName {
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
+
+// isRuntimeSetFinalizer は呼び出しが runtime.SetFinalizer かを判定する
+func (da *DeferAnalyzer) isRuntimeSetFinalizer(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "SetFinalizer" {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
 		return false
 	}
+	// 型情報があればインポートパスで判定し、なければ識別子名で判定する
+	if da.tracker != nil && da.tracker.typeInfo != nil {
+		if pkg, ok := da.tracker.typeInfo.Uses[ident].(*types.PkgName); ok {
+			return pkg.Imported().Path() == "runtime"
+		}
+	}
+	return ident.Name == "runtime"
+}
+
+// FindOnceWrappedDefer は sync.OnceFunc/sync.OnceValue でリソースの解放処理を包んだ関数を
+// 呼び出す defer 文を返す（closeFn := sync.OnceFunc(client.Close); defer closeFn()）
+func (da *DeferAnalyzer) FindOnceWrappedDefer(block *ast.BlockStmt, resource ResourceInfo, defers []*ast.DeferStmt) *ast.DeferStmt {
+	if block == nil || resource.VariableName == "" {
+		return nil
+	}
+
+	// 解放処理を包んだ関数を保持する変数名を収集
+	wrappers := make(map[string]bool)
+	ast.Inspect(block, func(n ast.Node) bool {
+		var names []*ast.Ident
+		var values []ast.Expr
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				ident, _ := lhs.(*ast.Ident)
+				names = append(names, ident)
+			}
+			values = node.Rhs
+		case *ast.ValueSpec:
+			names, values = node.Names, node.Values
+		default:
+			return true
+		}
+		if len(names) != len(values) {
+			return true
+		}
+		for i, value := range values {
+			if names[i] == nil || names[i].Name == "_" {
+				continue
+			}
+			if call, ok := value.(*ast.CallExpr); ok && da.isOnceWrappedClose(call, resource) {
+				wrappers[names[i].Name] = true
+			}
+		}
+		return true
+	})
+
+	for _, deferStmt := range defers {
+		if deferStmt.Call == nil || deferStmt.Pos() <= resource.CreationPos {
+			continue
+		}
+		switch fun := deferStmt.Call.Fun.(type) {
+		case *ast.Ident:
+			if wrappers[fun.Name] {
+				return deferStmt
+			}
+		case *ast.CallExpr:
+			// defer sync.OnceFunc(client.Close)()
+			if da.isOnceWrappedClose(fun, resource) {
+				return deferStmt
+			}
+		}
+	}
+
+	return nil
+}
 
-	// 左辺が "defers" 変数かチェック
-	if lhsIdent, ok := assignStmt.Lhs[0].(*ast.Ident); ok {
-		if lhsIdent.Name != "defers" {
+// isOnceWrappedClose は呼び出しがリソースの解放処理を引数とする sync.OnceFunc/sync.OnceValue かを判定する
+func (da *DeferAnalyzer) isOnceWrappedClose(call *ast.CallExpr, resource ResourceInfo) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 1 || (sel.Sel.Name != "OnceFunc" && sel.Sel.Name != "OnceValue") {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	// 型情報があればインポートパスで判定し、なければ識別子名で判定する
+	if da.tracker != nil && da.tracker.typeInfo != nil {
+		pkg, ok := da.tracker.typeInfo.Uses[ident].(*types.PkgName)
+		if !ok || pkg.Imported().Path() != "sync" {
 			return false
 		}
-	} else {
+	} else if ident.Name != "sync" {
 		return false
 	}
+	return da.isResourceCloseCall(call.Args[0], resource)
+}
 
-	// 右辺が append(defers, ...) かチェック
-	if callExpr, ok := assignStmt.Rhs[0].(*ast.CallExpr); ok {
-		if ident, ok := callExpr.Fun.(*ast.Ident); ok {
-			if ident.Name == "append" && len(callExpr.Args) >= 2 {
-				// 第一引数が "defers" かチェック
-				if firstArg, ok := callExpr.Args[0].(*ast.Ident); ok {
-					if firstArg.Name != "defers" {
-						return false
-					}
-				} else {
-					return false
-				}
+// IsRegisteredForCleanup はリソースの解放処理が設定された登録メソッドに渡されているかチェック
+func (da *DeferAnalyzer) IsRegisteredForCleanup(block *ast.BlockStmt, resource ResourceInfo) bool {
+	if block == nil || resource.VariableName == "" || da.tracker == nil || da.tracker.ruleEngine == nil {
+		return false
+	}
 
-				// 第二引数以降でresourceVar.Close を探す
-				for i := 1; i < len(callExpr.Args); i++ {
-					if da.isResourceCloseCall(callExpr.Args[i], resource) {
-						return true
-					}
-				}
+	found := false
+	ast.Inspect(block, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return !found
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !da.tracker.ruleEngine.IsDeferRegistrationMethod(sel.Sel.Name) {
+			return true
+		}
+
+		// 引数が resourceVar.Close またはそれを呼ぶクロージャか
+		for _, arg := range call.Args {
+			if da.isResourceCloseCall(arg, resource) {
+				found = true
+				break
 			}
 		}
+		return !found
+	})
+
+	return found
+}
+
+// isAppendToDeferArray は代入文が解放関数スライスへの追加かチェック
+func (da *DeferAnalyzer) isAppendToDeferArray(assignStmt *ast.AssignStmt, resource ResourceInfo) bool {
+	// s = append(s, resourceVar.Close) の形式をチェック（スライス名は問わない）
+	if len(assignStmt.Lhs) != 1 || len(assignStmt.Rhs) != 1 {
+		return false
+	}
+
+	callExpr, ok := assignStmt.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := callExpr.Fun.(*ast.Ident)
+	if !ok || ident.Name != "append" || len(callExpr.Args) < 2 {
+		return false
+	}
+
+	// 左辺と第一引数が同じスライスかチェック（_ への代入は保持されないため対象外）
+	lhs := types.ExprString(assignStmt.Lhs[0])
+	if lhs == "_" || lhs != types.ExprString(callExpr.Args[0]) {
+		return false
+	}
+
+	// 第二引数以降でresourceVar.Close を探す
+	for i := 1; i < len(callExpr.Args); i++ {
+		if da.isResourceCloseCall(callExpr.Args[i], resource) {
+			return true
+		}
 	}
 
 	return false
@@ -297,13 +1659,48 @@ func (da *DeferAnalyzer) isDirectMethodCall(sel *ast.SelectorExpr, resource Reso
 	}
 
 	// 変数名がリソース変数名と一致するかチェック
-	if ident, ok := sel.X.(*ast.Ident); ok {
-		return ident.Name == resource.VariableName
+	switch x := sel.X.(type) {
+	case *ast.Ident:
+		return x.Name == resource.VariableName && da.isMethodOfResourceType(sel, x, resource)
+	case *ast.IndexExpr:
+		// 定数インデックスの要素として追跡したリソース（defer clients[0].Close()）
+		var typeInfo *types.Info
+		if da.tracker != nil {
+			typeInfo = da.tracker.typeInfo
+		}
+		return resource.VariableName != "" && constantIndexName(typeInfo, x) == resource.VariableName
 	}
 
 	return false
 }
 
+// isMethodOfResourceType は型情報がある場合に、呼び出されたメソッドがリソースの型のもの
+// （埋め込みによる昇格を含む）かを検証する。同名の別の型の変数による Close を解放処理とみなさない。
+// 型情報がない場合は名前による判定のみとする
+func (da *DeferAnalyzer) isMethodOfResourceType(sel *ast.SelectorExpr, ident *ast.Ident, resource ResourceInfo) bool {
+	if da.tracker == nil || da.tracker.typeInfo == nil || resource.Variable == nil {
+		return true
+	}
+	typeInfo := da.tracker.typeInfo
+
+	// 同じ変数、または同じ型の変数（再代入前の別名など）であること
+	obj, ok := typeInfo.Uses[ident].(*types.Var)
+	if !ok {
+		return true
+	}
+	if obj != resource.Variable && !types.Identical(obj.Type(), resource.Variable.Type()) {
+		return false
+	}
+
+	// 呼び出されたメソッドがリソースの型のメソッドセットに含まれること
+	selection, ok := typeInfo.Selections[sel]
+	if !ok {
+		return true
+	}
+	method, _, _ := types.LookupFieldOrMethod(resource.Variable.Type(), true, resource.Variable.Pkg(), resource.CleanupMethod)
+	return method != nil && method == selection.Obj()
+}
+
 // isClosureWithResourceClose はクロージャ内でリソースのCloseが呼ばれているかチェック
 func (da *DeferAnalyzer) isClosureWithResourceClose(funcLit *ast.FuncLit, resource ResourceInfo) bool {
 	if funcLit == nil || funcLit.Body == nil {
@@ -314,6 +1711,9 @@ func (da *DeferAnalyzer) isClosureWithResourceClose(funcLit *ast.FuncLit, resour
 	found := false
 	ast.Inspect(funcLit.Body, func(n ast.Node) bool {
 		switch node := n.(type) {
+		case *ast.SelectStmt:
+			// select の case 内の解放はその case が選ばれたときだけ実行されるため、解放処理とみなさない
+			return false
 		case *ast.CallExpr:
 			// メソッド呼び出しをチェック
 			if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
@@ -347,12 +1747,15 @@ func (da *DeferAnalyzer) isValidVariableNamePattern(creationFunction, varName st
 		"NewClient":            da.isValidClientVariableName,
 		"NewWriter":            da.isValidWriterVariableName,
 		"NewReader":            da.isValidReaderVariableName,
+		"NewRangeReader":       da.isValidReaderVariableName,
 		"Query":                da.isValidQueryVariableName,
 		"QueryWithOptions":     da.isValidQueryVariableName,
 		"Read":                 da.isValidQueryVariableName,
 		"ReadWithOptions":      da.isValidQueryVariableName,
 		"ReadWriteTransaction": da.isValidTransactionVariableName,
 		"ReadOnlyTransaction":  da.isValidTransactionVariableName,
+		"Topic":                da.isValidTopicVariableName,
+		"TopicInProject":       da.isValidTopicVariableName,
 	}
 
 	if validator, exists := validators[creationFunction]; exists {
@@ -388,29 +1791,125 @@ func (da *DeferAnalyzer) isValidTransactionVariableName(varName string) bool {
 		strings.Contains(varName, "tx") || strings.Contains(varName, "Tx")
 }
 
+func (da *DeferAnalyzer) isValidTopicVariableName(varName string) bool {
+	return strings.Contains(varName, "topic") || strings.Contains(varName, "Topic") ||
+		varName == "t"
+}
+
 // ValidateCleanupOrder はdefer文の順序が適切かを検証する
-// Goのdeferはスタック（LIFO）なので、依存関係の逆順で呼び出される
+// Goのdeferはスタック（LIFO）なので、依存関係の逆順で呼び出される必要がある
+// つまり defer client.Close(), defer txn.Close(), defer iter.Stop() の順に記述する
 func (da *DeferAnalyzer) ValidateCleanupOrder(block *ast.BlockStmt) bool {
+	return len(da.findCleanupOrderViolations(block)) == 0
+}
+
+// cleanupOrderViolation は依存先のリソースが依存元より先に解放される defer の組
+type cleanupOrderViolation struct {
+	dependent       string         // 依存元の変数（iter など）
+	dependency      string         // 依存先の変数（txn, client など）
+	dependentDefer  *ast.DeferStmt // 依存元の解放 defer
+	dependencyDefer *ast.DeferStmt // 依存元より後に記述され、先に実行される依存先の解放 defer
+}
+
+// findCleanupOrderViolations は defer の記述順から、依存先が先に解放されてしまう組を検出する。
+// 依存関係は x := y.Method(...) の形の代入（x は y から生成された）から推定する
+func (da *DeferAnalyzer) findCleanupOrderViolations(block *ast.BlockStmt) []cleanupOrderViolation {
 	if block == nil {
-		return true
+		return nil
 	}
 
 	defers := da.FindDeferStatements(block)
 	if len(defers) <= 1 {
-		return true // 単一または0個のdeferは常に正しい
+		return nil // 単一または0個のdeferは常に正しい
 	}
 
-	// defer文の順序を検証
-	// RowIterator.Stop() → Transaction.Close() → Client.Close() の逆順
-	// つまり defer client.Close(), defer txn.Close(), defer iter.Stop() の順
-	var deferTypes []string
-	for _, deferStmt := range defers {
-		resourceType := da.identifyResourceTypeFromDefer(deferStmt)
-		deferTypes = append(deferTypes, resourceType)
+	parents := collectReceiverDependencies(block)
+	receivers := make([]string, len(defers))
+	firstDefer := make(map[string]int)
+	for i, deferStmt := range defers {
+		receivers[i] = deferredReceiver(deferStmt)
+		if _, seen := firstDefer[receivers[i]]; receivers[i] != "" && !seen {
+			firstDefer[receivers[i]] = i
+		}
+	}
+
+	var violations []cleanupOrderViolation
+	for i, receiver := range receivers {
+		if receiver == "" {
+			continue
+		}
+		// 依存先を辿り、この defer より後に解放が記述されている最も近い依存先を探す
+		visited := map[string]bool{receiver: true}
+		for parent := parents[receiver]; parent != "" && !visited[parent]; parent = parents[parent] {
+			visited[parent] = true
+			if j, ok := firstDefer[parent]; ok && j > i {
+				violations = append(violations, cleanupOrderViolation{
+					dependent:       receiver,
+					dependency:      parent,
+					dependentDefer:  defers[i],
+					dependencyDefer: defers[j],
+				})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// collectReceiverDependencies は x := y.Method(...) の形の代入から、変数 x -> y の依存関係を収集する
+func collectReceiverDependencies(block *ast.BlockStmt) map[string]string {
+	parents := make(map[string]string)
+	ast.Inspect(block, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+			return true
+		}
+		lhs, ok := assign.Lhs[0].(*ast.Ident)
+		if !ok || lhs.Name == "_" {
+			return true
+		}
+		if call, ok := assign.Rhs[0].(*ast.CallExpr); ok {
+			if root := receiverRoot(call.Fun); root != "" && root != lhs.Name {
+				parents[lhs.Name] = root
+			}
+		}
+		return true
+	})
+	return parents
+}
+
+// receiverRoot は client.Single().Query のようなメソッド呼び出しの起点となる変数名を返す
+func receiverRoot(fun ast.Expr) string {
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
 	}
+	expr := sel.X
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e.Name
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.CallExpr:
+			expr = e.Fun
+		default:
+			return ""
+		}
+	}
+}
 
-	// 期待される順序（deferの実行とは逆順）
-	return da.isValidDeferOrder(deferTypes)
+// deferredReceiver は defer x.Method(...) の x を返す
+func deferredReceiver(deferStmt *ast.DeferStmt) string {
+	if deferStmt == nil || deferStmt.Call == nil {
+		return ""
+	}
+	if sel, ok := deferStmt.Call.Fun.(*ast.SelectorExpr); ok {
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			return ident.Name
+		}
+	}
+	return ""
 }
 
 // analyzeFunction は関数内のリソース生成を解析する
@@ -426,6 +1925,8 @@ func (da *DeferAnalyzer) collectDeferStatements(stmt ast.Stmt, defers *[]*ast.De
 		*defers = append(*defers, s)
 	case *ast.BlockStmt:
 		da.collectDefersFromBlockStmt(s, defers)
+	case *ast.LabeledStmt:
+		da.collectDeferStatements(s.Stmt, defers)
 	case *ast.IfStmt:
 		da.collectDefersFromIfStmt(s, defers)
 	case *ast.ForStmt:
@@ -444,15 +1945,53 @@ func (da *DeferAnalyzer) collectDeferStatements(stmt ast.Stmt, defers *[]*ast.De
 		da.collectDefersFromCommClause(s, defers)
 	case *ast.ExprStmt:
 		da.collectDeferFromExpression(s.X, defers)
+	case *ast.GoStmt:
+		da.collectDefersFromGoStmt(s, defers)
 	case *ast.AssignStmt:
 		da.collectDefersFromAssignStmt(s, defers)
+	case *ast.ReturnStmt:
+		for _, result := range s.Results {
+			da.collectDeferFromExpression(result, defers)
+		}
+	case *ast.DeclStmt:
+		da.collectDefersFromDeclStmt(s, defers)
 	}
 }
 
 func (da *DeferAnalyzer) collectDefersFromBlockStmt(s *ast.BlockStmt, defers *[]*ast.DeferStmt) {
-	for _, blockStmt := range s.List {
-		da.collectDeferStatements(blockStmt, defers)
+	da.collectDefersFromStatementList(s.List, defers)
+}
+
+// collectDefersFromStatementList は文の並びからdefer文を収集する。
+// 無条件の return/panic より後の文は実行されないため、その defer は登録されず収集しない
+// （goto のラベル付き文からは再び到達可能とみなす）
+func (da *DeferAnalyzer) collectDefersFromStatementList(stmts []ast.Stmt, defers *[]*ast.DeferStmt) {
+	unreachable := false
+	for _, stmt := range stmts {
+		if _, ok := stmt.(*ast.LabeledStmt); ok {
+			unreachable = false
+		}
+		if unreachable {
+			continue
+		}
+		da.collectDeferStatements(stmt, defers)
+		unreachable = isUnconditionalExit(stmt)
+	}
+}
+
+// isUnconditionalExit は文が無条件に関数を抜ける return または panic かを判定する
+func isUnconditionalExit(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+				return true
+			}
+		}
 	}
+	return false
 }
 
 func (da *DeferAnalyzer) collectDefersFromIfStmt(s *ast.IfStmt, defers *[]*ast.DeferStmt) {
@@ -495,15 +2034,22 @@ func (da *DeferAnalyzer) collectDefersFromSelectStmt(s *ast.SelectStmt, defers *
 }
 
 func (da *DeferAnalyzer) collectDefersFromCaseClause(s *ast.CaseClause, defers *[]*ast.DeferStmt) {
-	for _, caseStmt := range s.Body {
-		da.collectDeferStatements(caseStmt, defers)
-	}
+	da.collectDefersFromStatementList(s.Body, defers)
 }
 
 func (da *DeferAnalyzer) collectDefersFromCommClause(s *ast.CommClause, defers *[]*ast.DeferStmt) {
-	for _, commStmt := range s.Body {
-		da.collectDeferStatements(commStmt, defers)
+	da.collectDefersFromStatementList(s.Body, defers)
+}
+
+// collectDefersFromGoStmt は go func() { ... }() で起動されるゴルーチン内のdefer文を収集する
+func (da *DeferAnalyzer) collectDefersFromGoStmt(s *ast.GoStmt, defers *[]*ast.DeferStmt) {
+	if s.Call == nil {
+		return
+	}
+	if funcLit, ok := s.Call.Fun.(*ast.FuncLit); ok && funcLit.Body != nil {
+		da.collectDeferStatements(funcLit.Body, defers)
 	}
+	da.collectDeferFromExpression(s.Call, defers)
 }
 
 func (da *DeferAnalyzer) collectDefersFromAssignStmt(s *ast.AssignStmt, defers *[]*ast.DeferStmt) {
@@ -512,10 +2058,45 @@ func (da *DeferAnalyzer) collectDefersFromAssignStmt(s *ast.AssignStmt, defers *
 	}
 }
 
-// collectDeferFromExpression は式の中のクロージャからdefer文を収集する
+// collectDefersFromDeclStmt は var h = func() { ... } のように宣言で初期化するクロージャからdefer文を収集する
+func (da *DeferAnalyzer) collectDefersFromDeclStmt(s *ast.DeclStmt, defers *[]*ast.DeferStmt) {
+	genDecl, ok := s.Decl.(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+			for _, value := range valueSpec.Values {
+				da.collectDeferFromExpression(value, defers)
+			}
+		}
+	}
+}
+
+// collectDeferFromExpression は式の中のクロージャからdefer文を収集する。
+// フィールドやマップに保存されるコールバック等、代入先に関わらずクロージャ本体を対象にする
+// （クロージャ内で生成したリソースは、そのクロージャ内の defer と照合される）
 func (da *DeferAnalyzer) collectDeferFromExpression(expr ast.Expr, defers *[]*ast.DeferStmt) {
 	switch e := expr.(type) {
+	case *ast.FuncLit:
+		if e.Body != nil {
+			da.collectDeferStatements(e.Body, defers)
+		}
+	case *ast.CompositeLit:
+		// map[string]func(){"x": func() { ... }} のようなコールバックのテーブル
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			da.collectDeferFromExpression(elt, defers)
+		}
+	case *ast.UnaryExpr:
+		da.collectDeferFromExpression(e.X, defers)
 	case *ast.CallExpr:
+		// 即時実行されるクロージャ func() { ... }()
+		if funcLit, ok := e.Fun.(*ast.FuncLit); ok && funcLit.Body != nil {
+			da.collectDeferStatements(funcLit.Body, defers)
+		}
 		// 関数呼び出しの引数にクロージャがある場合
 		for _, arg := range e.Args {
 			if funcLit, ok := arg.(*ast.FuncLit); ok {
@@ -566,17 +2147,6 @@ func (da *DeferAnalyzer) extractMethodFromDefer(deferStmt *ast.DeferStmt) string
 	return ""
 }
 
-// isValidDeferOrder はdefer文の順序が適切かを判定する
-func (da *DeferAnalyzer) isValidDeferOrder(_ []string) bool {
-	// 簡単な実装：現在は常にtrueを返す
-	// 実際の実装では、依存関係を考慮した順序チェックを行う
-
-	// 例：Client -> Transaction -> Iterator の逆順（defer実行順序）
-	// defer client.Close() -> defer txn.Close() -> defer iter.Stop()
-
-	return true // 現在は順序チェックをスキップ
-}
-
 // generateDiagnosticMessage はリソースに対する診断メッセージを生成する
 func (da *DeferAnalyzer) generateDiagnosticMessage(resource ResourceInfo) string {
 	varName := resource.VariableName
@@ -588,6 +2158,38 @@ func (da *DeferAnalyzer) generateDiagnosticMessage(resource ResourceInfo) string
 	return "GCP リソース '" + varName + "' の解放処理 (" + method + ") が見つかりません"
 }
 
+// generateUnusedResourceMessage は生成後に一度も使用されないリソースの診断メッセージを生成する
+func (da *DeferAnalyzer) generateUnusedResourceMessage(resource ResourceInfo) string {
+	return fmt.Sprintf(messages.UnusedResource, resource.VariableName, resource.CleanupMethod)
+}
+
+// generateReassignedElementMessage は再代入された要素経由の解放の診断メッセージを生成する
+func (da *DeferAnalyzer) generateReassignedElementMessage(resource ResourceInfo, elementName string) string {
+	if resource.VariableName == elementName {
+		return fmt.Sprintf(messages.ReassignedElement,
+			elementName, resource.CleanupMethod)
+	}
+	return fmt.Sprintf(messages.ReassignedHeldElement,
+		resource.VariableName, elementName, resource.CleanupMethod, resource.VariableName)
+}
+
+// generateLoopOverwriteMessage はループ内の再代入によるリークの診断メッセージを生成する
+func (da *DeferAnalyzer) generateLoopOverwriteMessage(resource ResourceInfo) string {
+	return fmt.Sprintf(messages.LoopOverwrite, resource.VariableName, resource.CleanupMethod)
+}
+
+// generateDeferInLoopMessage はループ内の defer で解放が関数終了まで遅れる場合のメッセージを生成する
+func (da *DeferAnalyzer) generateDeferInLoopMessage(resource ResourceInfo) string {
+	return fmt.Sprintf(messages.DeferInLoop, resource.VariableName, resource.CleanupMethod)
+}
+
+// generateCleanupOrderMessage は defer の順序により依存先が先に解放される場合のメッセージを生成する
+func generateCleanupOrderMessage(violation cleanupOrderViolation) string {
+	return fmt.Sprintf(messages.CleanupOrder,
+		types.ExprString(violation.dependencyDefer.Call), types.ExprString(violation.dependentDefer.Call),
+		violation.dependent, violation.dependency, violation.dependency)
+}
+
 // DeferInfo はdefer文に関する情報を保持する
 type DeferInfo struct {
 	DeferStmt    *ast.DeferStmt
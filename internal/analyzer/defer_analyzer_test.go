@@ -5,6 +5,8 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -348,45 +350,335 @@ func test(ctx context.Context) {
 
 func TestDeferAnalyzer_CleanupOrderValidation(t *testing.T) {
 	// Cleanup order test: RowIterator → Transaction → Client
-	code := `
+	tests := []struct {
+		name       string
+		defers     string
+		validOrder bool
+	}{
+		{
+			name: "Client, transaction, iterator (LIFO releases iterator first)",
+			defers: `defer client.Close()  // Third (last)
+	defer txn.Close()     // Second
+	defer iter.Stop()     // First`,
+			validOrder: true,
+		},
+		{
+			name: "Inverted order closes the client first",
+			defers: `defer iter.Stop()
+	defer txn.Close()
+	defer client.Close()`,
+			validOrder: false,
+		},
+		{
+			name: "Client closed before the transaction",
+			defers: `defer txn.Close()
+	defer client.Close()`,
+			validOrder: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := `
 package test
 import "cloud.google.com/go/spanner"
 func test(ctx context.Context) {
 	client, _ := spanner.NewClient(ctx, "test")
 	txn := client.ReadOnlyTransaction()
 	iter := txn.Query(ctx, spanner.NewStatement("SELECT 1"))
-	defer client.Close()  // Third (last)
-	defer txn.Close()     // Second
-	defer iter.Stop()     // First
+	` + tt.defers + `
 }`
 
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("Failed to parse code: %v", err)
+			}
+
+			analyzer := createTestDeferAnalyzer(t)
+
+			// Find function
+			var fn *ast.FuncDecl
+			for _, decl := range file.Decls {
+				if f, ok := decl.(*ast.FuncDecl); ok {
+					fn = f
+					break
+				}
+			}
+
+			if fn == nil {
+				t.Fatal("Function not found")
+			}
+
+			if got := analyzer.ValidateCleanupOrder(fn.Body); got != tt.validOrder {
+				t.Errorf("ValidateCleanupOrder() = %v, want %v", got, tt.validOrder)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CleanupOrderDiagnostics(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func ordered(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
+
+func inverted(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	defer client.Close()
+}
+`
+
+	if diagnostics := runAnalyzerOnSource(t, "test.go", src); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics without -gcpcleanup-order, got %v", diagnostics)
+	}
+
+	diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{CleanupOrder: true})
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic with -gcpcleanup-order, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if diagnostics[0].Category != CategoryCleanupOrder || !strings.Contains(diagnostics[0].Message, "defer client.Close()") {
+		t.Errorf("Unexpected diagnostic: %+v", diagnostics[0])
+	}
+}
+
+func TestDeferAnalyzer_SpannerCleanupOrder(t *testing.T) {
+	// Client → ReadOnlyTransaction → RowIterator must be released in reverse order
+	tests := []struct {
+		name         string
+		defers       string
+		expectedMsgs []string
+	}{
+		{
+			name: "correct order",
+			defers: `defer client.Close()
+	defer txn.Close()
+	defer iter.Stop()`,
+		},
+		{
+			name: "fully inverted",
+			defers: `defer iter.Stop()
+	defer txn.Close()
+	defer client.Close()`,
+			expectedMsgs: []string{"defer txn.Close()", "defer client.Close()"},
+		},
+		{
+			name: "client closed before transaction and iterator",
+			defers: `defer txn.Close()
+	defer iter.Stop()
+	defer client.Close()`,
+			expectedMsgs: []string{"defer client.Close()"},
+		},
+		{
+			name: "transaction closed before iterator",
+			defers: `defer client.Close()
+	defer iter.Stop()
+	defer txn.Close()`,
+			expectedMsgs: []string{"defer txn.Close()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	client, _ := spanner.NewClient(ctx, "db")
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.NewStatement("SELECT 1"))
+	` + tt.defers + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{CleanupOrder: true})
+			if len(diagnostics) != len(tt.expectedMsgs) {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", len(tt.expectedMsgs), len(diagnostics), diagnostics)
+			}
+			for i, want := range tt.expectedMsgs {
+				if diagnostics[i].Category != CategoryCleanupOrder || !strings.HasPrefix(diagnostics[i].Message, want) {
+					t.Errorf("diagnostic %d = %q, want prefix %q", i, diagnostics[i].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_DeferOnDifferentClient(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	clientA, _ := spanner.NewClient(ctx, "a")
+	clientB, _ := spanner.NewClient(ctx, "b")
+	defer clientA.Close()
+	defer clientA.Close()
+	_ = clientB
+}
+`
+
+	diagnostics := runAnalyzerOnSource(t, "test.go", src)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if len(diagnosticsContaining(diagnostics, "'clientB'")) != 1 {
+		t.Errorf("Expected the leak of clientB to be reported, got %v", diagnostics)
+	}
+
+	// A named resource must not be matched to the defer of a similarly named client
 	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	file, err := parser.ParseFile(fset, "test.go", src, 0)
 	if err != nil {
 		t.Fatalf("Failed to parse code: %v", err)
 	}
-
+	fn := file.Decls[1].(*ast.FuncDecl)
 	analyzer := createTestDeferAnalyzer(t)
+	defers := analyzer.FindDeferStatements(fn.Body)
+	resource := ResourceInfo{VariableName: "clientB", CreationFunction: "NewClient", CleanupMethod: "Close", CreationPos: fn.Body.List[1].Pos()}
+	if got := analyzer.FindBestMatchingDefer(resource, defers); got != nil {
+		t.Errorf("FindBestMatchingDefer() matched %s for clientB", types.ExprString(got.Call))
+	}
+}
 
-	// Find function
-	var fn *ast.FuncDecl
-	for _, decl := range file.Decls {
-		if f, ok := decl.(*ast.FuncDecl); ok {
-			fn = f
-			break
+func TestDeferAnalyzer_BooleanGuardedDefer(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedCategory string // empty means no diagnostic
+		expectedSeverity string
+	}{
+		{
+			name: "boolean-guarded defer",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	if !alreadyClosed {
+		defer client.Close()
+	}`,
+			expectedCategory: CategoryConditionalCleanup,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name: "field-guarded defer in else branch",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	if opts.shared {
+		_ = client
+	} else {
+		defer client.Close()
+	}`,
+			expectedCategory: CategoryConditionalCleanup,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name: "boolean-guarded deferred closure",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	if !alreadyClosed {
+		defer func() {
+			client.Close()
+		}()
+	}`,
+			expectedCategory: CategoryConditionalCleanup,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name: "boolean guard inside deferred closure",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	defer func() {
+		if !alreadyClosed {
+			client.Close()
+		}
+	}()`,
+			expectedCategory: CategoryConditionalCleanup,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name: "nil-checked close inside deferred closure",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	defer func() {
+		if client != nil {
+			client.Close()
 		}
+	}()`,
+		},
+		{
+			name: "no defer",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	_ = client`,
+			expectedCategory: CategoryResourceLeak,
+			expectedSeverity: SeverityError,
+		},
+		{
+			name: "error-checked defer",
+			body: `client, err := spanner.NewClient(ctx, "db")
+	if err == nil {
+		defer client.Close()
+	}`,
+		},
+		{
+			name: "unconditional defer",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()`,
+		},
 	}
 
-	if fn == nil {
-		t.Fatal("Function not found")
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
 
-	// Validate defer order
-	isValidOrder := analyzer.ValidateCleanupOrder(fn.Body)
+type options struct{ shared bool }
 
-	// Test should succeed because proper order (reverse) is maintained
-	if !isValidOrder {
-		t.Error("defer statement order should be appropriate")
+func run(ctx context.Context, alreadyClosed bool, opts options) {
+	` + tt.body + `
+}
+`
+			if tt.expectedCategory == CategoryConditionalCleanup {
+				if diagnostics := runAnalyzerOnSource(t, "test.go", src); len(diagnostics) != 0 {
+					t.Errorf("Expected no diagnostics without -gcpconditional-defer, got %v", diagnostics)
+				}
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ConditionalDefer: true})
+			if tt.expectedCategory == "" {
+				if len(diagnostics) != 0 {
+					t.Errorf("Expected no diagnostics, got %v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+			}
+			if diagnostics[0].Category != tt.expectedCategory {
+				t.Errorf("Category = %q, want %q", diagnostics[0].Category, tt.expectedCategory)
+			}
+			if got := SeverityOf(diagnostics[0].Category); got != tt.expectedSeverity {
+				t.Errorf("Severity = %q, want %q", got, tt.expectedSeverity)
+			}
+		})
 	}
 }
 
@@ -467,7 +759,7 @@ func testScopeAwareDeferValidation() {
 }`,
 			expectedResources:    1,
 			expectedMissingDefer: 0, // properly deferred
-			expectedDeferFound:   1, // Actual count from current analysis
+			expectedDeferFound:   2, // including the defer in the immediately invoked closure
 		},
 		{
 			name: "Support for multiple context.WithTimeout/WithCancel calls",
@@ -807,3 +1099,1442 @@ func containsJapaneseChars(text string) bool {
 	}
 	return false
 }
+
+func TestDeferAnalyzer_TypeSwitchCaseScope(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedCount int
+	}{
+		{
+			name: "Defer in sibling case does not cover resource",
+			src: `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type A struct{}
+type B struct{}
+
+func handle(ctx context.Context, v interface{}, shared *spanner.Client) {
+	switch v.(type) {
+	case *A:
+		client, err := spanner.NewClient(ctx, "db")
+		if err != nil {
+			return
+		}
+		_ = client
+	case *B:
+		client := shared
+		defer client.Close()
+	}
+}
+`,
+			expectedCount: 1,
+		},
+		{
+			name: "Defer in same case covers resource",
+			src: `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type A struct{}
+
+func handle(ctx context.Context, v interface{}) {
+	switch v.(type) {
+	case *A:
+		client, err := spanner.NewClient(ctx, "db")
+		if err != nil {
+			return
+		}
+		defer client.Close()
+	}
+}
+`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSource(t, "test.go", tt.src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_ArgumentCapturedClosure(t *testing.T) {
+	tests := []struct {
+		name          string
+		deferStmt     string
+		expectedCount int
+	}{
+		{
+			name:          "Resource passed to closure parameter",
+			deferStmt:     `defer func(c *spanner.Client) { c.Close() }(client)`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Resource passed after another argument",
+			deferStmt:     `defer func(name string, c *spanner.Client) { _ = name; c.Close() }("db", client)`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Different value passed to closure parameter",
+			deferStmt:     `defer func(c *spanner.Client) { c.Close() }(other)`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context, other *spanner.Client) {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	` + tt.deferStmt + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_MethodValueNotInvoked(t *testing.T) {
+	tests := []struct {
+		name          string
+		cleanup       string
+		expectedCount int
+	}{
+		{
+			name:          "Method value discarded without call",
+			cleanup:       `_ = client.Close`,
+			expectedCount: 1,
+		},
+		{
+			name:          "Method value appended to discarded slice",
+			cleanup:       `_ = append(cleanups, client.Close)`,
+			expectedCount: 1,
+		},
+		{
+			name:          "Deferred call",
+			cleanup:       `defer client.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Method value appended to cleanup slice",
+			cleanup:       `cleanups = append(cleanups, client.Close)`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	var cleanups []func()
+	defer func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	` + tt.cleanup + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_SelectCaseCreation(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseBody      string
+		expectedCount int
+	}{
+		{
+			name: "Leaked client inside select case",
+			caseBody: `case <-ch:
+		client, _ := spanner.NewClient(ctx, "db")
+		_ = client`,
+			expectedCount: 1,
+		},
+		{
+			name: "Client closed within the same case",
+			caseBody: `case <-ch:
+		client, _ := spanner.NewClient(ctx, "db")
+		defer client.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Close only in a sibling case",
+			caseBody: `case <-ch:
+		client, _ := spanner.NewClient(ctx, "db")
+		_ = client
+	case <-done:
+		var client *spanner.Client
+		defer client.Close()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Leaked client in goroutine spawned from case",
+			caseBody: `case req := <-ch:
+		go func() {
+			client, _ := spanner.NewClient(ctx, req)
+			_ = client
+		}()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Client closed in goroutine spawned from case",
+			caseBody: `case req := <-ch:
+		go func() {
+			client, _ := spanner.NewClient(ctx, req)
+			defer client.Close()
+		}()`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func serve(ctx context.Context, ch chan string, done chan struct{}) {
+	for {
+		select {
+		` + tt.caseBody + `
+		}
+	}
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_SelectGatedCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		cleanup       string
+		expectedCount int
+	}{
+		{
+			name: "Direct Close inside a select case",
+			cleanup: `select {
+	case <-done:
+		client.Close()
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "Deferred closure closing only in a select case",
+			cleanup: `defer func() {
+		select {
+		case <-done:
+			client.Close()
+		default:
+		}
+	}()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Deferred closure closing after the select",
+			cleanup: `defer func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		client.Close()
+	}()`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context, done chan struct{}) {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	` + tt.cleanup + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CustomCleanupStack(t *testing.T) {
+	writeConfig := func(t *testing.T, registrationMethods string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+` + registrationMethods
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name          string
+		registration  string
+		register      string
+		expectedCount int
+	}{
+		{
+			name:          "Method value registered on configured method",
+			registration:  "defer_registration_methods: [Defer]\n",
+			register:      `stack.Defer(client.Close)`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Closure registered on configured method",
+			registration:  "defer_registration_methods: [Defer]\n",
+			register:      `stack.Defer(func() { client.Close() })`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Registration method not configured",
+			registration:  "",
+			register:      `stack.Defer(client.Close)`,
+			expectedCount: 1,
+		},
+		{
+			name:          "Different resource registered",
+			registration:  "defer_registration_methods: [Defer]\n",
+			register:      `stack.Defer(other.Close)`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type cleanupStack struct {
+	funcs []func()
+}
+
+func (s *cleanupStack) Defer(f func()) {
+	s.funcs = append(s.funcs, f)
+}
+
+func (s *cleanupStack) Run() {
+	for i := len(s.funcs) - 1; i >= 0; i-- {
+		s.funcs[i]()
+	}
+}
+
+func run(ctx context.Context, other *spanner.Client) {
+	stack := &cleanupStack{}
+	defer stack.Run()
+
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	` + tt.register + `
+}
+`
+			opts := Options{ConfigPath: writeConfig(t, tt.registration)}
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, opts)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_OverwrittenInLoop(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+		expectLoopMsg bool
+	}{
+		{
+			name: "Client reassigned in range loop with defer outside",
+			body: `var client *spanner.Client
+	for _, db := range dbs {
+		client, _ = spanner.NewClient(ctx, db)
+	}
+	defer client.Close()`,
+			expectedCount: 1,
+			expectLoopMsg: true,
+		},
+		{
+			name: "Client reassigned in for loop with defer outside",
+			body: `var client *spanner.Client
+	for i := 0; i < len(dbs); i++ {
+		client, _ = spanner.NewClient(ctx, dbs[i])
+	}
+	defer client.Close()`,
+			expectedCount: 1,
+			expectLoopMsg: true,
+		},
+		{
+			name: "Previous client closed before reassignment",
+			body: `var client *spanner.Client
+	for _, db := range dbs {
+		if client != nil {
+			client.Close()
+		}
+		client, _ = spanner.NewClient(ctx, db)
+	}
+	defer client.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Retry loop breaking on success",
+			body: `var client *spanner.Client
+	var err error
+	for _, db := range dbs {
+		client, err = spanner.NewClient(ctx, db)
+		if err == nil {
+			break
+		}
+	}
+	defer client.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Return only inside the error check",
+			body: `var client *spanner.Client
+	var err error
+	for _, db := range dbs {
+		client, err = spanner.NewClient(ctx, db)
+		if err != nil {
+			return
+		}
+	}
+	defer client.Close()`,
+			expectedCount: 1,
+			expectLoopMsg: true,
+		},
+		{
+			name: "Client declared and closed per iteration",
+			body: `for _, db := range dbs {
+		client, _ := spanner.NewClient(ctx, db)
+		defer client.Close()
+	}`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func connect(ctx context.Context, dbs []string) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+			if tt.expectLoopMsg && !strings.Contains(diagnostics[0].Message, "reassigned in a loop") {
+				t.Errorf("Expected loop overwrite message, got %q", diagnostics[0].Message)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_FinalizerCleanup(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		enabled          bool
+		expectedCategory string // 空の場合は診断なし
+	}{
+		{
+			name: "Finalizer registered with method expression",
+			body: `runtime.SetFinalizer(client, (*spanner.Client).Close)
+	return client`,
+			enabled:          true,
+			expectedCategory: CategoryFinalizerCleanup,
+		},
+		{
+			name: "Finalizer registered with closure",
+			body: `runtime.SetFinalizer(client, func(c *spanner.Client) { c.Close() })
+	return client`,
+			enabled:          true,
+			expectedCategory: CategoryFinalizerCleanup,
+		},
+		{
+			name: "Check disabled reports a plain leak",
+			body: `runtime.SetFinalizer(client, (*spanner.Client).Close)
+	return nil`,
+			enabled:          false,
+			expectedCategory: CategoryResourceLeak,
+		},
+		{
+			name: "Check disabled keeps returned client as escaped",
+			body: `runtime.SetFinalizer(client, (*spanner.Client).Close)
+	return client`,
+			enabled:          false,
+			expectedCategory: "",
+		},
+		{
+			name: "Explicit Close alongside finalizer",
+			body: `runtime.SetFinalizer(client, (*spanner.Client).Close)
+	defer client.Close()
+	return nil`,
+			enabled:          true,
+			expectedCategory: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"runtime"
+
+	"cloud.google.com/go/spanner"
+)
+
+func open(ctx context.Context) *spanner.Client {
+	client, _ := spanner.NewClient(ctx, "db")
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{Finalizer: tt.enabled})
+			if tt.expectedCategory == "" {
+				if len(diagnostics) != 0 {
+					t.Errorf("Expected no diagnostics, got %v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+			}
+			if diagnostics[0].Category != tt.expectedCategory {
+				t.Errorf("Expected category %q, got %q (%s)", tt.expectedCategory, diagnostics[0].Category, diagnostics[0].Message)
+			}
+			if tt.expectedCategory == CategoryFinalizerCleanup &&
+				diagnostics[0].Message != "relying on finalizer for cleanup is unreliable; prefer explicit Close" {
+				t.Errorf("Unexpected message: %s", diagnostics[0].Message)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CreatedInDeferredClosure(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "Client leaked inside deferred closure",
+			body: `defer func() {
+		c, _ := spanner.NewClient(ctx, "db")
+		doFinal(c)
+	}()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Client closed inside deferred closure",
+			body: `defer func() {
+		c, _ := spanner.NewClient(ctx, "db")
+		defer c.Close()
+		doFinal(c)
+	}()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Outer defer of a shadowed variable does not release the closure's client",
+			body: `c, _ := spanner.NewClient(ctx, "db")
+	defer c.Close()
+	defer func() {
+		c, _ := spanner.NewClient(ctx, "db")
+		doFinal(c)
+	}()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Closure assigning an outer variable released by an outer defer",
+			body: `var c *spanner.Client
+	func() {
+		c, _ = spanner.NewClient(ctx, "db")
+	}()
+	defer c.Close()`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func doFinal(c *spanner.Client) {}
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CleanupMethodOfDifferentType(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "Close of a shadowing variable of another type",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	_ = client
+	{
+		client := &fileCloser{}
+		defer client.Close()
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "Close of the resource itself",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	defer client.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Close through a closure parameter of the resource type",
+			body: `client, _ := spanner.NewClient(ctx, "db")
+	defer func(c *spanner.Client) { c.Close() }(client)`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type fileCloser struct{}
+
+func (f *fileCloser) Close() error { return nil }
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_DeferInLoop(t *testing.T) {
+	tests := []struct {
+		name          string
+		loopBody      string
+		expectedCount int
+	}{
+		{
+			name: "Deferred in the loop body",
+			loopBody: `client, _ := spanner.NewClient(ctx, db)
+		defer client.Close()
+		_ = client`,
+			expectedCount: 1,
+		},
+		{
+			name: "Deferred in a per-iteration function literal",
+			loopBody: `func() {
+			client, _ := spanner.NewClient(ctx, db)
+			defer client.Close()
+			_ = client
+		}()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Deferred in a nested block of the loop body",
+			loopBody: `client, _ := spanner.NewClient(ctx, db)
+		if client != nil {
+			defer client.Close()
+		}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context, dbs []string) {
+	for _, db := range dbs {
+		` + tt.loopBody + `
+	}
+}
+`
+			if diagnostics := runAnalyzerOnSource(t, "test.go", src); len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics without -gcpdefer-in-loop, got %v", diagnostics)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{DeferInLoop: true})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryDeferInLoop {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryDeferInLoop)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_ExitSkipsDefer(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "log.Fatal after the defer",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	if err := work(client); err != nil {
+		log.Fatalf("work: %v", err)
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "os.Exit after the defer",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	defer client.Close()
+	if work(client) != nil {
+		os.Exit(1)
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "log.Fatal only before the defer",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	_ = work(client)`,
+			expectedCount: 0,
+		},
+		{
+			name: "log.Fatal inside a function literal",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	defer client.Close()
+	onError := func(err error) { log.Fatal(err) }
+	if err := work(client); err != nil {
+		onError(err)
+	}`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"cloud.google.com/go/spanner"
+)
+
+var _ = os.Exit
+
+func work(client *spanner.Client) error { return nil }
+
+func run(ctx context.Context, db string) {
+	` + tt.body + `
+}
+`
+			if diagnostics := runAnalyzerOnSource(t, "test.go", src); len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics without -gcpexit-skips-defer, got %v", diagnostics)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ExitSkipsDefer: true})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryExitSkipsDefer {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryExitSkipsDefer)
+				}
+				if !strings.Contains(d.Message, "deferred cleanup will not run due to") {
+					t.Errorf("Message = %q", d.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CleanupErrorIgnored(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "Writer Close deferred directly",
+			body: `w := obj.NewWriter(ctx)
+	defer w.Close()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Writer Close error assigned to blank in a deferred closure",
+			body: `w := obj.NewWriter(ctx)
+	defer func() { _ = w.Close() }()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Writer Close error joined into the result",
+			body: `w := obj.NewWriter(ctx)
+	defer func() {
+		if closeErr := w.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Client Close error is not significant",
+			body: `client, _ := storage.NewClient(ctx)
+	defer client.Close()
+	w := obj.NewWriter(ctx)
+	defer func() { err = w.Close() }()`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func upload(ctx context.Context, obj *storage.ObjectHandle) (err error) {
+	` + tt.body + `
+	return nil
+}
+`
+			if diagnostics := runAnalyzerOnSource(t, "test.go", src); len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics without -gcpcleanup-error, got %v", diagnostics)
+			}
+
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{CleanupError: true})
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryCleanupErrorIgnored {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryCleanupErrorIgnored)
+				}
+				if !strings.Contains(d.Message, "error of deferred w.Close() is discarded") {
+					t.Errorf("Message = %q", d.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_CreationErrorIgnored(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "error discarded with _",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	defer client.Close()`,
+			expectedCount: 1,
+		},
+		{
+			name: "error checked after the defer",
+			body: `client, err := spanner.NewClient(ctx, db)
+	defer client.Close()
+	if err != nil {
+		return err
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "shadowed err checked instead",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err := work(nil); err != nil {
+		return err
+	}
+	defer client.Close()
+	return err`,
+			expectedCount: 1,
+		},
+		{
+			name: "error checked before use",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return nil`,
+			expectedCount: 0,
+		},
+		{
+			name: "error discarded but resource only passed on",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	return work(client)`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func work(client *spanner.Client) error { return nil }
+
+func run(ctx context.Context, db string) error {
+	` + tt.body + `
+	return nil
+}
+`
+			if diagnostics := diagnosticsWithCategory(runAnalyzerOnSource(t, "test.go", src), CategoryCreationErrorIgnored); len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics without -gcpcreation-error, got %v", diagnostics)
+			}
+
+			all := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{CreationError: true})
+			diagnostics := diagnosticsWithCategory(all, CategoryCreationErrorIgnored)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, all)
+			}
+			for _, d := range diagnostics {
+				if !strings.Contains(d.Message, "creation error ignored; resource may be nil") {
+					t.Errorf("Message = %q", d.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_TestMainExit(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedCategory string // empty means no diagnostics
+	}{
+		{
+			name: "deferred Close before os.Exit",
+			body: `client, _ := spanner.NewClient(context.Background(), "db")
+	defer client.Close()
+	os.Exit(m.Run())`,
+			expectedCategory: CategoryExitSkipsDefer,
+		},
+		{
+			name: "explicit Close before os.Exit",
+			body: `client, _ := spanner.NewClient(context.Background(), "db")
+	code := m.Run()
+	client.Close()
+	os.Exit(code)`,
+		},
+		{
+			name: "no Close at all",
+			body: `client, _ := spanner.NewClient(context.Background(), "db")
+	_ = client
+	os.Exit(m.Run())`,
+			expectedCategory: CategoryResourceLeak,
+		},
+		{
+			name: "explicit Close only in a select case",
+			body: `client, _ := spanner.NewClient(context.Background(), "db")
+	done := make(chan struct{})
+	code := m.Run()
+	select {
+	case <-done:
+		client.Close()
+	default:
+	}
+	os.Exit(code)`,
+			expectedCategory: CategoryResourceLeak,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+func TestMain(m *testing.M) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "main_test.go", src)
+			if tt.expectedCategory == "" {
+				if len(diagnostics) != 0 {
+					t.Errorf("Expected no diagnostics, got %v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 || diagnostics[0].Category != tt.expectedCategory {
+				t.Fatalf("Expected one %s diagnostic, got %v", tt.expectedCategory, diagnostics)
+			}
+			if tt.expectedCategory == CategoryExitSkipsDefer &&
+				!strings.Contains(diagnostics[0].Message, "call client.Close() explicitly before os.Exit") {
+				t.Errorf("Message should suggest an explicit Close, got %q", diagnostics[0].Message)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_UnreachableDefer(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "defer after return",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		return err
+	}
+	return nil
+	defer client.Close()
+	return nil`,
+			expectedCount: 1,
+		},
+		{
+			name: "defer after panic",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	panic("not implemented")
+	defer client.Close()
+	return nil`,
+			expectedCount: 1,
+		},
+		{
+			name: "return only in a nested block",
+			body: `client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return nil`,
+			expectedCount: 0,
+		},
+		{
+			name: "defer after a label reachable by goto",
+			body: `client, _ := spanner.NewClient(ctx, db)
+	goto cleanup
+	return nil
+cleanup:
+	defer client.Close()
+	return nil`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context, db string) error {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+			for _, d := range diagnostics {
+				if d.Category != CategoryResourceLeak {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryResourceLeak)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_StoredClosures(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "leak in a callback assigned to a field",
+			body: `s.handler = func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		_ = client
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "deferred in a callback assigned to a field",
+			body: `s.handler = func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		defer client.Close()
+	}`,
+			expectedCount: 0,
+		},
+		{
+			name: "deferred in a callback table",
+			body: `s.handlers = map[string]func(){"x": func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		defer client.Close()
+	}}`,
+			expectedCount: 0,
+		},
+		{
+			name: "deferred in a closure declared with var",
+			body: `var handler = func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		defer client.Close()
+	}
+	s.handler = handler`,
+			expectedCount: 0,
+		},
+		{
+			name: "callback defer does not release a leak in another callback",
+			body: `s.handlers = map[string]func(){
+		"open": func() {
+			client, _ := spanner.NewClient(ctx, "db")
+			_ = client
+		},
+		"close": func() {
+			client, _ := spanner.NewClient(ctx, "db")
+			defer client.Close()
+		},
+	}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type server struct {
+	handler  func()
+	handlers map[string]func()
+}
+
+func (s *server) setup(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_IndexedElementCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "constant index closed by defer",
+			body: `clients := make([]*storage.Client, 2)
+	clients[0], _ = storage.NewClient(ctx)
+	defer clients[0].Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "constant index without Close",
+			body: `clients := make([]*storage.Client, 2)
+	clients[0], _ = storage.NewClient(ctx)
+	_ = clients[0].Bucket("b")`,
+			expectedCount: 1,
+		},
+		{
+			name: "defer on another element",
+			body: `var clients [2]*storage.Client
+	clients[0], _ = storage.NewClient(ctx)
+	defer clients[1].Close()`,
+			expectedCount: 1,
+		},
+		{
+			// 要素単位で追えないため従来どおり追跡しない
+			name: "slice closed in a range loop",
+			body: `clients := make([]*storage.Client, 2)
+	clients[0], _ = storage.NewClient(ctx)
+	for _, c := range clients {
+		defer c.Close()
+	}`,
+			expectedCount: 0,
+		},
+		{
+			name: "variable index is not tracked",
+			body: `clients := make([]*storage.Client, 2)
+	for i := range 2 {
+		clients[i], _ = storage.NewClient(ctx)
+	}`,
+			expectedCount: 0,
+		},
+		{
+			name: "field slice is not tracked",
+			body: `var h struct{ clients []*storage.Client }
+	h.clients = make([]*storage.Client, 1)
+	h.clients[0], _ = storage.NewClient(ctx)`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+			if tt.expectedCount > 0 && len(diagnosticsContaining(diagnostics, "'clients[0]'")) != 1 {
+				t.Errorf("Expected the element to be reported as 'clients[0]', got %v", diagnostics)
+			}
+		})
+	}
+}
+
+func TestDeferAnalyzer_ReassignedElementCleanup(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedCategory string // empty means no diagnostics
+	}{
+		{
+			name: "stored client closed via the element",
+			body: `m := map[string]*spanner.Client{}
+	client, _ := spanner.NewClient(ctx, "db")
+	m["k"] = client
+	defer m["k"].Close()`,
+		},
+		{
+			name: "element reassigned before the defer",
+			body: `m := map[string]*spanner.Client{}
+	client, _ := spanner.NewClient(ctx, "db")
+	m["k"] = client
+	other, _ := spanner.NewClient(ctx, "other")
+	m["k"] = other
+	defer m["k"].Close()`,
+			expectedCategory: CategoryReassignedElement,
+		},
+		{
+			// defer m["k"].Close() はレシーバを defer 文の時点で評価するため、その後の再代入は影響しない
+			name: "element reassigned after a plain defer",
+			body: `m := map[string]*spanner.Client{}
+	client, _ := spanner.NewClient(ctx, "db")
+	m["k"] = client
+	defer m["k"].Close()
+	m["k"] = nil`,
+		},
+		{
+			name: "element reassigned before a deferred closure runs",
+			body: `m := map[string]*spanner.Client{}
+	client, _ := spanner.NewClient(ctx, "db")
+	m["k"] = client
+	defer func() { m["k"].Close() }()
+	m["k"] = nil`,
+			expectedCategory: CategoryReassignedElement,
+		},
+		{
+			name: "container reassigned before a deferred closure runs",
+			body: `m := map[string]*spanner.Client{}
+	client, _ := spanner.NewClient(ctx, "db")
+	m["k"] = client
+	defer func() { m["k"].Close() }()
+	m = map[string]*spanner.Client{}`,
+			expectedCategory: CategoryReassignedElement,
+		},
+		{
+			name: "element created twice before the defer",
+			body: `clients := make([]*spanner.Client, 1)
+	clients[0], _ = spanner.NewClient(ctx, "a")
+	clients[0], _ = spanner.NewClient(ctx, "b")
+	defer clients[0].Close()`,
+			expectedCategory: CategoryReassignedElement,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if tt.expectedCategory == "" {
+				if len(diagnostics) != 0 {
+					t.Errorf("Expected no diagnostics, got %v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 || diagnostics[0].Category != tt.expectedCategory {
+				t.Fatalf("Expected one %s diagnostic, got %v", tt.expectedCategory, diagnostics)
+			}
+			if !strings.Contains(diagnostics[0].Message, "reassigned element") {
+				t.Errorf("Message should explain the reassigned element, got %q", diagnostics[0].Message)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"strings"
 
 	"golang.org/x/tools/go/analysis"
@@ -11,9 +12,14 @@ import (
 	"github.com/yukia3e/gcpclosecheck/internal/messages"
 )
 
+// contextBackgroundArg はスコープ内に context がない場合に解放メソッドへ渡す引数
+const contextBackgroundArg = "context.Background()"
+
 // DiagnosticGenerator は診断レポートを生成する
 type DiagnosticGenerator struct {
-	fset *token.FileSet
+	fset  *token.FileSet
+	pkg   *types.Package // 修正提案に使うスコープ内の変数の解決用（未設定の場合は解決しない）
+	files []*ast.File    // 修正提案で context の import を追加する対象（未設定の場合は追加しない）
 }
 
 // NewDiagnosticGenerator は新しいDiagnosticGeneratorを作成する
@@ -23,24 +29,89 @@ func NewDiagnosticGenerator(fset *token.FileSet) *DiagnosticGenerator {
 	}
 }
 
+// SetPackage は修正提案でスコープ内の変数（Shutdown(ctx) に渡す context 等）を解決するためのパッケージを設定する
+func (dg *DiagnosticGenerator) SetPackage(pkg *types.Package) {
+	dg.pkg = pkg
+}
+
+// SetFiles は修正提案で context.Background() を渡すときに context の import を追加するファイルを設定する
+func (dg *DiagnosticGenerator) SetFiles(files []*ast.File) {
+	dg.files = files
+}
+
 // ReportMissingDefer はdefer文が不足しているリソースの診断を生成する
 func (dg *DiagnosticGenerator) ReportMissingDefer(resource ResourceInfo) analysis.Diagnostic {
 	message := fmt.Sprintf(messages.MissingResourceCleanup,
 		resource.Variable.Name(), resource.CleanupMethod)
 
-	suggestedFix := dg.CreateSuggestedFix(
-		resource.Variable.Name(),
-		resource.CleanupMethod,
-		resource.CreationPos,
-	)
-
 	return analysis.Diagnostic{
 		Pos:            resource.CreationPos,
 		End:            resource.CreationPos,
-		Category:       "resource-leak",
+		Category:       CategoryResourceLeak,
 		Message:        message,
-		SuggestedFixes: []analysis.SuggestedFix{suggestedFix},
+		SuggestedFixes: []analysis.SuggestedFix{dg.missingDeferFix(resource, resource.Variable.Name(), resource.CreationPos)},
+	}
+}
+
+// MissingDeferFixes はリソースの解放の defer 文を insertPos に挿入する修正提案を返す
+// （変数に代入されていないリソースや挿入位置がない場合は nil）。
+// 解放メソッドが context.Context を取る場合はスコープ内の context を渡し、
+// context.Background() を渡す場合は必要に応じて context の import も追加する
+func (dg *DiagnosticGenerator) MissingDeferFixes(resource ResourceInfo, insertPos token.Pos) []analysis.SuggestedFix {
+	name := resource.VariableName
+	if resource.Variable != nil {
+		name = resource.Variable.Name()
+	}
+	if name == "" || name == "_" || !insertPos.IsValid() {
+		return nil
 	}
+	return []analysis.SuggestedFix{dg.missingDeferFix(resource, name, insertPos)}
+}
+
+// missingDeferFix は name の解放の defer 文を insertPos に挿入する修正提案を作成する
+func (dg *DiagnosticGenerator) missingDeferFix(resource ResourceInfo, name string, insertPos token.Pos) analysis.SuggestedFix {
+	var args string
+	if resource.RequiresContext {
+		args = dg.ContextArgAt(resource.CreationPos)
+	}
+	suggestedFix := dg.createSuggestedFix(name, resource.CleanupMethod, args, insertPos)
+	if args == contextBackgroundArg {
+		if edit, ok := dg.contextImportEdit(insertPos); ok {
+			suggestedFix.TextEdits = append([]analysis.TextEdit{edit}, suggestedFix.TextEdits...)
+		}
+	}
+	return suggestedFix
+}
+
+// contextImportEdit は pos を含むファイルが context を import していない場合に、import を追加する編集を返す
+func (dg *DiagnosticGenerator) contextImportEdit(pos token.Pos) (analysis.TextEdit, bool) {
+	var file *ast.File
+	for _, f := range dg.files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return analysis.TextEdit{}, false
+	}
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"context"` {
+			return analysis.TextEdit{}, false
+		}
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			return analysis.TextEdit{Pos: gen.Lparen + 1, End: gen.Lparen + 1, NewText: []byte("\n\t\"context\"")}, true
+		}
+		return analysis.TextEdit{Pos: gen.Pos(), End: gen.Pos(), NewText: []byte("import \"context\"\n")}, true
+	}
+	return analysis.TextEdit{Pos: file.Name.End(), End: file.Name.End(), NewText: []byte("\n\nimport \"context\"")}, true
 }
 
 // ReportMissingContextCancel はcontext.WithCancelのキャンセル関数が不足している診断を生成する
@@ -57,7 +128,7 @@ func (dg *DiagnosticGenerator) ReportMissingContextCancel(contextInfo ContextInf
 	return analysis.Diagnostic{
 		Pos:            contextInfo.CreationPos,
 		End:            contextInfo.CreationPos,
-		Category:       "context-leak",
+		Category:       CategoryContextLeak,
 		Message:        message,
 		SuggestedFixes: []analysis.SuggestedFix{suggestedFix},
 	}
@@ -65,6 +136,11 @@ func (dg *DiagnosticGenerator) ReportMissingContextCancel(contextInfo ContextInf
 
 // CreateSuggestedFix はdefer文追加の修正提案を作成する
 func (dg *DiagnosticGenerator) CreateSuggestedFix(variableName, method string, creationPos token.Pos) analysis.SuggestedFix {
+	return dg.createSuggestedFix(variableName, method, "", creationPos)
+}
+
+// createSuggestedFix は解放メソッドに args を渡す defer 文追加の修正提案を作成する
+func (dg *DiagnosticGenerator) createSuggestedFix(variableName, method, args string, creationPos token.Pos) analysis.SuggestedFix {
 	var message string
 	var deferStatement string
 
@@ -75,7 +151,7 @@ func (dg *DiagnosticGenerator) CreateSuggestedFix(variableName, method string, c
 	} else {
 		// Resource cleanup method
 		message = fmt.Sprintf(messages.AddDeferMethodCall, variableName, method)
-		deferStatement = fmt.Sprintf("defer %s.%s()", variableName, method)
+		deferStatement = fmt.Sprintf("defer %s.%s(%s)", variableName, method, args)
 	}
 
 	// TextEdit を作成 - リソース作成後の次の行にdefer文を挿入
@@ -91,6 +167,41 @@ func (dg *DiagnosticGenerator) CreateSuggestedFix(variableName, method string, c
 	}
 }
 
+// ContextArgAt は pos の時点でスコープ内にある context.Context 型の変数（引数またはローカル変数）の名前を返す。
+// 最も内側のスコープで直前に宣言されたものを優先し、見つからない場合は context.Background() を返す
+func (dg *DiagnosticGenerator) ContextArgAt(pos token.Pos) string {
+	if dg.pkg == nil {
+		return contextBackgroundArg
+	}
+
+	for scope := dg.pkg.Scope().Innermost(pos); scope != nil && scope != dg.pkg.Scope(); scope = scope.Parent() {
+		var best types.Object
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.Var)
+			if !ok || name == "_" || obj.Pos() >= pos || !isContextType(obj.Type()) {
+				continue
+			}
+			if best == nil || obj.Pos() > best.Pos() {
+				best = obj
+			}
+		}
+		if best != nil {
+			return best.Name()
+		}
+	}
+	return contextBackgroundArg
+}
+
+// isContextType は型が context.Context かを判定する
+func isContextType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
 // ShouldIgnoreNolint はnolintディレクティブをチェックし、診断を抑制すべきかどうかを判定する
 func (dg *DiagnosticGenerator) ShouldIgnoreNolint(file *ast.File, pos token.Pos) bool {
 	// pos の行番号を取得
@@ -184,6 +184,125 @@ func testFunction(ctx context.Context) error {
 	}
 }
 
+func TestDiagnosticGenerator_ContextArgInSuggestedFix(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       string
+		expected string
+	}{
+		{
+			name: "context parameter",
+			fn: `func run(ctx context.Context) {
+	exp := newExporter()
+	_ = exp
+}`,
+			expected: "defer exp.Shutdown(ctx)",
+		},
+		{
+			name: "local context declared before creation",
+			fn: `func run(parent context.Context) {
+	shutdownCtx, cancel := context.WithCancel(parent)
+	defer cancel()
+	exp := newExporter()
+	_ = exp
+	_ = shutdownCtx
+}`,
+			expected: "defer exp.Shutdown(shutdownCtx)",
+		},
+		{
+			name: "no context in scope",
+			fn: `func run() {
+	exp := newExporter()
+	_ = exp
+}`,
+			expected: "defer exp.Shutdown(context.Background())",
+		},
+		{
+			name: "context declared after creation is not used",
+			fn: `func run() {
+	exp := newExporter()
+	ctx := context.Background()
+	_, _ = exp, ctx
+}`,
+			expected: "defer exp.Shutdown(context.Background())",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import "context"
+
+type exporter struct{}
+
+func newExporter() *exporter { return &exporter{} }
+
+func (e *exporter) Shutdown(ctx context.Context) error { return nil }
+
+` + tt.fn + `
+`
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "test.go", src, 0)
+			if err != nil {
+				t.Fatalf("Failed to parse test code: %v", err)
+			}
+			conf := types.Config{Importer: stdImporter}
+			pkg, err := conf.Check("test", fset, []*ast.File{file}, nil)
+			if err != nil {
+				t.Fatalf("Failed to type-check test code: %v", err)
+			}
+
+			var creationPos token.Pos
+			ast.Inspect(file, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok && types.ExprString(call.Fun) == "newExporter" {
+					creationPos = call.Pos()
+				}
+				return creationPos == token.NoPos
+			})
+
+			generator := NewDiagnosticGenerator(fset)
+			generator.SetPackage(pkg)
+			diagnostic := generator.ReportMissingDefer(ResourceInfo{
+				Variable:        types.NewVar(creationPos, pkg, "exp", nil),
+				CreationPos:     creationPos,
+				CleanupMethod:   "Shutdown",
+				IsRequired:      true,
+				RequiresContext: true,
+			})
+
+			edit := diagnostic.SuggestedFixes[0].TextEdits[0]
+			if got := string(edit.NewText); got != "\n\t"+tt.expected {
+				t.Errorf("Suggested fix = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiagnosticGenerator_SuggestedFixWithUnknownPosition(t *testing.T) {
+	generator := NewDiagnosticGenerator(token.NewFileSet())
+	resource := ResourceInfo{
+		Variable:      types.NewVar(token.NoPos, nil, "client", nil),
+		CreationPos:   token.NoPos,
+		CleanupMethod: "Close",
+		IsRequired:    true,
+	}
+
+	// ReportMissingDefer は生成位置が不明でも修正提案を付ける
+	diagnostic := generator.ReportMissingDefer(resource)
+	if len(diagnostic.SuggestedFixes) != 1 {
+		t.Fatalf("Expected 1 SuggestedFix, got %d", len(diagnostic.SuggestedFixes))
+	}
+	if got, want := diagnostic.SuggestedFixes[0].Message, "Add defer client.Close() for client cleanup"; got != want {
+		t.Errorf("SuggestedFix message = %q, want %q", got, want)
+	}
+
+	// MissingDeferFixes は挿入位置がない場合は修正提案を返さない
+	if fixes := generator.MissingDeferFixes(resource, token.NoPos); fixes != nil {
+		t.Errorf("Expected no SuggestedFix for an unknown insert position, got %v", fixes)
+	}
+}
+
 func TestDiagnosticGenerator_ShouldIgnoreNolint(t *testing.T) {
 	testCode := `
 package test
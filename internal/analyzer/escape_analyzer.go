@@ -2,21 +2,61 @@ package analyzer
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
+	"strings"
+
+	"github.com/yukia3e/gcpclosecheck/internal/config"
 )
 
 // EscapeAnalyzer はリソースの逃げパス（戻り値、フィールド代入）を解析する
 type EscapeAnalyzer struct {
-	escapeInfo map[*types.Var]*EscapeInfo
+	escapeInfo     map[*types.Var]*EscapeInfo
+	closureHelpers map[string]int // クロージャ管理ヘルパー名 -> リソースを受け取るクロージャ引数の位置
+	// fieldAssignmentChecked はフィールド代入をエスケープとみなさないサービス名（field_assignment_exempt: false）
+	fieldAssignmentChecked map[string]bool
+	// appLifetime はパッケージ変数への代入もエスケープとみなすサービス名（lifetime: app）
+	appLifetime map[string]bool
+	// typeInfo は識別子の解決や型での判定（ラッパーの Close 等）に使う型情報（nil の場合は判定しない）
+	typeInfo *types.Info
 }
 
 // NewEscapeAnalyzer は新しいEscapeAnalyzerを作成する
 func NewEscapeAnalyzer() *EscapeAnalyzer {
 	return &EscapeAnalyzer{
-		escapeInfo: make(map[*types.Var]*EscapeInfo),
+		escapeInfo:             make(map[*types.Var]*EscapeInfo),
+		closureHelpers:         make(map[string]int),
+		fieldAssignmentChecked: make(map[string]bool),
+		appLifetime:            make(map[string]bool),
+	}
+}
+
+// SetClosureManagedHelpers はクロージャ引数のリソースを自動管理するヘルパー関数を設定する
+func (ea *EscapeAnalyzer) SetClosureManagedHelpers(helpers []config.ClosureManagedHelper) {
+	for _, helper := range helpers {
+		ea.closureHelpers[helper.Function] = helper.Param
 	}
 }
 
+// SetFieldAssignmentCheckedServices は構造体フィールドへの代入でも解放漏れを報告するサービスを設定する
+func (ea *EscapeAnalyzer) SetFieldAssignmentCheckedServices(serviceNames []string) {
+	for _, name := range serviceNames {
+		ea.fieldAssignmentChecked[name] = true
+	}
+}
+
+// SetAppLifetimeServices はパッケージ変数への代入を解放漏れの対象外にするサービス（lifetime: app）を設定する
+func (ea *EscapeAnalyzer) SetAppLifetimeServices(serviceNames []string) {
+	for _, name := range serviceNames {
+		ea.appLifetime[name] = true
+	}
+}
+
+// SetTypesInfo は識別子の解決や型での判定に使う型情報を設定する
+func (ea *EscapeAnalyzer) SetTypesInfo(typeInfo *types.Info) {
+	ea.typeInfo = typeInfo
+}
+
 // AnalyzeEscape は変数のエスケープパターンを解析する
 func (ea *EscapeAnalyzer) AnalyzeEscape(variable *types.Var, fn *ast.FuncDecl) EscapeInfo {
 	if variable == nil || fn == nil {
@@ -24,8 +64,13 @@ func (ea *EscapeAnalyzer) AnalyzeEscape(variable *types.Var, fn *ast.FuncDecl) E
 	}
 
 	escapeInfo := EscapeInfo{
-		IsReturned:      ea.IsReturnedValue(variable, fn),
-		IsFieldAssigned: ea.IsFieldAssigned(variable, fn),
+		IsReturned:         ea.IsReturnedValue(variable, fn),
+		IsFieldAssigned:    ea.IsFieldAssigned(variable, fn),
+		IsWrappedAndClosed: ea.IsWrappedAndClosed(variable, fn),
+		IsOutParamAssigned: ea.IsOutParamAssigned(variable, fn),
+		IsUsedWithReflect:  ea.IsUsedWithReflect(variable, fn),
+		IsGlobalAssigned:   ea.IsGlobalAssigned(variable, fn),
+		IsAtomicStored:     ea.IsAtomicPointerStored(variable, fn),
 	}
 
 	// エスケープ理由を設定
@@ -33,6 +78,16 @@ func (ea *EscapeAnalyzer) AnalyzeEscape(variable *types.Var, fn *ast.FuncDecl) E
 		escapeInfo.EscapeReason = "returned from function"
 	} else if escapeInfo.IsFieldAssigned {
 		escapeInfo.EscapeReason = "assigned to struct field"
+	} else if escapeInfo.IsWrappedAndClosed {
+		escapeInfo.EscapeReason = "wrapped and closed"
+	} else if escapeInfo.IsOutParamAssigned {
+		escapeInfo.EscapeReason = "assigned via out-parameter"
+	} else if escapeInfo.IsUsedWithReflect {
+		escapeInfo.EscapeReason = "used with reflection (cleanup unverifiable)"
+	} else if escapeInfo.IsGlobalAssigned {
+		escapeInfo.EscapeReason = "assigned to package-level variable"
+	} else if escapeInfo.IsAtomicStored {
+		escapeInfo.EscapeReason = "stored in atomic pointer"
 	}
 
 	// 結果をキャッシュ
@@ -49,10 +104,32 @@ func (ea *EscapeAnalyzer) IsReturnedValue(variable *types.Var, fn *ast.FuncDecl)
 
 	varName := variable.Name()
 
+	// 変数を宣言した関数（関数リテラルを含む）の return 文のみを対象にする。
+	// 即時実行する関数リテラル等の return は外側の関数から返すわけではない
+	body, funcType := fn.Body, fn.Type
+	if variable.Pos().IsValid() {
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if funcLit, ok := n.(*ast.FuncLit); ok && funcLit.Pos() <= variable.Pos() && variable.Pos() < funcLit.End() {
+				body, funcType = funcLit.Body, funcLit.Type
+			}
+			return true
+		})
+	}
+
+	// 名前付き戻り値そのものであれば、naked return でも返される
+	namedResult := isNamedResult(funcType, variable)
+
 	// 関数内のreturn文を検索
 	var isReturned bool
-	ast.Inspect(fn.Body, func(n ast.Node) bool {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
 		if retStmt, ok := n.(*ast.ReturnStmt); ok {
+			if namedResult && len(retStmt.Results) == 0 {
+				isReturned = true
+				return false
+			}
 			// return文の式を確認
 			for _, expr := range retStmt.Results {
 				if ident, ok := expr.(*ast.Ident); ok {
@@ -69,6 +146,21 @@ func (ea *EscapeAnalyzer) IsReturnedValue(variable *types.Var, fn *ast.FuncDecl)
 	return isReturned
 }
 
+// isNamedResult は変数が関数型の名前付き戻り値として宣言されたものかを判定する
+func isNamedResult(funcType *ast.FuncType, variable *types.Var) bool {
+	if funcType == nil || funcType.Results == nil || !variable.Pos().IsValid() {
+		return false
+	}
+	for _, field := range funcType.Results.List {
+		for _, name := range field.Names {
+			if name.Pos() == variable.Pos() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // IsFieldAssigned は変数が構造体のフィールドに代入されるかどうかを判定する
 func (ea *EscapeAnalyzer) IsFieldAssigned(variable *types.Var, fn *ast.FuncDecl) bool {
 	if variable == nil || fn == nil || fn.Body == nil {
@@ -100,8 +192,300 @@ func (ea *EscapeAnalyzer) IsFieldAssigned(variable *types.Var, fn *ast.FuncDecl)
 	return isAssigned
 }
 
+// IsGlobalAssigned は変数がパッケージ変数であるか、パッケージ変数に代入されるか（shared = client）を判定する
+func (ea *EscapeAnalyzer) IsGlobalAssigned(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || variable.Pkg() == nil {
+		return false
+	}
+	pkgScope := variable.Pkg().Scope()
+	if variable.Parent() == pkgScope {
+		return true
+	}
+	if fn == nil || fn.Body == nil || variable.Parent() == nil {
+		return false
+	}
+
+	varName := variable.Name()
+
+	var isAssigned bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		assignStmt, ok := n.(*ast.AssignStmt)
+		if !ok || len(assignStmt.Lhs) != len(assignStmt.Rhs) {
+			return !isAssigned
+		}
+		for i, rhs := range assignStmt.Rhs {
+			if ident, ok := rhs.(*ast.Ident); !ok || ident.Name != varName {
+				continue
+			}
+			// 左辺の名前が変数のスコープから見てパッケージ変数に解決されるか
+			if lhs, ok := assignStmt.Lhs[i].(*ast.Ident); ok {
+				if scope, obj := variable.Parent().LookupParent(lhs.Name, token.NoPos); scope == pkgScope {
+					if _, isVar := obj.(*types.Var); isVar {
+						isAssigned = true
+					}
+				}
+			}
+		}
+		return !isAssigned
+	})
+
+	return isAssigned
+}
+
+// IsOutParamAssigned は変数がポインタ引数の参照先に代入されるか（*out = v）を判定する
+func (ea *EscapeAnalyzer) IsOutParamAssigned(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || fn == nil || fn.Body == nil || fn.Type.Params == nil {
+		return false
+	}
+
+	varName := variable.Name()
+
+	// 関数の引数名を収集
+	params := make(map[string]bool)
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			params[name.Name] = true
+		}
+	}
+
+	var isAssigned bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		assignStmt, ok := n.(*ast.AssignStmt)
+		if !ok || len(assignStmt.Lhs) != len(assignStmt.Rhs) {
+			return !isAssigned
+		}
+		for i, rhs := range assignStmt.Rhs {
+			if ident, ok := rhs.(*ast.Ident); !ok || ident.Name != varName {
+				continue
+			}
+			// 左辺が *out（**out 等の多重参照も含む）で、out が引数か
+			lhs := assignStmt.Lhs[i]
+			deref := false
+			for {
+				star, ok := lhs.(*ast.StarExpr)
+				if !ok {
+					break
+				}
+				lhs, deref = star.X, true
+			}
+			if ident, ok := lhs.(*ast.Ident); ok && deref && params[ident.Name] {
+				isAssigned = true
+			}
+		}
+		return !isAssigned
+	})
+
+	return isAssigned
+}
+
+// IsAtomicPointerStored は変数が ptr.Store(client) のように sync/atomic.Pointer[T] に格納されるかを判定する。
+// ホットスワップ用に保持するクライアントは格納先に所有権が移り、差し替えた側で解放する
+func (ea *EscapeAnalyzer) IsAtomicPointerStored(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || fn == nil || fn.Body == nil || ea.typeInfo == nil {
+		return false
+	}
+
+	var isStored bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return !isStored
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Store" {
+			return true
+		}
+		if arg, ok := ast.Unparen(call.Args[0]).(*ast.Ident); !ok || ea.typeInfo.Uses[arg] != variable {
+			return true
+		}
+		if isAtomicPointer(ea.typeInfo.TypeOf(sel.X)) {
+			isStored = true
+		}
+		return !isStored
+	})
+
+	return isStored
+}
+
+// isAtomicPointer は型が sync/atomic.Pointer[T]（またはそのポインタ）かを判定する
+func isAtomicPointer(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "sync/atomic" && obj.Name() == "Pointer"
+}
+
+// IsUsedWithReflect は変数が reflect.ValueOf(client) のように reflect パッケージの関数に渡されるかを判定する。
+// reflect 経由の MethodByName("Close").Call(nil) 等は静的に追えないため、解放済みの可能性があるものとして扱う
+func (ea *EscapeAnalyzer) IsUsedWithReflect(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || fn == nil || fn.Body == nil {
+		return false
+	}
+
+	varName := variable.Name()
+
+	var isUsed bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return !isUsed
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "reflect" {
+			return true
+		}
+		for _, arg := range call.Args {
+			// reflect.ValueOf(&client) のようにアドレスを渡す場合も含める
+			if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				arg = unary.X
+			}
+			if ident, ok := arg.(*ast.Ident); ok && ident.Name == varName {
+				isUsed = true
+			}
+		}
+		return !isUsed
+	})
+
+	return isUsed
+}
+
+// IsWrappedAndClosed は変数が複合リテラルの要素やコンストラクタの引数としてラップされ、
+// そのラッパーが defer で Close されるかどうかを判定する（保守的な判定）。
+// 識別子は型情報で解決し、型情報がない場合はラップとみなさない
+func (ea *EscapeAnalyzer) IsWrappedAndClosed(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || fn == nil || fn.Body == nil || ea.typeInfo == nil {
+		return false
+	}
+
+	// 変数をラップした値が代入される変数を収集
+	wrappers := make(map[types.Object]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		assignStmt, ok := n.(*ast.AssignStmt)
+		if !ok || len(assignStmt.Lhs) != len(assignStmt.Rhs) {
+			return true
+		}
+		for i, rhs := range assignStmt.Rhs {
+			if !ea.wrapsVariable(rhs, variable) {
+				continue
+			}
+			if ident, ok := assignStmt.Lhs[i].(*ast.Ident); ok && ident.Name != "_" {
+				if obj := ea.typeInfo.ObjectOf(ident); obj != nil && obj != variable {
+					wrappers[obj] = true
+				}
+			}
+		}
+		return true
+	})
+	if len(wrappers) == 0 {
+		return false
+	}
+
+	// ラッパーの Close が defer されているか
+	var isClosed bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		deferStmt, ok := n.(*ast.DeferStmt)
+		if !ok {
+			return !isClosed
+		}
+		// defer w.Close() と defer func() { w.Close() }() の両方を対象とする
+		ast.Inspect(deferStmt.Call, func(m ast.Node) bool {
+			if call, ok := m.(*ast.CallExpr); ok {
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Close" {
+					if ident, ok := sel.X.(*ast.Ident); ok && wrappers[ea.typeInfo.Uses[ident]] {
+						isClosed = true
+					}
+				}
+			}
+			return !isClosed
+		})
+		return !isClosed
+	})
+
+	return isClosed
+}
+
+// wrapsVariable は式が変数を要素に持つ複合リテラル、または変数を引数に取るコンストラクタの呼び出しかを判定する。
+// 呼び出しは、結果の型が Close メソッドを持ち、変数を保持できるフィールドを持つ構造体の場合のみラッパーとみなす
+func (ea *EscapeAnalyzer) wrapsVariable(expr ast.Expr, variable *types.Var) bool {
+	if unary, ok := expr.(*ast.UnaryExpr); ok {
+		expr = unary.X
+	}
+
+	isVar := func(e ast.Expr) bool {
+		ident, ok := ast.Unparen(e).(*ast.Ident)
+		return ok && ea.typeInfo.Uses[ident] == variable
+	}
+
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if isVar(elt) {
+				return true
+			}
+		}
+	case *ast.CallExpr:
+		for _, arg := range e.Args {
+			if isVar(arg) {
+				return isWrapperType(ea.typeInfo.TypeOf(e), variable.Type())
+			}
+		}
+	}
+
+	return false
+}
+
+// isWrapperType は型が Close メソッドを持ち、resourceType の値を保持できるフィールドを持つ構造体（またはそのポインタ）かを判定する
+func isWrapperType(t, resourceType types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "Close"); obj == nil {
+		return false
+	} else if _, ok := obj.(*types.Func); !ok {
+		return false
+	}
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		fieldType := st.Field(i).Type()
+		if iface, ok := fieldType.Underlying().(*types.Interface); ok && iface.Empty() {
+			continue
+		}
+		if types.AssignableTo(resourceType, fieldType) {
+			return true
+		}
+	}
+	return false
+}
+
 // ShouldSkipResource はリソースをスキップすべきかどうかを判定する
 func (ea *EscapeAnalyzer) ShouldSkipResource(resource ResourceInfo, escape EscapeInfo) (bool, string) {
+	// ラップされたリソースはラッパーの Close で解放される
+	if escape.IsWrappedAndClosed {
+		return true, escape.EscapeReason
+	}
+
+	// reflect 経由の操作は解放を確認できないため、誤検知を避けてスキップ（iterator も含む）
+	if escape.IsUsedWithReflect {
+		return true, escape.EscapeReason
+	}
+
 	// RowIteratorは特別扱い：戻り値として返されても関数内で処理すべき
 	if resource.CreationFunction == "Query" || resource.CreationFunction == "Read" {
 		// IteratorやReader系は基本的に関数内で処理
@@ -113,8 +497,23 @@ func (ea *EscapeAnalyzer) ShouldSkipResource(resource ResourceInfo, escape Escap
 		return true, escape.EscapeReason
 	}
 
-	// フィールドに代入される場合はスキップ
-	if escape.IsFieldAssigned {
+	// フィールドに代入される場合はスキップ（field_assignment_exempt: false のサービスを除く）
+	if escape.IsFieldAssigned && !ea.fieldAssignmentChecked[resource.ServiceType] {
+		return true, escape.EscapeReason
+	}
+
+	// 出力引数経由で呼び出し元に渡される場合はスキップ
+	if escape.IsOutParamAssigned {
+		return true, escape.EscapeReason
+	}
+
+	// atomic.Pointer に格納したリソースは格納先が所有する
+	if escape.IsAtomicStored {
+		return true, escape.EscapeReason
+	}
+
+	// アプリケーション全体で共有するリソース（lifetime: app）はパッケージ変数への代入もスキップ
+	if escape.IsGlobalAssigned && ea.appLifetime[resource.ServiceType] {
 		return true, escape.EscapeReason
 	}
 
@@ -138,6 +537,14 @@ func (ea *EscapeAnalyzer) DetectSpannerAutoManagement(variable *types.Var, fn *a
 		return nil
 	}
 
+	// RowIterator.Do は終了時にイテレータを自動で Stop する
+	if ea.IsConsumedOnlyByDo(variable, fn) {
+		return &SpannerEscapeInfo{
+			IsAutoManaged:        true,
+			AutoManagementReason: "RowIterator.Do で自動停止",
+		}
+	}
+
 	// クロージャパターンを検出
 	isPattern, transactionType := ea.IsSpannerClosurePattern(variable, fn)
 	if !isPattern {
@@ -149,6 +556,47 @@ func (ea *EscapeAnalyzer) DetectSpannerAutoManagement(variable *types.Var, fn *a
 	return NewSpannerEscapeInfo(transactionType, true, reason)
 }
 
+// IsConsumedOnlyByDo は spanner.RowIterator の変数が iter.Do(...) のみで読み出されているかを判定する。
+// Next も呼んでいる場合は Do を通らない経路があり得るため Stop が必要
+func (ea *EscapeAnalyzer) IsConsumedOnlyByDo(variable *types.Var, fn *ast.FuncDecl) bool {
+	if variable == nil || fn == nil || fn.Body == nil || !isSpannerRowIterator(variable.Type()) {
+		return false
+	}
+
+	varName := variable.Name()
+	usesDo, usesNext := false, false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == varName {
+			switch sel.Sel.Name {
+			case "Do":
+				usesDo = true
+			case "Next":
+				usesNext = true
+			}
+		}
+		return true
+	})
+	return usesDo && !usesNext
+}
+
+// isSpannerRowIterator は型が *spanner.RowIterator かを判定する
+func isSpannerRowIterator(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == "RowIterator" && named.Obj().Pkg() != nil &&
+		strings.HasPrefix(named.Obj().Pkg().Path(), "cloud.google.com/go/spanner")
+}
+
 // IsSpannerClosurePattern は変数がSpannerクロージャパターンで使用されているかを検出する
 func (ea *EscapeAnalyzer) IsSpannerClosurePattern(variable *types.Var, fn *ast.FuncDecl) (bool, string) {
 	if variable == nil || fn == nil || fn.Body == nil {
@@ -190,6 +638,90 @@ func (ea *EscapeAnalyzer) IsSpannerClosurePattern(variable *types.Var, fn *ast.F
 	return foundPattern, transactionType
 }
 
+// IsClosureManaged はリソースが設定されたヘルパー関数のクロージャ引数として管理されているかを判定する。
+// ReadWriteTransaction のクロージャパターンと同様に、クロージャの指定位置の引数名がリソース変数名と一致するか、
+// リソース（生成呼び出しまたは変数）をヘルパーに直接渡している場合に自動管理とみなす
+func (ea *EscapeAnalyzer) IsClosureManaged(resource ResourceInfo, fn *ast.FuncDecl) (bool, string) {
+	if len(ea.closureHelpers) == 0 || fn == nil || fn.Body == nil {
+		return false, ""
+	}
+
+	var helperName string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		callExpr, ok := n.(*ast.CallExpr)
+		if !ok {
+			return helperName == ""
+		}
+		name := calleeName(callExpr)
+		param, ok := ea.closureHelpers[name]
+		if !ok {
+			return true
+		}
+
+		for _, arg := range callExpr.Args {
+			funcLit, ok := arg.(*ast.FuncLit)
+			if !ok {
+				continue
+			}
+			paramName := closureParamName(funcLit, param)
+			if paramName == "" {
+				continue
+			}
+			if paramName == resource.VariableName || ea.passesResource(callExpr, resource) {
+				helperName = name
+				return false
+			}
+		}
+		return true
+	})
+
+	if helperName == "" {
+		return false, ""
+	}
+	return true, helperName + "クロージャ内で自動管理"
+}
+
+// passesResource はヘルパー呼び出しの引数にリソースの生成呼び出しまたはリソース変数があるかを判定する
+func (ea *EscapeAnalyzer) passesResource(call *ast.CallExpr, resource ResourceInfo) bool {
+	for _, arg := range call.Args {
+		if arg.Pos() == resource.CreationPos {
+			return true
+		}
+		if ident, ok := arg.(*ast.Ident); ok && resource.VariableName != "" && ident.Name == resource.VariableName {
+			return true
+		}
+	}
+	return false
+}
+
+// calleeName は呼び出し先の関数名（メソッドの場合はメソッド名）を返す
+func calleeName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+// closureParamName はクロージャの index 番目（0 始まり）の引数名を返す
+func closureParamName(funcLit *ast.FuncLit, index int) string {
+	if funcLit == nil || funcLit.Type == nil || funcLit.Type.Params == nil {
+		return ""
+	}
+	i := 0
+	for _, param := range funcLit.Type.Params.List {
+		for _, name := range param.Names {
+			if i == index {
+				return name.Name
+			}
+			i++
+		}
+	}
+	return ""
+}
+
 // isSpannerTransactionMethod はメソッドがSpannerトランザクションメソッドかどうかを判定する
 func (ea *EscapeAnalyzer) isSpannerTransactionMethod(methodName string) bool {
 	return methodName == "ReadWriteTransaction" || methodName == "ReadOnlyTransaction"
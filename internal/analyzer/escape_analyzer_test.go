@@ -5,6 +5,8 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -895,3 +897,386 @@ func TestEscapeAnalyzer_EdgeCases(t *testing.T) {
 		t.Error("空のパラメータリストで findVariableInClosureParams は false を返すべき")
 	}
 }
+
+func TestEscapeAnalyzer_WrappedAndClosed(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "複合リテラルでラップしてラッパーをdeferでClose",
+			body: `	rc := &readCloser{client: client}
+	defer rc.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "コンストラクタでラップしてラッパーをクロージャ内でClose",
+			body: `	rc := newReadCloser(client)
+	defer func() { _ = rc.Close() }()`,
+			expectedCount: 0,
+		},
+		{
+			name: "ラップしたがラッパーをCloseしない",
+			body: `	rc := &readCloser{client: client}
+	_ = rc`,
+			expectedCount: 1,
+		},
+		{
+			name: "リソースを保持しない呼び出し結果をClose",
+			body: `	res := process(client)
+	defer res.Close()`,
+			expectedCount: 1,
+		},
+		{
+			name: "同名の別変数をラップしてClose",
+			body: `	_ = client
+	func() {
+		client := &spanner.Client{}
+		rc := &readCloser{client: client}
+		defer rc.Close()
+	}()`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package wrap
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type readCloser struct {
+	client *spanner.Client
+}
+
+func newReadCloser(client *spanner.Client) *readCloser {
+	return &readCloser{client: client}
+}
+
+func (r *readCloser) Close() error {
+	r.client.Close()
+	return nil
+}
+
+type result struct {
+	rows int
+}
+
+func process(client *spanner.Client) *result {
+	return &result{}
+}
+
+func (r *result) Close() error {
+	return nil
+}
+
+func run(ctx context.Context) error {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+` + tt.body + `
+	return nil
+}
+`
+			diags := runAnalyzerOnSource(t, "wrap.go", src)
+			if got := len(diagnosticsContaining(diags, "'client'")); got != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics for client, got %d: %v", tt.expectedCount, got, diags)
+			}
+		})
+	}
+}
+
+func TestEscapeAnalyzer_OutParamAssigned(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedCount int
+	}{
+		{
+			name: "出力引数の参照先に代入",
+			src: `func setup(ctx context.Context, out **spanner.Client) error {
+	c, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	*out = c
+	return nil
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "ローカルのポインタ経由の代入は逃げとみなさない",
+			src: `func setup(ctx context.Context) error {
+	var local *spanner.Client
+	p := &local
+	c, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	*p = c
+	return nil
+}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package outparam
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+` + tt.src + `
+`
+			diags := runAnalyzerOnSource(t, "outparam.go", src)
+			if got := len(diagnosticsContaining(diags, "'c'")); got != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics for c, got %d: %v", tt.expectedCount, got, diags)
+			}
+		})
+	}
+
+	ea := NewEscapeAnalyzer()
+	fn := &ast.FuncDecl{
+		Name: ast.NewIdent("setup"),
+		Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("out")}}}}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.StarExpr{X: ast.NewIdent("out")}},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{ast.NewIdent("c")},
+		}}},
+	}
+	info := ea.AnalyzeEscape(types.NewVar(token.NoPos, nil, "c", nil), fn)
+	if !info.IsOutParamAssigned || info.EscapeReason != "assigned via out-parameter" {
+		t.Errorf("Expected out-parameter escape, got %+v", info)
+	}
+}
+
+func TestEscapeAnalyzer_UsedWithReflect(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedCount int
+	}{
+		{
+			name: "reflect 経由で Close を呼び出す",
+			src: `func shutdown(ctx context.Context) {
+	c, _ := spanner.NewClient(ctx, "db")
+	reflect.ValueOf(c).MethodByName("Close").Call(nil)
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "アドレスを reflect に渡す",
+			src: `func shutdown(ctx context.Context) {
+	c, _ := spanner.NewClient(ctx, "db")
+	v := reflect.ValueOf(&c).Elem()
+	_ = v
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "reflect に渡さない場合は報告する",
+			src: `func shutdown(ctx context.Context) {
+	c, _ := spanner.NewClient(ctx, "db")
+	_ = reflect.TypeOf(ctx)
+	_ = c
+}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package reflected
+
+import (
+	"context"
+	"reflect"
+
+	"cloud.google.com/go/spanner"
+)
+
+` + tt.src + `
+`
+			diags := runAnalyzerOnSource(t, "reflected.go", src)
+			if got := len(diagnosticsContaining(diags, "'c'")); got != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics for c, got %d: %v", tt.expectedCount, got, diags)
+			}
+		})
+	}
+
+	ea := NewEscapeAnalyzer()
+	fn := &ast.FuncDecl{
+		Name: ast.NewIdent("shutdown"),
+		Type: &ast.FuncType{},
+		Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent("reflect"), Sel: ast.NewIdent("ValueOf")},
+			Args: []ast.Expr{ast.NewIdent("c")},
+		}}}},
+	}
+	info := ea.AnalyzeEscape(types.NewVar(token.NoPos, nil, "c", nil), fn)
+	if !info.IsUsedWithReflect || info.EscapeReason != "used with reflection (cleanup unverifiable)" {
+		t.Errorf("Expected reflection escape, got %+v", info)
+	}
+}
+
+func TestEscapeAnalyzer_ClosureManagedHelper(t *testing.T) {
+	writeConfig := func(t *testing.T, helpers string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+      - ReadOnlyTransaction
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client or transaction
+` + helpers
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name          string
+		helpers       string
+		call          string
+		expectedCount int
+	}{
+		{
+			name:          "設定したヘルパーのクロージャ引数で管理",
+			helpers:       "closure_managed_helpers:\n  - function: withTxn\n    param: 0\n",
+			call:          `withTxn(txn, func(txn *spanner.ReadOnlyTransaction) error { return nil })`,
+			expectedCount: 0,
+		},
+		{
+			name:          "メソッドとして呼び出すヘルパー",
+			helpers:       "closure_managed_helpers:\n  - function: withTxn\n    param: 0\n",
+			call:          `r.withTxn(txn, func(txn *spanner.ReadOnlyTransaction) error { return nil })`,
+			expectedCount: 0,
+		},
+		{
+			name:          "ヘルパー未設定",
+			helpers:       "",
+			call:          `withTxn(txn, func(txn *spanner.ReadOnlyTransaction) error { return nil })`,
+			expectedCount: 1,
+		},
+		{
+			name:          "存在しないクロージャ引数位置を設定",
+			helpers:       "closure_managed_helpers:\n  - function: withTxn\n    param: 1\n",
+			call:          `withTxn(txn, func(txn *spanner.ReadOnlyTransaction) error { return nil })`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+type repo struct{}
+
+func withTxn(txn *spanner.ReadOnlyTransaction, fn func(txn *spanner.ReadOnlyTransaction) error) error {
+	defer txn.Close()
+	return fn(txn)
+}
+
+func (repo) withTxn(txn *spanner.ReadOnlyTransaction, fn func(txn *spanner.ReadOnlyTransaction) error) error {
+	defer txn.Close()
+	return fn(txn)
+}
+
+func run(ctx context.Context, client *spanner.Client, r repo) error {
+	txn := client.ReadOnlyTransaction()
+	return ` + tt.call + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ConfigPath: writeConfig(t, tt.helpers)})
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
+
+func TestEscapeAnalyzer_RowIteratorConsumedByDo(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "consumed only by Do",
+			body: `iter := txn.Query(ctx, spanner.Statement{})
+	return iter.Do(func(r *spanner.Row) error { return nil })`,
+			expectedCount: 0,
+		},
+		{
+			name: "consumed by Do with a redundant Stop",
+			body: `iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	return iter.Do(func(r *spanner.Row) error { return nil })`,
+			expectedCount: 0,
+		},
+		{
+			name: "looped with Next without Stop",
+			body: `iter := txn.Query(ctx, spanner.Statement{})
+	for {
+		if _, err := iter.Next(); err != nil {
+			return err
+		}
+	}`,
+			expectedCount: 1,
+		},
+		{
+			name: "both Next and Do without Stop",
+			body: `iter := txn.Query(ctx, spanner.Statement{})
+	if _, err := iter.Next(); err != nil {
+		return err
+	}
+	return iter.Do(func(r *spanner.Row) error { return nil })`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func read(ctx context.Context, txn *spanner.ReadOnlyTransaction) error {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", tt.expectedCount, len(diagnostics), diagnostics)
+			}
+		})
+	}
+}
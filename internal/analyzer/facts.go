@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ReturnsResourceFact は関数が本体で生成した GCP リソースを呼び出し元に返す（所有権を渡すコンストラクタである）ことを示す。
+// パッケージ A のコンストラクタを呼ぶパッケージ B の解析で、A の本体を再解析せずに戻り値を解放の検査対象にする
+type ReturnsResourceFact struct {
+	ServiceName   string
+	CleanupMethod string
+}
+
+// AFact は analysis.Fact を実装する
+func (*ReturnsResourceFact) AFact() {}
+
+// String はテストや -debug 出力用の表現を返す
+func (f *ReturnsResourceFact) String() string {
+	return fmt.Sprintf("returnsResource(%s, %s)", f.ServiceName, f.CleanupMethod)
+}
+
+// ExportFacts は診断や -gcpmetrics の集計を行わず、パッケージのコンストラクタの Fact のみを付与する。
+// ドライバが解析対象の import するパッケージを解析するときに使う
+func ExportFacts(pass *analysis.Pass) error {
+	serviceRuleEngine := NewServiceRuleEngine()
+	if err := serviceRuleEngine.LoadRules(analyzerOptions.ConfigPath); err != nil {
+		return err
+	}
+	resourceTracker := NewResourceTracker(pass.TypesInfo, serviceRuleEngine)
+	resourceTracker.SetFactImporter(pass.ImportObjectFact)
+	exportResourceFacts(pass, resourceTracker)
+	return nil
+}
+
+// exportResourceFacts は解析中のパッケージの公開関数・公開型の公開メソッドのうち、
+// 最初の戻り値の GCP リソースを本体で生成しているものに ReturnsResourceFact を付与する
+func exportResourceFacts(pass *analysis.Pass, resourceTracker *ResourceTracker) {
+	if pass.ExportObjectFact == nil || pass.TypesInfo == nil {
+		return
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || !fn.Name.IsExported() {
+				continue
+			}
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				if typeName := receiverTypeName(pass.TypesInfo, fn.Recv.List[0].Type); typeName == nil || !typeName.Exported() {
+					continue
+				}
+			}
+			obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			results := obj.Type().(*types.Signature).Results()
+			if results.Len() == 0 {
+				continue
+			}
+			resultType := results.At(0).Type()
+			serviceName, cleanupMethod, ok := resourceTracker.CleanupMethodForType(resultType)
+			if !ok || !resourceTracker.createsResourceOfType(fn.Body, resultType) {
+				continue
+			}
+			pass.ExportObjectFact(obj, &ReturnsResourceFact{ServiceName: serviceName, CleanupMethod: cleanupMethod})
+		}
+	}
+}
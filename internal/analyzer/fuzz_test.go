@@ -0,0 +1,202 @@
+package analyzer
+
+import (
+	"go/types"
+	"testing"
+	"time"
+)
+
+// fuzzMaxSourceSize は1入力あたりのソースサイズ上限（巨大入力による遅延を避ける）
+const fuzzMaxSourceSize = 64 * 1024
+
+// fuzzMaxDuration は1入力あたりの解析時間の上限
+const fuzzMaxDuration = 5 * time.Second
+
+// fuzzSeedPrograms はファズテストのシードコーパス
+var fuzzSeedPrograms = []string{
+	// コンテキストのキャンセル
+	`package seed
+
+import (
+	"context"
+	"time"
+)
+
+func run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	child, stop := context.WithCancel(ctx)
+	_ = child
+	_ = stop
+}
+`,
+	// Spanner のクライアント・トランザクション・イテレータ
+	`package seed
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func query(ctx context.Context) error {
+	client, err := spanner.NewClient(ctx, "projects/p/instances/i/databases/d")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	tx := client.ReadOnlyTransaction()
+	defer tx.Close()
+
+	iter := tx.Query(ctx, spanner.NewStatement("SELECT 1"))
+	defer iter.Stop()
+
+	_, err = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		_, err := txn.Update(ctx, spanner.NewStatement("UPDATE t SET x = 1"))
+		return err
+	})
+	return err
+}
+`,
+	// Storage のリーダー・ライター
+	`package seed
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func copyObject(ctx context.Context) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	obj := client.Bucket("b").Object("o")
+	reader, err := obj.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	writer := obj.NewWriter(ctx)
+	defer writer.Close()
+	return nil
+}
+`,
+	// クロージャと goroutine
+	`package seed
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func closures(ctx context.Context) {
+	var defers []func()
+	defer func() {
+		for _, f := range defers {
+			f()
+		}
+	}()
+
+	func() {
+		client, err := spanner.NewClient(ctx, "db")
+		if err != nil {
+			return
+		}
+		defer func() { client.Close() }()
+	}()
+
+	go func() {
+		client, _ := spanner.NewClient(ctx, "db")
+		defers = append(defers, client.Close)
+	}()
+}
+`,
+	// ループ・switch・select のネスト
+	`package seed
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func loops(ctx context.Context, names []string, ch chan int, v interface{}) {
+	for i := 0; i < len(names); i++ {
+		for _, name := range names {
+			switch v.(type) {
+			case int:
+				client, err := storage.NewClient(ctx)
+				if err != nil {
+					continue
+				}
+				_ = name
+				defer client.Close()
+			case string:
+				select {
+				case <-ch:
+					client, _ := storage.NewClient(ctx)
+					_ = client
+				default:
+				}
+			}
+		}
+	}
+}
+`,
+	// 再帰的な型と型スイッチ
+	`package seed
+
+type node struct {
+	next *node
+	val  interface{}
+}
+
+func walk(n *node) {
+	switch v := n.val.(type) {
+	case *node:
+		switch w := v.val.(type) {
+		case *node:
+			walk(w)
+		}
+	case nil:
+	}
+	if n.next != nil {
+		walk(n.next)
+	}
+}
+`,
+}
+
+func FuzzAnalyzeSource(f *testing.F) {
+	for _, seed := range fuzzSeedPrograms {
+		f.Add([]byte(seed))
+	}
+
+	// スタブの型チェックは Fuzz 関数外で済ませ、以降はキャッシュを使う
+	imp := &fakeGCPImporter{t: f, packages: make(map[string]*types.Package)}
+	for path := range fakeGCPPackages {
+		if _, err := imp.Import(path); err != nil {
+			f.Fatalf("Failed to import fake package %s: %v", path, err)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		if len(src) > fuzzMaxSourceSize {
+			t.Skip("source too large")
+		}
+
+		start := time.Now()
+		// パースできない入力はエラーとして返るだけで、パニックしないことが重要
+		_, _ = AnalyzeSource("fuzz.go", src, imp)
+		if elapsed := time.Since(start); elapsed > fuzzMaxDuration {
+			t.Errorf("AnalyzeSource took %v, want <= %v", elapsed, fuzzMaxDuration)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+)
+
+// pubsubPackagePrefix は Pub/Sub パッケージ（v2 を含む）のパスの接頭辞
+const pubsubPackagePrefix = "cloud.google.com/go/pubsub"
+
+// MessageAckAnalyzer は Pub/Sub の Receive ハンドラで、メッセージが Ack/Nack されない経路を検出する。
+// Ack も Nack もされないメッセージは期限切れ後に再配信され続けるため、実質的なリークになる
+type MessageAckAnalyzer struct {
+	typeInfo    *types.Info
+	diagnostics []analysis.Diagnostic
+}
+
+// NewMessageAckAnalyzer は新しいMessageAckAnalyzerを作成する
+func NewMessageAckAnalyzer() *MessageAckAnalyzer {
+	return &MessageAckAnalyzer{}
+}
+
+// FindUnackedMessages は Receive(ctx, func(ctx, msg *pubsub.Message){...}) のハンドラを検査し、
+// msg.Ack() も msg.Nack() も呼ばずに抜ける経路を報告する
+func (ma *MessageAckAnalyzer) FindUnackedMessages(pass *analysis.Pass) []analysis.Diagnostic {
+	if pass == nil || pass.TypesInfo == nil {
+		return nil
+	}
+	ma.typeInfo = pass.TypesInfo
+	ma.diagnostics = nil
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !ma.isReceiveCall(call) || len(call.Args) == 0 {
+				return true
+			}
+			handler, ok := call.Args[len(call.Args)-1].(*ast.FuncLit)
+			if !ok || handler.Body == nil {
+				return true
+			}
+			if msg := ma.messageParam(handler); msg != nil {
+				acked, terminated := ma.checkStatements(handler.Body.List, msg, false)
+				if !acked && !terminated {
+					ma.report(handler.Body.Rbrace)
+				}
+			}
+			return true
+		})
+	}
+
+	return ma.diagnostics
+}
+
+// isReceiveCall は Pub/Sub パッケージの Receive メソッド呼び出しかを判定する
+func (ma *MessageAckAnalyzer) isReceiveCall(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Receive" {
+		return false
+	}
+	fn, ok := ma.typeInfo.Uses[sel.Sel].(*types.Func)
+	return ok && fn.Pkg() != nil && strings.HasPrefix(fn.Pkg().Path(), pubsubPackagePrefix)
+}
+
+// messageParam はハンドラの *pubsub.Message 型の引数を返す（名前のない引数は対象外）
+func (ma *MessageAckAnalyzer) messageParam(handler *ast.FuncLit) types.Object {
+	for _, field := range handler.Type.Params.List {
+		for _, name := range field.Names {
+			obj := ma.typeInfo.Defs[name]
+			if obj == nil || name.Name == "_" {
+				continue
+			}
+			ptr, ok := obj.Type().(*types.Pointer)
+			if !ok {
+				continue
+			}
+			if named, ok := ptr.Elem().(*types.Named); ok && named.Obj().Name() == "Message" &&
+				named.Obj().Pkg() != nil && strings.HasPrefix(named.Obj().Pkg().Path(), pubsubPackagePrefix) {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// checkStatements は文の並びを順に検査し、末尾に到達した時点で Ack/Nack 済みか、
+// および全経路が return 等で終了したかを返す。未処理のまま return する経路は報告する
+func (ma *MessageAckAnalyzer) checkStatements(stmts []ast.Stmt, msg types.Object, acked bool) (bool, bool) {
+	for _, stmt := range stmts {
+		var terminated bool
+		acked, terminated = ma.checkStatement(stmt, msg, acked)
+		if terminated {
+			return acked, true
+		}
+	}
+	return acked, false
+}
+
+// checkStatement は1つの文を検査する
+func (ma *MessageAckAnalyzer) checkStatement(stmt ast.Stmt, msg types.Object, acked bool) (bool, bool) {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		if !acked && !ma.handlesMessage(s, msg) {
+			ma.report(s.Pos())
+		}
+		return true, true
+
+	case *ast.BlockStmt:
+		return ma.checkStatements(s.List, msg, acked)
+
+	case *ast.LabeledStmt:
+		return ma.checkStatement(s.Stmt, msg, acked)
+
+	case *ast.IfStmt:
+		if s.Init != nil {
+			acked, _ = ma.checkStatement(s.Init, msg, acked)
+		}
+		if !acked && ma.handlesMessage(s.Cond, msg) {
+			acked = true
+		}
+		var branches []ast.Stmt
+		branches = append(branches, s.Body)
+		if s.Else != nil {
+			branches = append(branches, s.Else)
+		}
+		return ma.mergeBranches(branches, s.Else == nil, msg, acked)
+
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		return ma.checkClauses(s, msg, acked)
+
+	case *ast.ForStmt, *ast.RangeStmt:
+		// ループ内の return は検査するが、0回で抜ける可能性があるため状態は引き継がない
+		var body *ast.BlockStmt
+		if f, ok := s.(*ast.ForStmt); ok {
+			body = f.Body
+		} else {
+			body = s.(*ast.RangeStmt).Body
+		}
+		ma.checkStatements(body.List, msg, acked)
+		return acked, false
+
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+				return acked, true
+			}
+		}
+	}
+
+	// その他の文は Ack/Nack の呼び出し（defer やクロージャ内を含む）や、
+	// 他の関数へのメッセージの受け渡しを含めば処理済みとみなす
+	return acked || ma.handlesMessage(stmt, msg), false
+}
+
+// mergeBranches は分岐ごとに検査し、末尾に到達する全分岐で Ack/Nack 済みかを返す。
+// implicit が true の場合は、どの分岐も通らない経路（else のない if 等）を含める
+func (ma *MessageAckAnalyzer) mergeBranches(branches []ast.Stmt, implicit bool, msg types.Object, acked bool) (bool, bool) {
+	allAcked := true
+	allTerminated := true
+	if implicit {
+		allAcked = acked
+		allTerminated = false
+	}
+	for _, branch := range branches {
+		branchAcked, terminated := ma.checkStatement(branch, msg, acked)
+		if terminated {
+			continue
+		}
+		allTerminated = false
+		allAcked = allAcked && branchAcked
+	}
+	if allTerminated {
+		return true, true
+	}
+	return allAcked, false
+}
+
+// checkClauses は switch/select の各節を分岐として検査する
+func (ma *MessageAckAnalyzer) checkClauses(stmt ast.Stmt, msg types.Object, acked bool) (bool, bool) {
+	var body *ast.BlockStmt
+	switch s := stmt.(type) {
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			acked, _ = ma.checkStatement(s.Init, msg, acked)
+		}
+		body = s.Body
+	case *ast.TypeSwitchStmt:
+		body = s.Body
+	case *ast.SelectStmt:
+		body = s.Body
+	}
+
+	var branches []ast.Stmt
+	hasDefault := false
+	for _, clause := range body.List {
+		switch c := clause.(type) {
+		case *ast.CaseClause:
+			hasDefault = hasDefault || c.List == nil
+			branches = append(branches, &ast.BlockStmt{List: c.Body})
+		case *ast.CommClause:
+			hasDefault = hasDefault || c.Comm == nil
+			branches = append(branches, &ast.BlockStmt{List: c.Body})
+		}
+	}
+	// select は default がなくてもいずれかの節を必ず通る
+	_, isSelect := stmt.(*ast.SelectStmt)
+	return ma.mergeBranches(branches, !hasDefault && !isSelect, msg, acked)
+}
+
+// handlesMessage はノードが msg.Ack()/msg.Nack() を呼ぶか、msg を他の関数に渡しているかを判定する
+func (ma *MessageAckAnalyzer) handlesMessage(node ast.Node, msg types.Object) bool {
+	if node == nil {
+		return false
+	}
+	handled := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if handled {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Ack" || sel.Sel.Name == "Nack") {
+			if ident, ok := sel.X.(*ast.Ident); ok && ma.typeInfo.Uses[ident] == msg {
+				handled = true
+				return false
+			}
+		}
+		for _, arg := range call.Args {
+			if ident, ok := ast.Unparen(arg).(*ast.Ident); ok && ma.typeInfo.Uses[ident] == msg {
+				handled = true
+				return false
+			}
+		}
+		return true
+	})
+	return handled
+}
+
+// report は Ack/Nack されずに抜ける経路を報告する
+func (ma *MessageAckAnalyzer) report(pos token.Pos) {
+	ma.diagnostics = append(ma.diagnostics, analysis.Diagnostic{
+		Pos:      pos,
+		Category: CategoryMessageNotAcked,
+		Message:  messages.MessageNotAcked,
+	})
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestMessageAckAnalyzer_FindUnackedMessages(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       string
+		expectedCount int
+	}{
+		{
+			name: "Always acked",
+			handler: `process(msg.Data)
+		msg.Ack()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Conditional ack without nack",
+			handler: `if err := process(msg.Data); err == nil {
+			msg.Ack()
+		}`,
+			expectedCount: 1,
+		},
+		{
+			name: "Nack on error path and ack otherwise",
+			handler: `if err := process(msg.Data); err != nil {
+			msg.Nack()
+			return
+		}
+		msg.Ack()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Early return before ack",
+			handler: `if len(msg.Data) == 0 {
+			return
+		}
+		msg.Ack()`,
+			expectedCount: 1,
+		},
+		{
+			name: "Deferred ack",
+			handler: `defer msg.Ack()
+		process(msg.Data)`,
+			expectedCount: 0,
+		},
+		{
+			name: "Switch without default",
+			handler: `switch len(msg.Data) {
+		case 0:
+			msg.Nack()
+		case 1:
+			msg.Ack()
+		}`,
+			expectedCount: 1,
+		},
+		{
+			name:          "Message handed off to another function",
+			handler:       `handle(msg)`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func process(data []byte) error { return nil }
+
+func handle(msg *pubsub.Message) { msg.Ack() }
+
+func run(ctx context.Context, sub *pubsub.Subscription) error {
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		` + tt.handler + `
+	})
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{PubSubAck: true})
+			unacked := diagnosticsContaining(diagnostics, "neither acked nor nacked")
+			if len(unacked) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(unacked), tt.expectedCount, diagnostics)
+			}
+			for _, d := range unacked {
+				if d.Category != CategoryMessageNotAcked {
+					t.Errorf("Category = %q, want %q", d.Category, CategoryMessageNotAcked)
+				}
+			}
+
+			if off := runAnalyzerOnSource(t, "test.go", src); len(diagnosticsContaining(off, "neither acked nor nacked")) != 0 {
+				t.Errorf("Expected no ack diagnostics without -gcppubsub-ack, got %v", off)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"sync"
+)
+
+// リソースごとの最終的な判定
+const (
+	decisionExempted = "exempted" // パッケージ/ファイル例外
+	decisionReleased = "released" // 解放処理を確認済み
+	decisionReported = "reported" // 診断を報告
+)
+
+// 誤検知抑制のスキップ理由（エスケープは EscapeInfo.EscapeReason を付けて記録する）
+const (
+	skipReasonEscapePrefix    = "escape: "
+	skipReasonSpannerManaged  = "spanner auto-management"
+	skipReasonClosureManaged  = "closure-managed helper"
+	skipReasonInitSkip        = "init function (analyze_init: skip)"
+	skipReasonGeneratedSkip   = "generated file (generated_file_policy: skip)"
+	skipReasonTestFileSkip    = "test file (applies_to_test_files: false)"
+	skipReasonIgnoreDirective = "function ignored by " + IgnoreDirective
+	skipReasonContextCancel   = "deferred context cancel (context_cancelled_cleanup)"
+	skipReasonRequiredAfter   = "cleanup not required yet (required_after)"
+)
+
+// Metrics は誤検知抑制の各判定でリソースがどう扱われたかの集計（-gcpmetrics）。
+// パッケージは並行に解析され、テスト有無で同じファイルが複数回解析されるため、
+// 判定はリソースの生成位置ごとに1つだけ保持する（後の判定で上書きされる）
+type Metrics struct {
+	mu        sync.Mutex
+	decisions map[string]string // 生成位置 -> 判定（スキップの場合は skip 理由）
+	skipped   map[string]bool   // スキップとして記録された判定
+}
+
+// NewMetrics は空の Metrics を作成する
+func NewMetrics() *Metrics {
+	return &Metrics{
+		decisions: make(map[string]string),
+		skipped:   make(map[string]bool),
+	}
+}
+
+// record はリソースの判定を記録する（nil の場合は何もしない）
+func (m *Metrics) record(pos token.Position, decision string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decisions[pos.String()] = decision
+}
+
+// recordSkip は誤検知抑制によりスキップしたリソースを理由とともに記録する
+func (m *Metrics) recordSkip(pos token.Position, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decisions[pos.String()] = reason
+	m.skipped[reason] = true
+}
+
+// Tracked は追跡したリソース数を返す
+func (m *Metrics) Tracked() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.decisions)
+}
+
+// Exempted はパッケージ/ファイル例外で除外したリソース数を返す
+func (m *Metrics) Exempted() int {
+	return m.count(decisionExempted)
+}
+
+// Released は解放処理を確認できたリソース数を返す
+func (m *Metrics) Released() int {
+	return m.count(decisionReleased)
+}
+
+// Reported は診断を報告したリソース数を返す
+func (m *Metrics) Reported() int {
+	return m.count(decisionReported)
+}
+
+// Skipped はスキップ理由ごとのリソース数を返す
+func (m *Metrics) Skipped() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int)
+	for _, decision := range m.decisions {
+		if m.skipped[decision] {
+			counts[decision]++
+		}
+	}
+	return counts
+}
+
+// count は指定した判定のリソース数を返す
+func (m *Metrics) count(decision string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, d := range m.decisions {
+		if d == decision {
+			n++
+		}
+	}
+	return n
+}
+
+// Write は集計結果を人が読める形式で出力する
+func (m *Metrics) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "gcpclosecheck metrics: %d resources tracked\n", m.Tracked()); err != nil {
+		return err
+	}
+	if err := writeMetricsLine(w, m.Exempted(), "exempted by package/file exceptions"); err != nil {
+		return err
+	}
+
+	skipped := m.Skipped()
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		if err := writeMetricsLine(w, skipped[reason], "skipped: "+reason); err != nil {
+			return err
+		}
+	}
+
+	if err := writeMetricsLine(w, m.Released(), "released"); err != nil {
+		return err
+	}
+	return writeMetricsLine(w, m.Reported(), "reported")
+}
+
+// writeMetricsLine は件数とラベルを1行出力する
+func writeMetricsLine(w io.Writer, count int, label string) error {
+	_, err := fmt.Fprintf(w, "  %4d  %s\n", count, label)
+	return err
+}
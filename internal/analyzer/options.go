@@ -0,0 +1,158 @@
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+)
+
+// 解放漏れの診断を報告する位置（-gcpdiagpos）
+const (
+	DiagPosCreation = "creation"  // リソースの生成位置（既定）
+	DiagPosScopeEnd = "scope-end" // 生成位置を囲むブロックの閉じ括弧の直前（解放処理を置くべき位置）
+)
+
+// validDiagPositions は -gcpdiagpos の有効な値のリスト
+var validDiagPositions = []string{DiagPosCreation, DiagPosScopeEnd}
+
+// Options は Analyzer の動作を切り替えるオプション
+type Options struct {
+	// Debug はデバッグモード
+	Debug bool
+	// ConfigPath は設定ファイルのパス
+	ConfigPath string
+	// Strict は監査用の deny-by-default モード。
+	// パッケージ/ファイル例外、自動管理リソースのフィルタリング、エスケープ判定をすべて無効化するため、
+	// 意図的にノイズが多くなる（監査時の全件洗い出し用途で、常用は想定しない）
+	Strict bool
+	// Timeout はパッケージ単位の解析時間の上限（0 の場合は無制限）。
+	// 超過した場合は残りの解析を打ち切り、その旨を通知する
+	Timeout time.Duration
+	// DuplicateCancel は同じキャンセル関数を複数回 defer している箇所を情報として報告する
+	DuplicateCancel bool
+	// ContextPropagation は派生させた context ではなく親 context を下流に渡している箇所を警告する
+	ContextPropagation bool
+	// AfterFuncStop は context.AfterFunc が返す stop 関数を破棄・未使用の箇所を警告する
+	AfterFuncStop bool
+	// Finalizer は runtime.SetFinalizer に解放を任せているリソースを、解放漏れではなく専用の警告で報告する
+	Finalizer bool
+	// CleanupOrder は依存先のリソース（client など）が依存元（iterator など）より先に解放される defer の順序を警告する
+	CleanupOrder bool
+	// DeferInLoop はループ内で生成したリソースをループ内で defer し、関数終了まで解放されない箇所を警告する
+	DeferInLoop bool
+	// ExitSkipsDefer は defer で解放するリソースの生成後に os.Exit/log.Fatal を呼び、解放処理が実行されない箇所を警告する
+	ExitSkipsDefer bool
+	// CreationError は生成時のエラーを _ に捨てる・確認しないままリソースを使用している箇所を警告する
+	CreationError bool
+	// ConditionalDefer は if !closed { defer client.Close() } のように真偽値の条件下でのみ登録される解放を警告する
+	ConditionalDefer bool
+	// UnusedResource は生成後に一度も使用されないまま解放されないリソースを専用のメッセージで報告する
+	UnusedResource bool
+	// WarnUnknown はサービスルールのない cloud.google.com/go パッケージの import を情報として報告する
+	WarnUnknown bool
+	// StructLifecycle は GCP リソースを保持するフィールドを解放するメソッドが構造体に定義されていない場合に警告する
+	StructLifecycle bool
+	// PubSubAck は Pub/Sub の Receive ハンドラでメッセージを Ack/Nack せずに抜ける経路を警告する
+	PubSubAck bool
+	// ResourceSlice は生成したリソースのスライスについて、どの要素も解放されない関数を警告する（要素単位では追跡しないヒューリスティック）
+	ResourceSlice bool
+	// DiagPos は解放漏れの診断を報告する位置（DiagPosCreation または DiagPosScopeEnd、空の場合は生成位置）
+	DiagPos string
+	// Include が空でない場合、指定したサービス（service_name）のリソースのみを報告する
+	Include []string
+	// Metrics が nil でない場合、誤検知抑制の各判定でリソースがどう扱われたかを集計する
+	Metrics *Metrics
+	// CleanupError は error_significant の解放メソッドのエラーを defer で捨てている箇所を警告する
+	CleanupError bool
+}
+
+// analyzerOptions はコマンドラインフラグから設定されるオプション
+var analyzerOptions Options
+
+// collectedMetrics は -gcpmetrics 指定時に全パッケージの判定を集計する
+var collectedMetrics = NewMetrics()
+
+// CollectedMetrics は -gcpmetrics 指定時に集計された判定を返す
+func CollectedMetrics() *Metrics {
+	return collectedMetrics
+}
+
+func init() {
+	// go vet との競合を避けるため固有の名前を使用
+	Analyzer.Flags.BoolVar(&analyzerOptions.Debug, "gcpdebug", false, "enable GCP close check debug mode")
+	Analyzer.Flags.StringVar(&analyzerOptions.ConfigPath, "gcpconfig", "", "path or HTTP(S) URL of GCP close check configuration file")
+	Analyzer.Flags.BoolVar(&analyzerOptions.Strict, "gcpstrict", false,
+		"disable all package exemptions and escape heuristics (intentionally noisy, for audits)")
+	Analyzer.Flags.DurationVar(&analyzerOptions.Timeout, "gcptimeout", 0,
+		"maximum analysis time per package, e.g. 30s (0 means no limit)")
+	Analyzer.Flags.BoolVar(&analyzerOptions.DuplicateCancel, "gcpduplicate-cancel", false,
+		"report cancel functions deferred more than once (informational)")
+	Analyzer.Flags.BoolVar(&analyzerOptions.ContextPropagation, "gcpcontext-propagation", false,
+		"warn when a derived context is not passed on and its parent is used instead")
+	Analyzer.Flags.BoolVar(&analyzerOptions.AfterFuncStop, "gcpafterfunc-stop", false,
+		"warn when the stop function returned by context.AfterFunc is discarded or never called")
+	Analyzer.Flags.BoolVar(&analyzerOptions.Finalizer, "gcpfinalizer", false,
+		"report resources released only by runtime.SetFinalizer with a dedicated warning")
+	Analyzer.Flags.BoolVar(&analyzerOptions.CleanupOrder, "gcpcleanup-order", false,
+		"warn when defers release a resource before the resources derived from it (e.g. client before iterator)")
+	Analyzer.Flags.BoolVar(&analyzerOptions.DeferInLoop, "gcpdefer-in-loop", false,
+		"warn when a resource created in a loop is released by a defer in the loop body (runs only at function exit)")
+	Analyzer.Flags.BoolVar(&analyzerOptions.ExitSkipsDefer, "gcpexit-skips-defer", false,
+		"warn when os.Exit or log.Fatal after a deferred cleanup prevents it from running")
+	Analyzer.Flags.BoolVar(&analyzerOptions.CreationError, "gcpcreation-error", false,
+		"warn when a resource is used although the error returned with it is ignored or not yet checked")
+	Analyzer.Flags.BoolVar(&analyzerOptions.ConditionalDefer, "gcpconditional-defer", false,
+		"warn when a cleanup is deferred only under a boolean guard such as if !closed { defer c.Close() }")
+	Analyzer.Flags.BoolVar(&analyzerOptions.UnusedResource, "gcpunused-resource", false,
+		"report resources that are created but never used or closed with a dedicated message")
+	Analyzer.Flags.BoolVar(&analyzerOptions.WarnUnknown, "gcpwarn-unknown", false,
+		"report imported cloud.google.com/go packages that have no service rule (informational)")
+	Analyzer.Flags.BoolVar(&analyzerOptions.StructLifecycle, "gcpstruct-lifecycle", false,
+		"warn when a struct holds a GCP resource in a field but none of its methods closes that field")
+	Analyzer.Flags.BoolVar(&analyzerOptions.PubSubAck, "gcppubsub-ack", false,
+		"warn when a Pub/Sub receive handler returns without acking or nacking the message")
+	Analyzer.Flags.BoolVar(&analyzerOptions.ResourceSlice, "gcpresource-slice", false,
+		"warn when a function gets a slice of GCP resources from a call but closes none of its elements (heuristic)")
+	Analyzer.Flags.Func("gcpinclude",
+		"comma-separated service names whose resource diagnostics are reported, e.g. spanner,storage", func(value string) error {
+			analyzerOptions.Include = splitList(value)
+			return nil
+		})
+	Analyzer.Flags.Func("gcpdiagpos",
+		"position of resource leak diagnostics: creation (default) or scope-end (before the closing brace of the enclosing block)", func(value string) error {
+			if !slices.Contains(validDiagPositions, value) {
+				return fmt.Errorf(messages.InvalidDiagnosticPosition, value, validDiagPositions)
+			}
+			analyzerOptions.DiagPos = value
+			return nil
+		})
+	Analyzer.Flags.BoolFunc("gcpmetrics",
+		"print how many resources each false-positive filter excluded after analyzing all packages", func(value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			analyzerOptions.Metrics = nil
+			if enabled {
+				analyzerOptions.Metrics = collectedMetrics
+			}
+			return nil
+		})
+	Analyzer.Flags.BoolVar(&analyzerOptions.CleanupError, "gcpcleanup-error", false,
+		"warn when a deferred cleanup discards the error of a method marked error_significant (e.g. storage Writer.Close)")
+}
+
+// splitList はカンマ区切りの値を空要素を除いて分割する
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
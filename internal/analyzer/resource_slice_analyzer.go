@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+)
+
+// ResourceSliceAnalyzer は clients := makeClients(ctx, projects...) のように
+// GCP リソースのスライスを返す呼び出しについて、要素ごとの解放が関数内にあるかを検査する。
+// 要素単位の追跡はできないため、range で回した要素やインデックス参照の要素に対する
+// 解放メソッドの呼び出しが 1 つもない場合に警告するヒューリスティック
+type ResourceSliceAnalyzer struct {
+	resourceTracker *ResourceTracker
+	typeInfo        *types.Info
+}
+
+// NewResourceSliceAnalyzer は新しいResourceSliceAnalyzerを作成する
+func NewResourceSliceAnalyzer(resourceTracker *ResourceTracker, typeInfo *types.Info) *ResourceSliceAnalyzer {
+	return &ResourceSliceAnalyzer{resourceTracker: resourceTracker, typeInfo: typeInfo}
+}
+
+// resourceSlice は関数内で生成されたリソースのスライス変数
+type resourceSlice struct {
+	assign        *ast.AssignStmt
+	call          *ast.CallExpr
+	variable      *types.Var
+	serviceName   string
+	cleanupMethod string
+}
+
+// FindUnclosedSlices は関数内で生成したリソースのスライスのうち、どの要素も解放されないものを報告する。
+// スライスを返す・他の関数に渡す・フィールドに代入するなど、要素の解放を他に委ねる使い方がある場合は報告しない。
+// filter には単独のリソースと同じ除外（-gcpinclude・applies_to_test_files・context_cancelled_cleanup）を渡し、
+// スライスを返した呼び出しを生成位置とする ResourceInfo のうち filter が残したもののみを報告する
+func (ra *ResourceSliceAnalyzer) FindUnclosedSlices(fn *ast.FuncDecl, filter func([]ResourceInfo) []ResourceInfo) []analysis.Diagnostic {
+	if fn == nil || fn.Body == nil || ra.typeInfo == nil {
+		return nil
+	}
+
+	unclosed := make(map[token.Pos]resourceSlice)
+	var candidates []ResourceInfo
+	for _, slice := range ra.resourceSlices(fn.Body) {
+		if ra.isHandedOff(fn.Body, slice) || ra.closesElement(fn.Body, slice) {
+			continue
+		}
+		unclosed[slice.call.Pos()] = slice
+		candidates = append(candidates, ResourceInfo{
+			Variable:      slice.variable,
+			VariableName:  slice.variable.Name(),
+			CreationPos:   slice.call.Pos(),
+			ServiceType:   slice.serviceName,
+			CleanupMethod: slice.cleanupMethod,
+			IsRequired:    true,
+		})
+	}
+	if filter != nil {
+		candidates = filter(candidates)
+	}
+
+	var diagnostics []analysis.Diagnostic
+	for _, resource := range candidates {
+		slice := unclosed[resource.CreationPos]
+		diagnostics = append(diagnostics, analysis.Diagnostic{
+			Pos:      slice.assign.Pos(),
+			End:      slice.assign.End(),
+			Category: CategoryResourceSliceNotClosed,
+			Message: fmt.Sprintf(messages.ResourceSliceNotClosed,
+				slice.variable.Name(), slice.variable.Name(), slice.cleanupMethod),
+		})
+	}
+	return diagnostics
+}
+
+// resourceSlices は関数呼び出しの戻り値（リソースのスライス）を代入した変数を返す。
+// make やスライスリテラルは要素が個別に代入されるため対象外にする
+func (ra *ResourceSliceAnalyzer) resourceSlices(body *ast.BlockStmt) []resourceSlice {
+	var found []resourceSlice
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+			return true
+		}
+		call, ok := ast.Unparen(assign.Rhs[0]).(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if tv, ok := ra.typeInfo.Types[call.Fun]; ok && (tv.IsBuiltin() || tv.IsType()) {
+			return true
+		}
+		ident, ok := assign.Lhs[0].(*ast.Ident)
+		if !ok {
+			return true
+		}
+		variable, ok := ra.typeInfo.ObjectOf(ident).(*types.Var)
+		if !ok {
+			return true
+		}
+		sliceType, ok := variable.Type().Underlying().(*types.Slice)
+		if !ok {
+			return true
+		}
+		if serviceName, cleanupMethod, ok := ra.resourceTracker.CleanupMethodForType(sliceType.Elem()); ok {
+			found = append(found, resourceSlice{assign: assign, call: call, variable: variable, serviceName: serviceName, cleanupMethod: cleanupMethod})
+		}
+		return true
+	})
+	return found
+}
+
+// isHandedOff はスライスが range・インデックス参照・len/cap 以外で使われている（返す・渡す・代入する等）かを判定する
+func (ra *ResourceSliceAnalyzer) isHandedOff(body *ast.BlockStmt, slice resourceSlice) bool {
+	// 代入先（clients = makeClients(...)）は使用に含めない
+	allowed := map[*ast.Ident]bool{}
+	if ident, ok := slice.assign.Lhs[0].(*ast.Ident); ok {
+		allowed[ident] = true
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.RangeStmt:
+			if ident, ok := node.X.(*ast.Ident); ok {
+				allowed[ident] = true
+			}
+		case *ast.IndexExpr:
+			if ident, ok := node.X.(*ast.Ident); ok {
+				allowed[ident] = true
+			}
+		case *ast.CallExpr:
+			if fun, ok := node.Fun.(*ast.Ident); ok && (fun.Name == "len" || fun.Name == "cap") && len(node.Args) == 1 {
+				if ident, ok := node.Args[0].(*ast.Ident); ok {
+					allowed[ident] = true
+				}
+			}
+		}
+		return true
+	})
+
+	handedOff := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ra.typeInfo.Uses[ident] == slice.variable && !allowed[ident] {
+			handedOff = true
+		}
+		return !handedOff
+	})
+	return handedOff
+}
+
+// closesElement は range で回した要素（for _, c := range clients）または
+// インデックス参照の要素（clients[i]）に対して解放メソッドを呼んでいるかを判定する
+func (ra *ResourceSliceAnalyzer) closesElement(body *ast.BlockStmt, slice resourceSlice) bool {
+	elements := make(map[types.Object]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		rangeStmt, ok := n.(*ast.RangeStmt)
+		if !ok || rangeStmt.Value == nil {
+			return true
+		}
+		if ident, ok := rangeStmt.X.(*ast.Ident); ok && ra.typeInfo.Uses[ident] == slice.variable {
+			if value, ok := rangeStmt.Value.(*ast.Ident); ok {
+				elements[ra.typeInfo.ObjectOf(value)] = true
+			}
+		}
+		return true
+	})
+
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return !found
+		}
+		sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != slice.cleanupMethod {
+			return true
+		}
+		switch receiver := ast.Unparen(sel.X).(type) {
+		case *ast.Ident:
+			found = elements[ra.typeInfo.Uses[receiver]]
+		case *ast.IndexExpr:
+			if ident, ok := receiver.X.(*ast.Ident); ok {
+				found = ra.typeInfo.Uses[ident] == slice.variable
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,231 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResourceSliceAnalyzer_FindUnclosedSlices(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "No per-element close",
+			body: `clients, err := makeClients(ctx, projects...)
+	if err != nil {
+		return err
+	}
+	for _, c := range clients {
+		_ = c
+	}
+	return nil`,
+			expectedCount: 1,
+		},
+		{
+			name: "Ranged defer close",
+			body: `clients, err := makeClients(ctx, projects...)
+	if err != nil {
+		return err
+	}
+	for _, c := range clients {
+		defer c.Close()
+	}
+	return nil`,
+			expectedCount: 0,
+		},
+		{
+			name: "Close by index",
+			body: `clients, _ := makeClients(ctx, projects...)
+	defer func() {
+		for i := range clients {
+			clients[i].Close()
+		}
+	}()
+	return nil`,
+			expectedCount: 0,
+		},
+		{
+			name: "Handed off to a cleanup helper",
+			body: `clients, _ := makeClients(ctx, projects...)
+	defer closeAll(clients)
+	return nil`,
+			expectedCount: 0,
+		},
+		{
+			name: "Slice built with make",
+			body: `clients := make([]*spanner.Client, len(projects))
+	_ = clients
+	return nil`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func makeClients(ctx context.Context, projects ...string) ([]*spanner.Client, error) {
+	var clients []*spanner.Client
+	for _, p := range projects {
+		c, err := spanner.NewClient(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+func closeAll(clients []*spanner.Client) {
+	for _, c := range clients {
+		c.Close()
+	}
+}
+
+func run(ctx context.Context, projects []string) error {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ResourceSlice: true})
+			unclosed := diagnosticsWithCategory(diagnostics, CategoryResourceSliceNotClosed)
+			if len(unclosed) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(unclosed), tt.expectedCount, diagnostics)
+			}
+			if len(unclosed) > 0 && len(diagnosticsContaining(unclosed, "slice of resources created; ensure each is closed")) != len(unclosed) {
+				t.Errorf("Unexpected message: %v", unclosed)
+			}
+		})
+	}
+}
+
+func TestResourceSliceAnalyzer_OptionsAndFilters(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func makeClients(ctx context.Context, projects ...string) ([]*spanner.Client, error) {
+	return nil, nil
+}
+
+func unclosed(ctx context.Context, projects []string) {
+	clients, _ := makeClients(ctx, projects...)
+	for _, c := range clients {
+		_ = c
+	}
+}
+
+func cancelled(ctx context.Context, projects []string) {
+	clientCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	clients, _ := makeClients(clientCtx, projects...)
+	_ = len(clients)
+}
+`
+
+	writeConfig := func(t *testing.T, extra string) string {
+		t.Helper()
+		content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+` + extra
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name          string
+		filename      string
+		opts          Options
+		extraConfig   string
+		expectedCount int
+	}{
+		{"disabled by default", "clients.go", Options{}, "", 0},
+		{"enabled", "clients.go", Options{ResourceSlice: true}, "", 2},
+		{"include skips other services", "clients.go", Options{ResourceSlice: true, Include: []string{"storage"}},
+			"  - service_name: storage\n    package_path: cloud.google.com/go/storage\n    creation_functions: [NewClient]\n" +
+				"    cleanup_methods:\n      - method: Close\n        required: true\n", 0},
+		{"applies_to_test_files: false", "clients_test.go", Options{ResourceSlice: true}, "    applies_to_test_files: false\n", 0},
+		{"context_cancelled_cleanup", "clients.go", Options{ResourceSlice: true}, "    context_cancelled_cleanup: true\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.ConfigPath = writeConfig(t, tt.extraConfig)
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", tt.filename, src, tt.opts)
+			unclosed := diagnosticsWithCategory(diagnostics, CategoryResourceSliceNotClosed)
+			if len(unclosed) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(unclosed), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceSliceAnalyzer_DiagnosticPosition(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func makeClients(ctx context.Context) ([]*spanner.Client, error) {
+	return nil, nil
+}
+
+func unclosed(ctx context.Context) {
+	clients, _ := makeClients(ctx)
+	_ = len(clients)
+}
+`
+
+	// 単一ファイルの FileSet ではオフセット 0 が Pos 1 になる
+	assign := strings.Index(src, "clients, _ :=") + 1
+	scopeEnd := strings.LastIndex(src, "}") + 1
+
+	tests := []struct {
+		diagPos string
+		want    int
+	}{
+		{"", assign},
+		{DiagPosCreation, assign},
+		{DiagPosScopeEnd, scopeEnd},
+	}
+
+	for _, tt := range tests {
+		t.Run("diagpos="+tt.diagPos, func(t *testing.T) {
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ResourceSlice: true, DiagPos: tt.diagPos})
+			unclosed := diagnosticsWithCategory(diagnostics, CategoryResourceSliceNotClosed)
+			if len(unclosed) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %d: %v", len(unclosed), diagnostics)
+			}
+			if got := int(unclosed[0].Pos); got != tt.want {
+				t.Errorf("Pos = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
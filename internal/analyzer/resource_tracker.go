@@ -1,7 +1,9 @@
 package analyzer
 
 import (
+	"context"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"strings"
 
@@ -13,17 +15,27 @@ type ResourceTracker struct {
 	typeInfo   *types.Info
 	ruleEngine *ServiceRuleEngine
 	variables  map[*types.Var]*ResourceInfo
+	// constructors はパッケージ内の関数がリソースを生成して返すコンストラクタかの判定結果のキャッシュ
+	constructors map[*types.Func]bool
+	// importFact は依存パッケージの関数に付与された ReturnsResourceFact を取得する（nil の場合は Fact を使わない）
+	importFact func(types.Object, analysis.Fact) bool
 }
 
 // NewResourceTracker は新しいResourceTrackerを作成する
 func NewResourceTracker(typeInfo *types.Info, ruleEngine *ServiceRuleEngine) *ResourceTracker {
 	return &ResourceTracker{
-		typeInfo:   typeInfo,
-		ruleEngine: ruleEngine,
-		variables:  make(map[*types.Var]*ResourceInfo),
+		typeInfo:     typeInfo,
+		ruleEngine:   ruleEngine,
+		variables:    make(map[*types.Var]*ResourceInfo),
+		constructors: make(map[*types.Func]bool),
 	}
 }
 
+// SetFactImporter は依存パッケージのコンストラクタを判定するための Fact の取得関数を設定する
+func (rt *ResourceTracker) SetFactImporter(importFact func(types.Object, analysis.Fact) bool) {
+	rt.importFact = importFact
+}
+
 // TrackCall は関数呼び出しを解析してGCPリソース生成を追跡する
 func (rt *ResourceTracker) TrackCall(call *ast.CallExpr) error {
 	if rt == nil || rt.typeInfo == nil || rt.ruleEngine == nil {
@@ -77,21 +89,35 @@ func (rt *ResourceTracker) TrackCall(call *ast.CallExpr) error {
 
 // FindResourceCreation はanalysis.Passを使用してリソース生成を検出する
 func (rt *ResourceTracker) FindResourceCreation(pass *analysis.Pass) []ResourceInfo {
+	resources, _ := rt.FindResourceCreationContext(context.Background(), pass)
+	return resources
+}
+
+// FindResourceCreationContext は FindResourceCreation と同様にリソース生成を検出する。
+// トップレベル宣言ごとに ctx を確認し、期限切れの場合はそれまでに検出したリソースと ctx.Err() を返す
+func (rt *ResourceTracker) FindResourceCreationContext(ctx context.Context, pass *analysis.Pass) ([]ResourceInfo, error) {
 	if pass == nil || len(pass.Files) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	var resources []ResourceInfo
+	var err error
 
 	// 各ファイルを走査
+scan:
 	for _, file := range pass.Files {
-		ast.Inspect(file, func(n ast.Node) bool {
-			// 代入文を検索してリソース生成を検出
-			if assignStmt, ok := n.(*ast.AssignStmt); ok {
-				rt.trackAssignmentStatement(assignStmt, pass)
+		for _, decl := range file.Decls {
+			if err = ctx.Err(); err != nil {
+				break scan
 			}
-			return true
-		})
+			ast.Inspect(decl, func(n ast.Node) bool {
+				// 代入文を検索してリソース生成を検出
+				if assignStmt, ok := n.(*ast.AssignStmt); ok {
+					rt.trackAssignmentStatement(assignStmt, pass)
+				}
+				return true
+			})
+		}
 	}
 
 	// 追跡されたリソースを返す
@@ -99,7 +125,7 @@ func (rt *ResourceTracker) FindResourceCreation(pass *analysis.Pass) []ResourceI
 		resources = append(resources, *info)
 	}
 
-	return resources
+	return resources, err
 }
 
 // IsResourceType は型がGCPリソース型かどうかを判定する
@@ -145,25 +171,42 @@ func (rt *ResourceTracker) GetPackageInfo(packagePath string) (bool, string) {
 
 	// GCPパッケージのパターン
 	gcpPatterns := map[string]string{
-		"cloud.google.com/go/spanner":                   "spanner",
-		"cloud.google.com/go/storage":                   "storage",
-		"cloud.google.com/go/pubsub":                    "pubsub",
-		"cloud.google.com/go/bigquery":                  "bigquery",
-		"cloud.google.com/go/firestore":                 "firestore",
-		"cloud.google.com/go/vision/apiv1":              "vision",
-		"cloud.google.com/go/iam/admin/apiv1":           "admin",
-		"cloud.google.com/go/recaptchaenterprise/apiv1": "recaptcha",
-		"cloud.google.com/go/functions/apiv1":           "functions",
+		"cloud.google.com/go/spanner":                      "spanner",
+		"cloud.google.com/go/spanner/admin/database/apiv1": "spanneradmin",
+		"cloud.google.com/go/spanner/admin/instance/apiv1": "spannerinstanceadmin",
+		"cloud.google.com/go/storage":                      "storage",
+		"cloud.google.com/go/pubsub":                       "pubsub",
+		"cloud.google.com/go/bigquery":                     "bigquery",
+		"cloud.google.com/go/firestore":                    "firestore",
+		"cloud.google.com/go/vision/apiv1":                 "vision",
+		"cloud.google.com/go/iam/admin/apiv1":              "admin",
+		"cloud.google.com/go/recaptchaenterprise/apiv1":    "recaptcha",
+		"cloud.google.com/go/functions/apiv1":              "functions",
 	}
 
 	if service, exists := gcpPatterns[packagePath]; exists {
 		return true, service
 	}
 
-	// プレフィックスマッチも試行
-	for path, service := range gcpPatterns {
-		if strings.HasPrefix(packagePath, path) {
-			return true, service
+	// プレフィックスマッチも試行（spanner と spanner/admin/... のように重なる場合は最長一致を優先する）
+	matched := ""
+	for path := range gcpPatterns {
+		if strings.HasPrefix(packagePath, path) && len(path) > len(matched) {
+			matched = path
+		}
+	}
+	if matched != "" {
+		return true, gcpPatterns[matched]
+	}
+
+	// 設定ファイルで追加されたサービスのパッケージ
+	if rt.ruleEngine != nil && rt.ruleEngine.config != nil {
+		if service := rt.ruleEngine.config.GetServiceByPackagePath(packagePath); service != nil {
+			return true, service.ServiceName
+		}
+		// フォークやミラー（package_path_aliases）
+		if serviceName, ok := rt.ruleEngine.config.ServiceForPackageAlias(packagePath); ok {
+			return true, serviceName
 		}
 	}
 
@@ -198,6 +241,16 @@ func (rt *ResourceTracker) extractFunctionIdent(call *ast.CallExpr) *ast.Ident {
 
 // extractPackagePath は関数呼び出しからパッケージパスを抽出する
 func (rt *ResourceTracker) extractPackagePath(call *ast.CallExpr, _ *ast.Ident) string {
+	// 非修飾の関数呼び出し（ドットインポートされたパッケージの関数）
+	if ident, ok := call.Fun.(*ast.Ident); ok {
+		if rt.typeInfo != nil && rt.typeInfo.Uses != nil {
+			if fn, ok := rt.typeInfo.Uses[ident].(*types.Func); ok && fn.Pkg() != nil {
+				return fn.Pkg().Path()
+			}
+		}
+		return ""
+	}
+
 	// セレクタ式の場合（pkg.Function または obj.Method）
 	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
 		// パッケージ関数の場合（pkg.Function）
@@ -217,6 +270,17 @@ func (rt *ResourceTracker) extractPackagePath(call *ast.CallExpr, _ *ast.Ident)
 		if rt.typeInfo != nil && rt.typeInfo.Types != nil {
 			if typeAndValue, exists := rt.typeInfo.Types[sel.X]; exists {
 				if typeAndValue.Type != nil {
+					// インターフェースのメソッド呼び出し（repo.NewClient(ctx)）は実装が見えないため、
+					// 戻り値の静的な型（*storage.Client 等）のパッケージを使う
+					if types.IsInterface(typeAndValue.Type) {
+						return namedTypePackagePath(firstResultType(rt.typeInfo.TypeOf(call)))
+					}
+
+					// google.golang.org/api の型は名前に storage 等を含んでも Cloud クライアントとは別物
+					if pkgPath := namedTypePackagePath(typeAndValue.Type); isGoogleAPIPackage(pkgPath) {
+						return pkgPath
+					}
+
 					typeName := typeAndValue.Type.String()
 					// 型名からパッケージパスを推定
 					if strings.Contains(typeName, "spanner") {
@@ -239,6 +303,24 @@ func (rt *ResourceTracker) extractPackagePath(call *ast.CallExpr, _ *ast.Ident)
 	return ""
 }
 
+// isGoogleAPIPackage は google.golang.org/api 配下の生成された REST クライアントかどうかを判定する。
+// これらは http.Client を使い Close を必要としないため既定では追跡しない
+// （Close を持つクライアントを所有する場合は設定ファイルでルールを追加できる）
+func isGoogleAPIPackage(packagePath string) bool {
+	return strings.HasPrefix(packagePath, "google.golang.org/api/")
+}
+
+// namedTypePackagePath はポインタを外した名前付き型の定義パッケージのパスを返す
+func namedTypePackagePath(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg().Path()
+	}
+	return ""
+}
+
 // isCreationFunction は関数名がリソース生成関数かどうかを確認する
 func (rt *ResourceTracker) isCreationFunction(serviceRule *ServiceRule, funcName string) bool {
 	if serviceRule == nil {
@@ -256,46 +338,23 @@ func (rt *ResourceTracker) isCreationFunction(serviceRule *ServiceRule, funcName
 
 // createResourceInfo はResourceInfoを作成する
 func (rt *ResourceTracker) createResourceInfo(call *ast.CallExpr, serviceName string, serviceRule *ServiceRule) *ResourceInfo {
-	if serviceRule == nil || len(serviceRule.CleanupMethods) == 0 {
-		return nil
-	}
-
 	// 関数名を取得
 	funcIdent := rt.extractFunctionIdent(call)
 	funcName := ""
 	if funcIdent != nil {
 		funcName = funcIdent.Name
 	}
+	return rt.newResourceInfo(call, funcName, serviceName, serviceRule)
+}
 
-	// 関数名に基づいてクリーンアップメソッドを決定
-	var cleanupMethod string
-	isRequired := true
-
-	// 特定の関数に対する特別なクリーンアップメソッド
-	switch funcName {
-	case "ReadOnlyTransaction", "ReadWriteTransaction", "BatchReadOnlyTransaction":
-		cleanupMethod = "Close" // Transactionは必ずClose
-		isRequired = true
-	case "Query", "Read":
-		cleanupMethod = "Stop" // IteratorはStop
-		isRequired = true
-	default:
-		// デフォルトのクリーンアップメソッドを取得
-		for _, method := range serviceRule.CleanupMethods {
-			if method.Required {
-				cleanupMethod = method.Method
-				isRequired = true
-				break
-			}
-		}
-
-		if cleanupMethod == "" && len(serviceRule.CleanupMethods) > 0 {
-			// 必須でなくても最初のメソッドを使用
-			cleanupMethod = serviceRule.CleanupMethods[0].Method
-			isRequired = serviceRule.CleanupMethods[0].Required
-		}
+// newResourceInfo は生成関数名 funcName に基づいて ResourceInfo を作成する
+func (rt *ResourceTracker) newResourceInfo(call *ast.CallExpr, funcName, serviceName string, serviceRule *ServiceRule) *ResourceInfo {
+	if serviceRule == nil || len(serviceRule.CleanupMethods) == 0 {
+		return nil
 	}
 
+	cleanupMethod, isRequired := cleanupMethodFor(funcName, serviceRule)
+
 	// ResourceInfoを作成
 	resourceInfo := &ResourceInfo{
 		Variable:         nil, // 後で設定
@@ -304,6 +363,9 @@ func (rt *ResourceTracker) createResourceInfo(call *ast.CallExpr, serviceName st
 		CreationFunction: funcName,
 		CleanupMethod:    cleanupMethod,
 		IsRequired:       isRequired,
+		ErrorSignificant: serviceRule.IsErrorSignificant(funcName, cleanupMethod),
+		RequiresContext:  serviceRule.RequiresContext(funcName, cleanupMethod),
+		RequiredAfter:    serviceRule.RequiredAfter(funcName, cleanupMethod),
 		Scope:            nil, // 後で設定
 	}
 
@@ -315,6 +377,36 @@ func (rt *ResourceTracker) createResourceInfo(call *ast.CallExpr, serviceName st
 	return resourceInfo
 }
 
+// cleanupMethodFor は生成関数の関数名に基づいてクリーンアップメソッドとその要否を決定する
+func cleanupMethodFor(funcName string, serviceRule *ServiceRule) (string, bool) {
+	// 特定の関数に対する特別なクリーンアップメソッド
+	switch funcName {
+	case "ReadOnlyTransaction", "ReadWriteTransaction", "BatchReadOnlyTransaction":
+		return "Close", true // Transactionは必ずClose
+	case "Query", "Read":
+		return "Stop", true // IteratorはStop
+	}
+
+	// applies_to で生成関数を指定した解放メソッド（pubsub の Topic の Stop 等）を優先する
+	for _, method := range serviceRule.CleanupMethods {
+		if method.Required && len(method.AppliesTo) > 0 && method.AppliesToFunction(funcName) {
+			return method.Method, true
+		}
+	}
+
+	// デフォルトのクリーンアップメソッドを取得
+	for _, method := range serviceRule.CleanupMethods {
+		if method.Required && method.AppliesToFunction(funcName) {
+			return method.Method, true
+		}
+	}
+	if len(serviceRule.CleanupMethods) > 0 {
+		// 必須でなくても最初のメソッドを使用
+		return serviceRule.CleanupMethods[0].Method, serviceRule.CleanupMethods[0].Required
+	}
+	return "", false
+}
+
 // trackVariableAssignment は変数への代入を追跡する（AST解析改良版）
 func (rt *ResourceTracker) trackVariableAssignment(call *ast.CallExpr, resourceInfo *ResourceInfo) {
 	if resourceInfo == nil {
@@ -435,7 +527,7 @@ func (rt *ResourceTracker) inferVariableNameFromFunction(funcName string) string
 	switch funcName {
 	case "NewClient":
 		return "client"
-	case "NewReader":
+	case "NewReader", "NewRangeReader":
 		return "reader"
 	case "NewWriter":
 		return "writer"
@@ -443,6 +535,8 @@ func (rt *ResourceTracker) inferVariableNameFromFunction(funcName string) string
 		return "tx"
 	case "Query":
 		return "iter"
+	case "Topic", "TopicInProject":
+		return "topic"
 	case "NewImageAnnotatorClient":
 		return "client"
 	case "NewProductSearchClient":
@@ -569,12 +663,24 @@ func (rt *ResourceTracker) trackAssignmentStatement(assignStmt *ast.AssignStmt,
 	}
 
 	for i, rhs := range assignStmt.Rhs {
+		// pool.Get().(*spanner.Client) のようにプールから借りたリソース
+		if assert, ok := rhs.(*ast.TypeAssertExpr); ok {
+			rt.trackPoolGetAssertion(assignStmt, i, assert)
+			continue
+		}
+
 		if call, ok := rhs.(*ast.CallExpr); ok {
 			// ラップされたSpannerトランザクションは除外
 			if rt.isWrappedSpannerTransactionCall(call) {
 				continue
 			}
 
+			// must(spanner.NewClient(ctx, db)) や mustClient(ctx)、c, _ := open() のように生成をラップした呼び出し
+			if !rt.isResourceCreationCall(call) && (len(assignStmt.Lhs) == len(assignStmt.Rhs) || len(assignStmt.Rhs) == 1) {
+				rt.trackWrappedCreationCall(assignStmt, i, call, pass)
+				continue
+			}
+
 			// リソース生成かチェック
 			if rt.isResourceCreationCall(call) {
 				// 複数戻り値の場合は、GCPリソースを返す戻り値のみ追跡
@@ -584,7 +690,11 @@ func (rt *ResourceTracker) trackAssignmentStatement(assignStmt *ast.AssignStmt,
 					// 単一戻り値の場合
 					varName := rt.extractVariableNameFromAssignment(assignStmt, i)
 					if varName != "" {
-						rt.trackCallWithVariableName(call, varName, pass)
+						variable := rt.extractVariableFromAssignment(assignStmt, i)
+						rt.trackCallWithVariable(call, varName, variable, pass)
+					} else {
+						// clients[0], err = storage.NewClient(ctx) のように定数インデックスの要素へ代入
+						rt.trackIndexedElement(assignStmt, i, call, pass)
 					}
 				}
 			}
@@ -619,6 +729,469 @@ func (rt *ResourceTracker) extractVariableNameFromAssignment(assignStmt *ast.Ass
 	return ""
 }
 
+// extractVariableFromAssignment は代入文の左辺識別子に束縛された変数を型情報から取得する
+func (rt *ResourceTracker) extractVariableFromAssignment(assignStmt *ast.AssignStmt, rhsIndex int) *types.Var {
+	if rt.typeInfo == nil || len(assignStmt.Lhs) == 0 {
+		return nil
+	}
+
+	lhsIndex := rhsIndex
+	if lhsIndex >= len(assignStmt.Lhs) {
+		lhsIndex = 0
+	}
+
+	ident, ok := assignStmt.Lhs[lhsIndex].(*ast.Ident)
+	if !ok || ident.Name == "_" {
+		return nil
+	}
+
+	// := による定義は Defs、= による再代入は Uses に記録される
+	if obj, ok := rt.typeInfo.Defs[ident].(*types.Var); ok {
+		return obj
+	}
+	if obj, ok := rt.typeInfo.Uses[ident].(*types.Var); ok {
+		return obj
+	}
+
+	return nil
+}
+
+// trackIndexedElement は定数インデックスのスライス・配列要素（clients[0] 等）に代入したリソースを追跡する。
+// 要素ごとに合成した変数で記録し、defer clients[0].Close() を解放処理として照合できるようにする。
+// スライス自体を range で回す・返す・別名に代入するなど要素単位で追えない使い方がある場合は、従来どおり追跡しない
+func (rt *ResourceTracker) trackIndexedElement(assignStmt *ast.AssignStmt, rhsIndex int, call *ast.CallExpr, pass *analysis.Pass) {
+	if rt.typeInfo == nil || pass == nil || len(assignStmt.Lhs) == 0 {
+		return
+	}
+	lhsIndex := rhsIndex
+	if lhsIndex >= len(assignStmt.Lhs) {
+		lhsIndex = 0
+	}
+	index, ok := assignStmt.Lhs[lhsIndex].(*ast.IndexExpr)
+	if !ok {
+		return
+	}
+	name := constantIndexName(rt.typeInfo, index)
+	if name == "" {
+		return
+	}
+	base, ok := rt.typeInfo.Uses[index.X.(*ast.Ident)].(*types.Var)
+	if !ok || !usedOnlyByConstantIndex(rt.typeInfo, pass.Files, base) {
+		return
+	}
+
+	element := types.NewVar(index.Pos(), pass.Pkg, name, rt.typeInfo.TypeOf(index))
+	rt.trackCallWithVariable(call, name, element, pass)
+	if resourceInfo, ok := rt.variables[element]; ok {
+		resourceInfo.Scope = base.Parent()
+	}
+}
+
+// constantIndexName は base[定数] 形式のインデックス式を "base[値]" に正規化した名前を返す（それ以外は空文字列）
+func constantIndexName(typeInfo *types.Info, index *ast.IndexExpr) string {
+	ident, ok := index.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if typeInfo == nil {
+		if lit, ok := index.Index.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			return ident.Name + "[" + lit.Value + "]"
+		}
+		return ""
+	}
+	value := typeInfo.Types[index.Index].Value
+	if value == nil {
+		return ""
+	}
+	return ident.Name + "[" + value.ExactString() + "]"
+}
+
+// usedOnlyByConstantIndex はスライス・配列変数 base の参照がすべて、代入先または
+// メソッド呼び出し・フィールド参照のレシーバとしての定数インデックス（clients[0] = ..., clients[0].Close()）かを判定する
+func usedOnlyByConstantIndex(typeInfo *types.Info, files []*ast.File, base *types.Var) bool {
+	allowed := make(map[*ast.Ident]bool)
+	allow := func(expr ast.Expr) {
+		if index, ok := expr.(*ast.IndexExpr); ok && constantIndexName(typeInfo, index) != "" {
+			allowed[index.X.(*ast.Ident)] = true
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range node.Lhs {
+					allow(lhs)
+				}
+			case *ast.SelectorExpr:
+				allow(node.X)
+			}
+			return true
+		})
+	}
+
+	for ident, obj := range typeInfo.Uses {
+		if obj == base && !allowed[ident] {
+			return false
+		}
+	}
+	return true
+}
+
+// trackPoolGetAssertion は sync.Pool.Get() の結果を GCP リソース型へ型アサーションした代入を追跡する
+// 借りたリソースは Close ではなく pool.Put(x) で返却する必要がある
+func (rt *ResourceTracker) trackPoolGetAssertion(assignStmt *ast.AssignStmt, rhsIndex int, assert *ast.TypeAssertExpr) {
+	if rt.typeInfo == nil || assert.Type == nil {
+		return
+	}
+
+	call, ok := assert.X.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != PoolGetMethod || !rt.isSyncPool(sel.X) {
+		return
+	}
+
+	// アサーション先の型が GCP パッケージの型か確認
+	assertedType := rt.typeInfo.TypeOf(assert.Type)
+	if assertedType == nil {
+		return
+	}
+	if ptr, ok := assertedType.(*types.Pointer); ok {
+		assertedType = ptr.Elem()
+	}
+	named, ok := assertedType.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return
+	}
+	isGCP, serviceName := rt.GetPackageInfo(named.Obj().Pkg().Path())
+	if !isGCP {
+		return
+	}
+
+	varName := rt.extractVariableNameFromAssignment(assignStmt, rhsIndex)
+	variable := rt.extractVariableFromAssignment(assignStmt, rhsIndex)
+	if varName == "" || variable == nil {
+		return
+	}
+
+	rt.variables[variable] = &ResourceInfo{
+		Variable:         variable,
+		VariableName:     varName,
+		CreationPos:      assert.Pos(),
+		ServiceType:      serviceName,
+		CreationFunction: PoolGetMethod,
+		CleanupMethod:    rt.ruleEngine.PoolReturnMethod(serviceName),
+		IsRequired:       true,
+		PoolExpr:         types.ExprString(sel.X),
+		Scope:            variable.Parent(),
+	}
+}
+
+// trackWrappedCreationCall は生成呼び出しをラップして同じ型を返す呼び出しを追跡する。
+// must(inner) は内側の生成呼び出しとして、mustXxx(...) とパッケージ内のコンストラクタ（open() 等）は
+// 最初の戻り値の型を返す生成関数として扱う。(T, error) を返す場合もエラーの受け方によらず追跡する
+func (rt *ResourceTracker) trackWrappedCreationCall(assignStmt *ast.AssignStmt, rhsIndex int, call *ast.CallExpr, pass *analysis.Pass) {
+	if rt.typeInfo == nil {
+		return
+	}
+	resultType := firstResultType(rt.typeInfo.TypeOf(call))
+	if resultType == nil {
+		return
+	}
+	varName := rt.extractVariableNameFromAssignment(assignStmt, rhsIndex)
+	variable := rt.extractVariableFromAssignment(assignStmt, rhsIndex)
+	if varName == "" {
+		return
+	}
+
+	// must(spanner.NewClient(ctx, db)): 引数の生成呼び出しと同じ型を返すラッパー
+	if len(call.Args) == 1 {
+		if inner, ok := call.Args[0].(*ast.CallExpr); ok && rt.isResourceCreationCall(inner) {
+			if types.Identical(firstResultType(rt.typeInfo.TypeOf(inner)), resultType) {
+				rt.trackCallWithVariable(inner, varName, variable, pass)
+			}
+			return
+		}
+	}
+
+	// mustClient(ctx): 名前が must で始まり、GCP リソース型を返すヘルパー
+	// open(ctx): 本体で同じ型のリソースを生成して返すパッケージ内のコンストラクタ
+	// db.Open(ctx): ReturnsResourceFact が付与された依存パッケージのコンストラクタ
+	name := calleeName(call)
+	if !strings.HasPrefix(name, "must") && !strings.HasPrefix(name, "Must") &&
+		!rt.isPackageConstructor(call, resultType, pass) {
+		return
+	}
+	packagePath := namedTypePackagePath(resultType)
+	isGCP, serviceName := rt.GetPackageInfo(packagePath)
+	if !isGCP {
+		return
+	}
+	serviceRule := rt.ruleEngine.GetServiceRule(serviceName)
+	if serviceRule == nil {
+		return
+	}
+	creationFunc := rt.creationFunctionReturning(packagePath, serviceRule, resultType)
+	if creationFunc == "" || variable == nil {
+		return
+	}
+
+	resourceInfo := rt.newResourceInfo(call, creationFunc, serviceName, serviceRule)
+	if resourceInfo == nil {
+		return
+	}
+	resourceInfo.VariableName = varName
+	resourceInfo.Variable = variable
+	resourceInfo.Scope = variable.Parent()
+	rt.recordVariable(variable, resourceInfo, pass)
+}
+
+// recordVariable は変数に代入したリソースを記録する。
+// var c *spanner.Client; if useA { c = newA() } else { c = newB() } のように if/else の別の分岐で
+// 同じ変数に代入した場合は、最初の分岐の生成を1つのリソースとして残し、他の分岐の生成位置を BranchCreationPos に加える
+// （分岐の後で一度解放すればよく、どの分岐を通っても解放される必要がある）
+func (rt *ResourceTracker) recordVariable(variable *types.Var, resourceInfo *ResourceInfo, pass *analysis.Pass) {
+	if existing, ok := rt.variables[variable]; ok && pass != nil &&
+		existing.ServiceType == resourceInfo.ServiceType && existing.CleanupMethod == resourceInfo.CleanupMethod &&
+		inExclusiveBranches(pass.Files, existing.CreationPos, resourceInfo.CreationPos) {
+		existing.BranchCreationPos = append(existing.BranchCreationPos, resourceInfo.CreationPos)
+		return
+	}
+	rt.variables[variable] = resourceInfo
+}
+
+// inExclusiveBranches は a と b が同じ if 文の then 節と else 節に分かれている（同時に実行されない）かを判定する
+func inExclusiveBranches(files []*ast.File, a, b token.Pos) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return false
+	}
+	var innermost *ast.IfStmt
+	for _, file := range files {
+		if a < file.Pos() || a >= file.End() {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil || a < n.Pos() || a >= n.End() || b < n.Pos() || b >= n.End() {
+				return false
+			}
+			if ifStmt, ok := n.(*ast.IfStmt); ok {
+				innermost = ifStmt
+			}
+			return true
+		})
+	}
+	if innermost == nil || innermost.Else == nil {
+		return false
+	}
+	inBody := func(pos token.Pos) bool { return pos >= innermost.Body.Pos() && pos < innermost.Body.End() }
+	inElse := func(pos token.Pos) bool { return pos >= innermost.Else.Pos() && pos < innermost.Else.End() }
+	return (inBody(a) && inElse(b)) || (inElse(a) && inBody(b))
+}
+
+// isPackageConstructor は call が解析中のパッケージで宣言された関数・メソッド、または即時実行する関数リテラルの呼び出しで、
+// その本体で resultType のリソースを生成している（生成して呼び出し元に返すコンストラクタである）かを判定する。
+// フィールドに保持したクライアントを返すだけのゲッターは対象外になる。
+// 依存パッケージの関数は ReturnsResourceFact が付与されているかで判定する
+func (rt *ResourceTracker) isPackageConstructor(call *ast.CallExpr, resultType types.Type, pass *analysis.Pass) bool {
+	// client := func() *spanner.Client { ... }()
+	if funcLit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		return rt.createsResourceOfType(funcLit.Body, resultType)
+	}
+	if pass == nil || pass.Pkg == nil {
+		return false
+	}
+	fn := rt.calledFunc(call)
+	if fn == nil {
+		return false
+	}
+	if fn.Pkg() != pass.Pkg {
+		return rt.isImportedConstructor(call)
+	}
+
+	if isConstructor, ok := rt.constructors[fn]; ok {
+		return isConstructor
+	}
+	if rt.constructors == nil {
+		rt.constructors = make(map[*types.Func]bool)
+	}
+	isConstructor := false
+	if decl := findFuncDecl(pass, rt.typeInfo, fn); decl != nil {
+		isConstructor = rt.createsResourceOfType(decl.Body, resultType)
+	}
+	rt.constructors[fn] = isConstructor
+	return isConstructor
+}
+
+// createsResourceOfType は関数本体で resultType のリソースを生成しているかを判定する
+func (rt *ResourceTracker) createsResourceOfType(body *ast.BlockStmt, resultType types.Type) bool {
+	if body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if inner, ok := n.(*ast.CallExpr); ok && (rt.isResourceCreationCall(inner) || rt.isImportedConstructor(inner)) &&
+			types.Identical(firstResultType(rt.typeInfo.TypeOf(inner)), resultType) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// calledFunc は呼び出し先の関数・メソッド（ジェネリクスの場合はインスタンス化前のもの）を返す
+func (rt *ResourceTracker) calledFunc(call *ast.CallExpr) *types.Func {
+	if rt.typeInfo == nil {
+		return nil
+	}
+	var ident *ast.Ident
+	switch fun := uninstantiatedFunc(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+	fn, ok := rt.typeInfo.Uses[ident].(*types.Func)
+	if !ok {
+		return nil
+	}
+	return fn.Origin()
+}
+
+// isImportedConstructor は呼び出し先に依存パッケージの解析で ReturnsResourceFact が付与されているかを判定する
+func (rt *ResourceTracker) isImportedConstructor(call *ast.CallExpr) bool {
+	if rt.importFact == nil {
+		return false
+	}
+	fn := rt.calledFunc(call)
+	if fn == nil {
+		return false
+	}
+	return rt.importFact(fn, new(ReturnsResourceFact))
+}
+
+// uninstantiatedFunc は New[T](...) や New[T, U](...) のような型引数付きの呼び出し先から型引数を取り除く
+func uninstantiatedFunc(fun ast.Expr) ast.Expr {
+	switch expr := ast.Unparen(fun).(type) {
+	case *ast.IndexExpr:
+		return ast.Unparen(expr.X)
+	case *ast.IndexListExpr:
+		return ast.Unparen(expr.X)
+	default:
+		return expr
+	}
+}
+
+// findFuncDecl は fn の宣言をパッケージのファイルから探す
+func findFuncDecl(pass *analysis.Pass, typeInfo *types.Info, fn *types.Func) *ast.FuncDecl {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && typeInfo.Defs[funcDecl.Name] == fn {
+				return funcDecl
+			}
+		}
+	}
+	return nil
+}
+
+// CleanupMethodForType は型がサービスルールの生成関数の戻り値型であれば、そのサービス名と必須の解放メソッドを返す
+func (rt *ResourceTracker) CleanupMethodForType(typ types.Type) (string, string, bool) {
+	pkg := packageOfNamedType(typ)
+	if pkg == nil || rt.ruleEngine == nil {
+		return "", "", false
+	}
+	known, serviceName := rt.GetPackageInfo(pkg.Path())
+	if !known {
+		return "", "", false
+	}
+	serviceRule := rt.ruleEngine.GetServiceRule(serviceName)
+	if serviceRule == nil {
+		return "", "", false
+	}
+	funcName := rt.creationFunctionReturning(pkg.Path(), serviceRule, typ)
+	if funcName == "" {
+		return "", "", false
+	}
+	cleanupMethod, required := cleanupMethodFor(funcName, serviceRule)
+	return serviceName, cleanupMethod, required && cleanupMethod != ""
+}
+
+// creationFunctionReturning はパッケージの生成関数のうち、最初の戻り値が typ のものの名前を返す
+func (rt *ResourceTracker) creationFunctionReturning(packagePath string, serviceRule *ServiceRule, typ types.Type) string {
+	pkg := packageOfNamedType(typ)
+	if pkg == nil || pkg.Path() != packagePath {
+		return ""
+	}
+	for _, funcName := range serviceRule.CreationFuncs {
+		fn, ok := pkg.Scope().Lookup(funcName).(*types.Func)
+		if !ok {
+			continue
+		}
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Results().Len() > 0 &&
+			types.Identical(sig.Results().At(0).Type(), typ) {
+			return funcName
+		}
+	}
+	return ""
+}
+
+// firstResultType は複数戻り値の場合に最初の戻り値の型を返す
+func firstResultType(typ types.Type) types.Type {
+	if tuple, ok := typ.(*types.Tuple); ok {
+		if tuple.Len() == 0 {
+			return nil
+		}
+		return tuple.At(0).Type()
+	}
+	return typ
+}
+
+// packageOfNamedType は（ポインタを外した）名前付き型の定義パッケージを返す
+func packageOfNamedType(typ types.Type) *types.Package {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Pkg()
+	}
+	return nil
+}
+
+// isSyncPool は式の型が sync.Pool（またはそのポインタ）か、sync.Pool を埋め込んだ構造体かを判定する。
+// 埋め込んだ型では独自の返却メソッド（pool_return_method）を定義できる
+func (rt *ResourceTracker) isSyncPool(expr ast.Expr) bool {
+	typ := rt.typeInfo.TypeOf(expr)
+	if typ == nil {
+		return false
+	}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if isSyncPoolType(typ) {
+		return true
+	}
+	if structType, ok := typ.Underlying().(*types.Struct); ok {
+		for i := 0; i < structType.NumFields(); i++ {
+			if field := structType.Field(i); field.Embedded() && isSyncPoolType(field.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSyncPoolType は型が sync.Pool かどうかを判定する
+func isSyncPoolType(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == "sync" && named.Obj().Name() == "Pool"
+}
+
 // shouldTrackMultipleReturnValues は複数戻り値の関数かどうかを判定
 func (rt *ResourceTracker) shouldTrackMultipleReturnValues(call *ast.CallExpr) bool {
 	// ReadWriteTransaction系のみ特別扱い（time.Time, errorを返す）
@@ -665,6 +1238,12 @@ func (rt *ResourceTracker) isResourceCreationCall(call *ast.CallExpr) bool {
 
 // trackCallWithVariableName は実際の変数名でリソース呼び出しを追跡する
 func (rt *ResourceTracker) trackCallWithVariableName(call *ast.CallExpr, varName string, pass *analysis.Pass) {
+	rt.trackCallWithVariable(call, varName, nil, pass)
+}
+
+// trackCallWithVariable は代入先の変数（判明している場合）でリソース呼び出しを追跡する
+// variable が nil の場合は変数名から型情報を検索する
+func (rt *ResourceTracker) trackCallWithVariable(call *ast.CallExpr, varName string, variable *types.Var, pass *analysis.Pass) {
 	funcIdent := rt.extractFunctionIdent(call)
 	if funcIdent == nil {
 		return
@@ -687,6 +1266,14 @@ func (rt *ResourceTracker) trackCallWithVariableName(call *ast.CallExpr, varName
 		// 実際の変数名を設定
 		resourceInfo.VariableName = varName
 
+		// 代入先の変数が判明している場合はスコープごと記録する
+		if variable != nil {
+			resourceInfo.Variable = variable
+			resourceInfo.Scope = variable.Parent()
+			rt.recordVariable(variable, resourceInfo, pass)
+			return
+		}
+
 		// 型情報から変数を検索
 		if rt.typeInfo != nil && pass.Pkg != nil {
 			for ident, obj := range rt.typeInfo.Defs {
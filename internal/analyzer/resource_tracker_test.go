@@ -409,6 +409,51 @@ func TestResourceTracker_GetPackageInfo(t *testing.T) {
 	}
 }
 
+func TestResourceTracker_GetPackageInfoAliases(t *testing.T) {
+	content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions: [NewClient]
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+package_path_aliases:
+  example.com/internal/vendored/spanner: spanner
+  example.com/internal/vendored: spanner
+`
+	path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	ruleEngine := NewServiceRuleEngine()
+	if err := ruleEngine.LoadRules(path); err != nil {
+		t.Fatalf("ルールエンジンの初期化に失敗: %v", err)
+	}
+	tracker := NewResourceTracker(&types.Info{}, ruleEngine)
+
+	tests := []struct {
+		packagePath string
+		wantIsGCP   bool
+		wantService string
+	}{
+		{"example.com/internal/vendored/spanner", true, "spanner"},
+		{"example.com/internal/vendored/spanner/admin", true, "spanner"},
+		{"example.com/internal/vendoredother", false, ""},
+		{"cloud.google.com/go/storage", true, "storage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.packagePath, func(t *testing.T) {
+			isGCP, service := tracker.GetPackageInfo(tt.packagePath)
+			if isGCP != tt.wantIsGCP || service != tt.wantService {
+				t.Errorf("GetPackageInfo(%q) = (%v, %q), want (%v, %q)", tt.packagePath, isGCP, service, tt.wantIsGCP, tt.wantService)
+			}
+		})
+	}
+}
+
 // ゴールデンテスト: testdataを使用した統合テスト
 func TestResourceTracker_GoldenTest(t *testing.T) {
 	tests := []struct {
@@ -1016,6 +1061,105 @@ func TestResourceTracker_FilterAutoManagedResources(t *testing.T) {
 	}
 }
 
+func TestResourceTracker_PubSubTopicHandles(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "TopicInProject without Stop",
+			body: `topic := client.TopicInProject("orders", "other-project")
+	topic.Publish(ctx, &pubsub.Message{Data: []byte("hello")})`,
+			expectedCount: 1,
+		},
+		{
+			name: "TopicInProject with Stop",
+			body: `topic := client.TopicInProject("orders", "other-project")
+	defer topic.Stop()
+	topic.Publish(ctx, &pubsub.Message{Data: []byte("hello")})`,
+			expectedCount: 0,
+		},
+		{
+			name: "Topic without Stop",
+			body: `topic := client.Topic("orders")
+	topic.Publish(ctx, &pubsub.Message{Data: []byte("hello")})`,
+			expectedCount: 1,
+		},
+		{
+			name: "Topic never published",
+			body: `topic := client.Topic("orders")
+	exists, _ := topic.Exists(ctx)
+	_ = exists`,
+			expectedCount: 0,
+		},
+		{
+			name: "TopicInProject never published",
+			body: `topic := client.TopicInProject("orders", "other-project")
+	_ = topic.ID`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package publisher
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func publish(ctx context.Context, client *pubsub.Client) {
+	` + tt.body + `
+}
+`
+			diagnostics := diagnosticsContaining(runAnalyzerOnSource(t, "publisher.go", src), "Stop")
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Stop の診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_ErrorSignificance(t *testing.T) {
+	tracker, ruleEngine, _ := setupResourceTrackerTest(t)
+	storageRule := ruleEngine.GetServiceRule("storage")
+	if storageRule == nil {
+		t.Fatal("storage ルールが見つかりません")
+	}
+
+	tests := []struct {
+		funcName        string
+		wantMethod      string
+		wantSignificant bool
+	}{
+		{"NewWriter", "Close", true},
+		{"NewReader", "Close", false},
+		{"NewClient", "Close", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.funcName, func(t *testing.T) {
+			call := &ast.CallExpr{
+				Fun: &ast.SelectorExpr{X: ast.NewIdent("obj"), Sel: ast.NewIdent(tt.funcName)},
+			}
+
+			info := tracker.createResourceInfo(call, "storage", storageRule)
+			if info == nil {
+				t.Fatal("ResourceInfo が作成されませんでした")
+			}
+			if info.CleanupMethod != tt.wantMethod {
+				t.Errorf("CleanupMethod = %s, want %s", info.CleanupMethod, tt.wantMethod)
+			}
+			if info.ErrorSignificant != tt.wantSignificant {
+				t.Errorf("ErrorSignificant = %v, want %v", info.ErrorSignificant, tt.wantSignificant)
+			}
+		})
+	}
+}
+
 // setupResourceTrackerTest はテスト用のResourceTrackerをセットアップする
 func setupResourceTrackerTest(t *testing.T) (*ResourceTracker, *ServiceRuleEngine, *types.Info) {
 	ruleEngine := NewServiceRuleEngine()
@@ -1033,3 +1177,440 @@ func setupResourceTrackerTest(t *testing.T) (*ResourceTracker, *ServiceRuleEngin
 	tracker := NewResourceTracker(typeInfo, ruleEngine)
 	return tracker, ruleEngine, typeInfo
 }
+
+func TestResourceTracker_SyncPoolBorrowedResource(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "Pool get with deferred put",
+			body: `client := pool.Get().(*spanner.Client)
+	defer pool.Put(client)`,
+			expectedCount: 0,
+		},
+		{
+			name: "Pool get with put in deferred closure",
+			body: `client := pool.Get().(*spanner.Client)
+	defer func() { pool.Put(client) }()`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Pool get without put",
+			body:          `client := pool.Get().(*spanner.Client)`,
+			expectedCount: 1,
+		},
+		{
+			name: "Pool get closed instead of returned",
+			body: `client := pool.Get().(*spanner.Client)
+	defer client.Close()`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+func borrow(pool *sync.Pool) {
+	` + tt.body + `
+	_ = client
+}
+`
+			diagnostics := diagnosticsContaining(runAnalyzerOnSource(t, "test.go", src), "Put")
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("Put の診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_SyncPoolReturnMethod(t *testing.T) {
+	content := `
+services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+    pool_return_method: Release
+`
+	configPath := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		configPath    string
+		deferStmt     string
+		expectedCount int
+	}{
+		{
+			name:          "Configured return method releases the resource",
+			configPath:    configPath,
+			deferStmt:     "defer pool.Release(client)",
+			expectedCount: 0,
+		},
+		{
+			name:          "Configured return method is missing",
+			configPath:    configPath,
+			expectedCount: 1,
+		},
+		{
+			name:          "Put does not replace the configured return method",
+			configPath:    configPath,
+			deferStmt:     "defer pool.Put(client)",
+			expectedCount: 1,
+		},
+		{
+			name:          "Put on a pool embedding sync.Pool by default",
+			deferStmt:     "defer pool.Put(client)",
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+type clientPool struct {
+	sync.Pool
+}
+
+func (p *clientPool) Release(c *spanner.Client) {
+	p.Put(c)
+}
+
+func borrow(pool *clientPool) {
+	client := pool.Get().(*spanner.Client)
+	` + tt.deferStmt + `
+	_ = client
+}
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ConfigPath: tt.configPath})
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_DotImport(t *testing.T) {
+	tests := []struct {
+		name          string
+		deferStmt     string
+		expectedCount int
+	}{
+		{
+			name:          "Dot-imported spanner client without Close",
+			deferStmt:     "",
+			expectedCount: 1,
+		},
+		{
+			name:          "Dot-imported spanner client with Close",
+			deferStmt:     "defer client.Close()",
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	. "cloud.google.com/go/spanner"
+)
+
+func run(ctx context.Context) {
+	client, err := NewClient(ctx, "db")
+	if err != nil {
+		return
+	}
+	` + tt.deferStmt + `
+	_ = client
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_GoogleAPIClientNotTracked(t *testing.T) {
+	src := `package test
+
+import (
+	"context"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+func run(ctx context.Context) error {
+	svc, err := compute.NewService(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = svc.Instances.List("project", "zone").Do()
+	return err
+}
+`
+
+	t.Run("default rules", func(t *testing.T) {
+		diagnostics := runAnalyzerOnSource(t, "test.go", src)
+		if len(diagnostics) != 0 {
+			t.Errorf("google.golang.org/api クライアントは既定では追跡しない: %v", diagnostics)
+		}
+	})
+
+	t.Run("user-defined rule", func(t *testing.T) {
+		content := `
+services:
+  - service_name: compute
+    package_path: google.golang.org/api/compute/v1
+    creation_functions:
+      - NewService
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close the underlying transport
+`
+		path := filepath.Join(t.TempDir(), "gcpclosecheck.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{ConfigPath: path})
+		if len(diagnostics) != 1 {
+			t.Errorf("設定ファイルでルールを追加した場合は報告する: 診断数 = %d, want 1: %v", len(diagnostics), diagnostics)
+		}
+	})
+}
+
+func TestResourceTracker_MustWrappedCreation(t *testing.T) {
+	tests := []struct {
+		name          string
+		create        string
+		deferStmt     string
+		expectedCount int
+	}{
+		{
+			name:          "Generic must wrapper without Close",
+			create:        `must(spanner.NewClient(ctx, "db"))`,
+			expectedCount: 1,
+		},
+		{
+			name:          "Generic must wrapper with Close",
+			create:        `must(spanner.NewClient(ctx, "db"))`,
+			deferStmt:     "defer client.Close()",
+			expectedCount: 0,
+		},
+		{
+			name:          "Must-prefixed helper without Close",
+			create:        "mustClient(ctx)",
+			expectedCount: 1,
+		},
+		{
+			name:          "Must-prefixed helper with Close",
+			create:        "mustClient(ctx)",
+			deferStmt:     "defer client.Close()",
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func mustClient(ctx context.Context) *spanner.Client {
+	return must(spanner.NewClient(ctx, "db"))
+}
+
+func run(ctx context.Context) {
+	client := ` + tt.create + `
+	` + tt.deferStmt + `
+	_ = client
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_PackageConstructor(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name: "Error ignored without Close",
+			body: `c, _ := open(ctx)
+	_ = c`,
+			expectedCount: 1,
+		},
+		{
+			name: "Error ignored with Close",
+			body: `c, _ := open(ctx)
+	defer c.Close()`,
+			expectedCount: 0,
+		},
+		{
+			name: "Error checked without Close",
+			body: `c, err := open(ctx)
+	if err != nil {
+		return
+	}
+	_ = c`,
+			expectedCount: 1,
+		},
+		{
+			name: "Getter returning a stored client",
+			body: `c := (&holder{}).Client()
+	_ = c`,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func open(ctx context.Context) (*storage.Client, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+type holder struct {
+	client *storage.Client
+}
+
+func (h *holder) Client() *storage.Client { return h.client }
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceTracker_GenericFunctions(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedCount int
+	}{
+		{
+			name:          "Leak inside a generic function",
+			body:          `leak[int](ctx, 1)`,
+			expectedCount: 1,
+		},
+		{
+			name: "Leak from a generic constructor",
+			body: `c, _ := open[int](ctx)
+	_ = c`,
+			expectedCount: 2,
+		},
+		{
+			name: "Leak from a constructor with multiple type parameters",
+			body: `c, _ := openPair[int, string](ctx)
+	_ = c`,
+			expectedCount: 2,
+		},
+		{
+			name: "Generic constructor closed",
+			body: `c, _ := open[int](ctx)
+	defer c.Close()`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+func leak[T any](ctx context.Context, v T) {
+	client, _ := storage.NewClient(ctx)
+	_, _ = client, v
+}
+
+func open[T any](ctx context.Context) (*storage.Client, error) {
+	return storage.NewClient(ctx)
+}
+
+func openPair[T any, U comparable](ctx context.Context) (*storage.Client, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func run(ctx context.Context) {
+	` + tt.body + `
+}
+`
+			// 件数には leak 本体のリーク（1件）が常に含まれる
+			diagnostics := runAnalyzerOnSource(t, "test.go", src)
+			if len(diagnostics) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(diagnostics), tt.expectedCount, diagnostics)
+			}
+		})
+	}
+}
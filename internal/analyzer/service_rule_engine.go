@@ -27,12 +27,16 @@ func (sre *ServiceRuleEngine) LoadDefaultRules() error {
 
 // LoadRules は設定ファイルからルールを読み込む
 // configPathが空またはファイルが存在しない場合はデフォルト設定を使用
+// configPathが HTTP(S) の URL の場合はリモートから取得する
 func (sre *ServiceRuleEngine) LoadRules(configPath string) error {
 	var err error
 
 	if configPath == "" {
 		// デフォルト設定を読み込み
 		sre.config, err = config.LoadDefaultConfig()
+	} else if config.IsRemoteConfig(configPath) {
+		// 集中管理されたリモート設定はデフォルトにフォールバックしない（取得できなければエラー）
+		sre.config, err = config.LoadRemoteConfig(configPath)
 	} else {
 		// カスタム設定を読み込み、失敗時はデフォルトにフォールバック
 		sre.config, err = config.LoadConfig(configPath)
@@ -99,15 +103,31 @@ func (sre *ServiceRuleEngine) GetServiceRule(serviceName string) *ServiceRule {
 
 	for i, cm := range configService.CleanupMethods {
 		rule.CleanupMethods[i] = CleanupMethod{
-			Method:      cm.Method,
-			Required:    cm.Required,
-			Description: cm.Description,
+			Method:           cm.Method,
+			Required:         cm.Required,
+			Description:      cm.Description,
+			ErrorSignificant: cm.ErrorSignificant,
+			RequiresContext:  cm.RequiresContext,
+			AppliesTo:        cm.AppliesTo,
+			RequiredAfter:    cm.RequiredAfter,
 		}
 	}
 
 	return rule
 }
 
+// ServiceNames は設定されているサービス名の一覧を返す
+func (sre *ServiceRuleEngine) ServiceNames() []string {
+	if sre.config == nil {
+		return nil
+	}
+	names := make([]string, 0, len(sre.config.Services))
+	for _, service := range sre.config.Services {
+		names = append(names, service.ServiceName)
+	}
+	return names
+}
+
 // findCleanupMethodFromConfig は設定からサービスタイプに対応する解放メソッドを検索する
 func (sre *ServiceRuleEngine) findCleanupMethodFromConfig(serviceType string) (string, bool) {
 	if sre.config == nil {
@@ -151,6 +171,115 @@ func extractServiceName(serviceType string) string {
 	return serviceType
 }
 
+// InitAnalysisMode は init 関数内のリソース生成の扱い（warn|skip|error）を返す
+func (sre *ServiceRuleEngine) InitAnalysisMode() string {
+	if sre.config == nil {
+		return config.AnalyzeInitWarn
+	}
+	return sre.config.InitAnalysisMode()
+}
+
+// GeneratedFilePolicy は生成コード内のリソースの扱い（skip|warn、未設定の場合は空文字）を返す
+func (sre *ServiceRuleEngine) GeneratedFilePolicy() string {
+	if sre.config == nil {
+		return ""
+	}
+	return sre.config.GeneratedFilePolicy
+}
+
+// IsPurePackage はパッケージが pure_packages に該当し、生成したリソースを報告しないかを返す
+func (sre *ServiceRuleEngine) IsPurePackage(packagePath string) bool {
+	if sre.config == nil {
+		return false
+	}
+	return sre.config.IsPurePackage(packagePath)
+}
+
+// FieldAssignmentCheckedServices は field_assignment_exempt: false により、
+// 構造体フィールドへの代入を逃げパスとみなさないサービス名を返す
+func (sre *ServiceRuleEngine) FieldAssignmentCheckedServices() []string {
+	if sre.config == nil {
+		return nil
+	}
+	var names []string
+	for i := range sre.config.Services {
+		if !sre.config.Services[i].IsFieldAssignmentExempt() {
+			names = append(names, sre.config.Services[i].ServiceName)
+		}
+	}
+	return names
+}
+
+// AppLifetimeServices は lifetime: app により、パッケージ変数への代入も逃げパスとみなすサービス名を返す
+func (sre *ServiceRuleEngine) AppLifetimeServices() []string {
+	if sre.config == nil {
+		return nil
+	}
+	var names []string
+	for i := range sre.config.Services {
+		if sre.config.Services[i].IsAppLifetime() {
+			names = append(names, sre.config.Services[i].ServiceName)
+		}
+	}
+	return names
+}
+
+// AppliesToTestFiles は指定したサービスのリソースをテストファイル内でも検査するかを返す
+// （applies_to_test_files: false の場合のみ false）
+func (sre *ServiceRuleEngine) AppliesToTestFiles(serviceName string) bool {
+	if sre.config == nil {
+		return true
+	}
+	service := sre.config.GetService(serviceName)
+	return service == nil || service.IsAppliedToTestFiles()
+}
+
+// IsAdminService は指定したサービスが管理 API のクライアント（service_type: admin）を生成するかを返す
+func (sre *ServiceRuleEngine) IsAdminService(serviceName string) bool {
+	if sre.config == nil {
+		return false
+	}
+	service := sre.config.GetService(serviceName)
+	return service != nil && service.IsAdmin()
+}
+
+// IsContextCancelledCleanup は指定したサービスのリソースが生成時の context のキャンセルで解放されるか
+// （context_cancelled_cleanup: true）を返す
+func (sre *ServiceRuleEngine) IsContextCancelledCleanup(serviceName string) bool {
+	if sre.config == nil {
+		return false
+	}
+	service := sre.config.GetService(serviceName)
+	return service != nil && service.ContextCancelledCleanup
+}
+
+// PoolReturnMethod は指定したサービスのリソースを sync.Pool に返却するメソッド名
+// （pool_return_method、未設定の場合は Put）を返す
+func (sre *ServiceRuleEngine) PoolReturnMethod(serviceName string) string {
+	if sre != nil && sre.config != nil {
+		if service := sre.config.GetService(serviceName); service != nil && service.PoolReturnMethod != "" {
+			return service.PoolReturnMethod
+		}
+	}
+	return PoolReturnMethod
+}
+
+// IsDeferRegistrationMethod は解放関数を登録するメソッド（stack.Defer 等）として設定されているかを判定する
+func (sre *ServiceRuleEngine) IsDeferRegistrationMethod(method string) bool {
+	if sre.config == nil {
+		return false
+	}
+	return sre.config.IsDeferRegistrationMethod(method)
+}
+
+// ClosureManagedHelpers はクロージャ引数のリソースを自動管理するヘルパー関数の設定を返す
+func (sre *ServiceRuleEngine) ClosureManagedHelpers() []config.ClosureManagedHelper {
+	if sre.config == nil {
+		return nil
+	}
+	return sre.config.ClosureManagedHelpers
+}
+
 // ShouldExemptPackage は指定されたパッケージパスが例外対象かを判定する
 func (sre *ServiceRuleEngine) ShouldExemptPackage(packagePath string) (bool, string) {
 	if sre.config == nil {
@@ -160,6 +289,14 @@ func (sre *ServiceRuleEngine) ShouldExemptPackage(packagePath string) (bool, str
 	return sre.config.ShouldExemptPackage(packagePath)
 }
 
+// ExemptionTypeForPackage はパッケージに適用されるパッケージ例外の条件タイプを返す（該当しない場合は空文字列）
+func (sre *ServiceRuleEngine) ExemptionTypeForPackage(packagePath string) string {
+	if sre.config == nil {
+		return ""
+	}
+	return sre.config.ExemptionTypeForPackage(packagePath)
+}
+
 // LoadPackageExceptions はパッケージ例外設定を読み込む
 // 設定がない場合、またはパッケージ例外が定義されていない場合でもエラーにならない
 func (sre *ServiceRuleEngine) LoadPackageExceptions(configPath string) error {
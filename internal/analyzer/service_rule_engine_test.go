@@ -252,6 +252,40 @@ func TestServiceRuleEngine_GetServiceRule(t *testing.T) {
 	}
 }
 
+func TestServiceRuleEngine_DefaultErrorSignificance(t *testing.T) {
+	engine := NewServiceRuleEngine()
+	if err := engine.LoadRules(""); err != nil {
+		t.Fatalf("デフォルト設定読み込み失敗: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		serviceName     string
+		creationFunc    string
+		method          string
+		wantSignificant bool
+	}{
+		{"storage Writer Close", "storage", "NewWriter", "Close", true},
+		{"storage Reader Close", "storage", "NewReader", "Close", false},
+		{"storage Client Close", "storage", "NewClient", "Close", false},
+		{"spanner Client Close", "spanner", "NewClient", "Close", false},
+		{"pubsub Client Close", "pubsub", "NewClient", "Close", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := engine.GetServiceRule(tt.serviceName)
+			if rule == nil {
+				t.Fatalf("サービス %s が見つかりません", tt.serviceName)
+			}
+
+			if got := rule.IsErrorSignificant(tt.creationFunc, tt.method); got != tt.wantSignificant {
+				t.Errorf("IsErrorSignificant(%s, %s) = %v, want %v", tt.creationFunc, tt.method, got, tt.wantSignificant)
+			}
+		})
+	}
+}
+
 func TestServiceRuleEngine_ShouldExemptPackage(t *testing.T) {
 	engine := NewServiceRuleEngine()
 
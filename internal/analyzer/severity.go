@@ -0,0 +1,84 @@
+package analyzer
+
+// 診断カテゴリ（analysis.Diagnostic.Category に設定するルールID）
+const (
+	CategoryResourceLeak            = "resource-leak"              // リソースの解放漏れ
+	CategoryContextLeak             = "context-leak"               // context のキャンセル漏れ
+	CategoryInitResourceLeak        = "init-resource-leak"         // init 関数内のリソース解放漏れ（analyze_init: warn）
+	CategoryGeneratedLeak           = "generated-code-leak"        // 生成コード内のリソース解放漏れ（generated_file_policy: warn）
+	CategoryAnalysisTimeout         = "analysis-timeout"           // -gcptimeout 超過による解析の打ち切り
+	CategoryDuplicateCancel         = "duplicate-cancel"           // 同じキャンセル関数の重複 defer（-gcpduplicate-cancel）
+	CategoryContextNotPropagated    = "context-not-propagated"     // 派生 context でなく親 context を下流に渡している（-gcpcontext-propagation）
+	CategoryAfterFuncNotStopped     = "afterfunc-not-stopped"      // context.AfterFunc の stop 関数を破棄・未使用（-gcpafterfunc-stop）
+	CategoryFinalizerCleanup        = "finalizer-cleanup"          // runtime.SetFinalizer 任せの解放（-gcpfinalizer）
+	CategoryCleanupOrder            = "cleanup-order"              // 依存先が先に解放される defer の順序（-gcpcleanup-order）
+	CategoryMessageNotAcked         = "message-not-acked"          // Pub/Sub メッセージを Ack/Nack せずに抜ける経路（-gcppubsub-ack）
+	CategoryDeferInLoop             = "defer-in-loop"              // ループ内の defer により関数終了まで解放されない（-gcpdefer-in-loop）
+	CategoryExitSkipsDefer          = "exit-skips-defer"           // os.Exit/log.Fatal により defer の解放処理が実行されない（-gcpexit-skips-defer）
+	CategoryCreationErrorIgnored    = "creation-error-ignored"     // 生成時のエラーを確認せずにリソースを使用している（-gcpcreation-error）
+	CategoryUnknownGCPPackage       = "unknown-gcp-package"        // サービスルールのない cloud.google.com/go パッケージの import（-gcpwarn-unknown）
+	CategoryConditionalCleanup      = "conditional-cleanup"        // 真偽値の条件下でのみ登録される解放の defer（-gcpconditional-defer）
+	CategoryStructResourceNotClosed = "struct-resource-not-closed" // リソースを保持するフィールドを解放するメソッドがない構造体（-gcpstruct-lifecycle）
+	CategoryResourceSliceNotClosed  = "resource-slice-not-closed"  // 生成したリソースのスライスの要素を解放する処理がない
+	CategoryPanicSkipsCleanup       = "panic-skips-cleanup"        // recover する関数で、直接呼び出しの解放が panic により飛ばされ得る
+	CategoryReassignedElement       = "reassigned-element-cleanup" // 要素経由の解放の defer より前に、リソースを格納した map/スライスの要素が再代入される
+	CategoryCleanupErrorIgnored     = "cleanup-error-ignored"      // error_significant の解放メソッドのエラーを defer で捨てている（-gcpcleanup-error）
+)
+
+// 診断の重大度
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// categorySeverities はカテゴリごとの重大度
+var categorySeverities = map[string]string{
+	CategoryResourceLeak:            SeverityError,
+	CategoryContextLeak:             SeverityError,
+	CategoryInitResourceLeak:        SeverityWarning,
+	CategoryGeneratedLeak:           SeverityWarning,
+	CategoryAnalysisTimeout:         SeverityWarning,
+	CategoryDuplicateCancel:         SeverityInfo,
+	CategoryContextNotPropagated:    SeverityWarning,
+	CategoryAfterFuncNotStopped:     SeverityWarning,
+	CategoryFinalizerCleanup:        SeverityWarning,
+	CategoryCleanupOrder:            SeverityWarning,
+	CategoryMessageNotAcked:         SeverityWarning,
+	CategoryDeferInLoop:             SeverityWarning,
+	CategoryExitSkipsDefer:          SeverityWarning,
+	CategoryCreationErrorIgnored:    SeverityWarning,
+	CategoryUnknownGCPPackage:       SeverityInfo,
+	CategoryConditionalCleanup:      SeverityWarning,
+	CategoryStructResourceNotClosed: SeverityWarning,
+	CategoryResourceSliceNotClosed:  SeverityWarning,
+	CategoryPanicSkipsCleanup:       SeverityError,
+	CategoryReassignedElement:       SeverityWarning,
+	CategoryCleanupErrorIgnored:     SeverityWarning,
+}
+
+// severityRanks は重大度の順位（大きいほど重い）
+var severityRanks = map[string]int{
+	SeverityInfo:    0,
+	SeverityWarning: 1,
+	SeverityError:   2,
+}
+
+// IsValidSeverity は重大度として有効な値かを判定する
+func IsValidSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// SeverityAtLeast は severity が threshold 以上の重大度かを判定する
+func SeverityAtLeast(severity, threshold string) bool {
+	return severityRanks[severity] >= severityRanks[threshold]
+}
+
+// SeverityOf はカテゴリの重大度を返す（未知のカテゴリは error として扱う）
+func SeverityOf(category string) string {
+	if severity, ok := categorySeverities[category]; ok {
+		return severity
+	}
+	return SeverityError
+}
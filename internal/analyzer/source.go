@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// AnalyzerConfig はライブラリ用エントリポイントの設定
+type AnalyzerConfig struct {
+	// Options は解析オプション（コマンドラインフラグと同等）
+	Options Options
+	// Importer は依存パッケージの解決に使うインポータ（nil の場合は標準ライブラリをソースから解決する）
+	Importer types.Importer
+	// DiagnosticFilter が設定されている場合、true を返した診断のみを返す（独自の抑制ポリシー用）
+	DiagnosticFilter func(analysis.Diagnostic) bool
+	// Cache が設定されている場合、内容と設定が同じファイルはキャッシュした結果を返す
+	// （Options.Metrics を指定した場合は判定を集計するため使用しない）
+	Cache *ResultCache
+}
+
+// AnalyzeSource は単一ファイルのソースを型チェックして解析し、診断を返すライブラリ用エントリポイント
+// imp が nil の場合は標準ライブラリをソースから解決するインポータを使用する
+func AnalyzeSource(filename string, src []byte, imp types.Importer) ([]analysis.Diagnostic, error) {
+	return AnalyzeSourceWithConfig(filename, src, AnalyzerConfig{Importer: imp})
+}
+
+// AnalyzeSourceWithConfig は設定を指定して AnalyzeSource と同様にソースを解析する
+func AnalyzeSourceWithConfig(filename string, src []byte, cfg AnalyzerConfig) ([]analysis.Diagnostic, error) {
+	return analyzeSource("", filename, src, cfg)
+}
+
+// analyzeSource はパッケージパスと設定を指定してソースを解析する
+// pkgPath が空の場合はパッケージ名をパスとして使用する
+func analyzeSource(pkgPath, filename string, src []byte, cfg AnalyzerConfig) ([]analysis.Diagnostic, error) {
+	if cfg.Cache == nil || cfg.Options.Metrics != nil {
+		diagnostics, err := analyzeSourceUncached(pkgPath, filename, src, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return filterDiagnostics(diagnostics, cfg.DiagnosticFilter), nil
+	}
+
+	key, err := cfg.Cache.key(pkgPath, filename, src, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, ok := cfg.Cache.load(key)
+	if !ok {
+		diagnostics, err = analyzeSourceUncached(pkgPath, filename, src, cfg)
+		if err != nil {
+			return nil, err
+		}
+		// 抑制ポリシーはキーに含められないため、フィルタ前の診断を保存する
+		cfg.Cache.store(key, diagnostics)
+	}
+	return filterDiagnostics(diagnostics, cfg.DiagnosticFilter), nil
+}
+
+// filterDiagnostics は filter が true を返した診断のみを返す（filter が nil の場合はすべて返す）
+func filterDiagnostics(diagnostics []analysis.Diagnostic, filter func(analysis.Diagnostic) bool) []analysis.Diagnostic {
+	if filter == nil {
+		return diagnostics
+	}
+	var filtered []analysis.Diagnostic
+	for _, d := range diagnostics {
+		if filter(d) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// analyzeSourceUncached はソースを型チェックして解析し、フィルタ前の診断を返す
+func analyzeSourceUncached(pkgPath, filename string, src []byte, cfg AnalyzerConfig) ([]analysis.Diagnostic, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := cfg.Importer
+	if imp == nil {
+		imp = importer.ForCompiler(fset, "source", nil)
+	}
+	if pkgPath == "" {
+		pkgPath = file.Name.Name
+	}
+
+	info := &types.Info{
+		Types:  make(map[ast.Expr]types.TypeAndValue),
+		Defs:   make(map[*ast.Ident]types.Object),
+		Uses:   make(map[*ast.Ident]types.Object),
+		Scopes: make(map[ast.Node]*types.Scope),
+	}
+
+	// 型エラーがあっても解析は継続する（analysis ドライバと同様に TypeErrors として渡す）
+	var typeErrors []types.Error
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				typeErrors = append(typeErrors, typeErr)
+			}
+		},
+	}
+	pkg, _ := conf.Check(pkgPath, fset, []*ast.File{file}, info)
+
+	var diagnostics []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Analyzer:   Analyzer,
+		Fset:       fset,
+		Files:      []*ast.File{file},
+		Pkg:        pkg,
+		TypesInfo:  info,
+		TypeErrors: typeErrors,
+		Report: func(d analysis.Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		},
+	}
+
+	if _, err := runWithOptions(pass, cfg.Options); err != nil {
+		return nil, err
+	}
+
+	return diagnostics, nil
+}
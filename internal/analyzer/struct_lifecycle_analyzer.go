@@ -0,0 +1,254 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+)
+
+// StructLifecycleAnalyzer は GCP リソースをフィールドに保持する構造体について、
+// そのフィールドを解放するメソッドが型に定義されているかを検査する。
+// コンストラクタでフィールドに代入したリソースはエスケープとして扱われるため、
+// 構造体の Close 等で解放し忘れると関数単位の解析では検出できない。
+// 外部から注入されたリソースは呼び出し元が所有するため、パッケージ内でリソースの生成結果を
+// 代入しているフィールドだけを対象にする
+type StructLifecycleAnalyzer struct {
+	resourceTracker *ResourceTracker
+}
+
+// NewStructLifecycleAnalyzer は新しいStructLifecycleAnalyzerを作成する
+func NewStructLifecycleAnalyzer(resourceTracker *ResourceTracker) *StructLifecycleAnalyzer {
+	return &StructLifecycleAnalyzer{resourceTracker: resourceTracker}
+}
+
+// resourceField は GCP リソースを保持する構造体フィールド
+type resourceField struct {
+	field         *ast.Field
+	name          *ast.Ident
+	variable      *types.Var
+	cleanupMethod string
+}
+
+// FindUnclosedFields はリソースを保持するフィールドのうち、型のどのメソッドでも解放されないものを報告する
+func (sa *StructLifecycleAnalyzer) FindUnclosedFields(pass *analysis.Pass) []analysis.Diagnostic {
+	if pass == nil || pass.TypesInfo == nil {
+		return nil
+	}
+
+	// 型ごとのメソッド宣言
+	methods := make(map[*types.TypeName][]*ast.FuncDecl)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 || fn.Body == nil {
+				continue
+			}
+			if typeName := receiverTypeName(pass.TypesInfo, fn.Recv.List[0].Type); typeName != nil {
+				methods[typeName] = append(methods[typeName], fn)
+			}
+		}
+	}
+
+	created := sa.createdFields(pass.TypesInfo, pass.Files)
+
+	var diagnostics []analysis.Diagnostic
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			structType, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			typeName, ok := pass.TypesInfo.Defs[spec.Name].(*types.TypeName)
+			if !ok {
+				return false
+			}
+			for _, rf := range sa.resourceFields(pass.TypesInfo, structType) {
+				if !created[rf.variable] || closesField(pass.TypesInfo, methods[typeName], rf) {
+					continue
+				}
+				diagnostics = append(diagnostics, analysis.Diagnostic{
+					Pos:      rf.name.Pos(),
+					End:      rf.field.End(),
+					Category: CategoryStructResourceNotClosed,
+					Message: fmt.Sprintf(messages.StructResourceNotClosed,
+						spec.Name.Name, rf.name.Name, rf.name.Name, rf.cleanupMethod),
+				})
+			}
+			return false
+		})
+	}
+	return diagnostics
+}
+
+// resourceFields は構造体の名前付きフィールドのうち GCP リソース型のものを返す。
+// 埋め込みフィールドは解放メソッドが構造体に昇格するため対象外にする
+func (sa *StructLifecycleAnalyzer) resourceFields(typeInfo *types.Info, structType *ast.StructType) []resourceField {
+	var fields []resourceField
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			continue
+		}
+		_, cleanupMethod, ok := sa.resourceTracker.CleanupMethodForType(typeInfo.TypeOf(field.Type))
+		if !ok {
+			continue
+		}
+		for _, name := range field.Names {
+			if variable, ok := typeInfo.Defs[name].(*types.Var); ok {
+				fields = append(fields, resourceField{field: field, name: name, variable: variable, cleanupMethod: cleanupMethod})
+			}
+		}
+	}
+	return fields
+}
+
+// createdFields はパッケージ内でリソースの生成結果を代入しているフィールドを返す。
+// 生成呼び出しの直接の代入（r.client, err = spanner.NewClient(...)）と、生成結果を受けた変数の
+// 代入・複合リテラルでの指定（&Repo{client: client}）を対象にする
+func (sa *StructLifecycleAnalyzer) createdFields(typeInfo *types.Info, files []*ast.File) map[*types.Var]bool {
+	// 生成呼び出しの結果を受けた変数
+	createdVars := make(map[types.Object]bool)
+	record := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, expr := range lhs {
+			call, ok := ast.Unparen(assignedValue(lhs, rhs, i)).(*ast.CallExpr)
+			if !ok || !sa.resourceTracker.isResourceCreationCall(call) {
+				continue
+			}
+			if ident, ok := expr.(*ast.Ident); ok {
+				if obj := typeInfo.ObjectOf(ident); obj != nil {
+					createdVars[obj] = true
+				}
+			}
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				record(node.Lhs, node.Rhs)
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, len(node.Names))
+				for i, name := range node.Names {
+					lhs[i] = name
+				}
+				record(lhs, node.Values)
+			}
+			return true
+		})
+	}
+
+	isCreated := func(expr ast.Expr) bool {
+		if expr == nil {
+			return false
+		}
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.CallExpr:
+			return sa.resourceTracker.isResourceCreationCall(e)
+		case *ast.Ident:
+			return createdVars[typeInfo.ObjectOf(e)]
+		}
+		return false
+	}
+
+	fields := make(map[*types.Var]bool)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range node.Lhs {
+					sel, ok := ast.Unparen(lhs).(*ast.SelectorExpr)
+					if !ok || !isCreated(assignedValue(node.Lhs, node.Rhs, i)) {
+						continue
+					}
+					if field, ok := typeInfo.Uses[sel.Sel].(*types.Var); ok && field.IsField() {
+						fields[field] = true
+					}
+				}
+			case *ast.CompositeLit:
+				typ := typeInfo.TypeOf(node)
+				if typ == nil {
+					return true
+				}
+				structType, ok := typ.Underlying().(*types.Struct)
+				if !ok {
+					return true
+				}
+				for i, elt := range node.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						key, ok := kv.Key.(*ast.Ident)
+						if !ok || !isCreated(kv.Value) {
+							continue
+						}
+						if field, ok := typeInfo.Uses[key].(*types.Var); ok && field.IsField() {
+							fields[field] = true
+						}
+						continue
+					}
+					if i < structType.NumFields() && isCreated(elt) {
+						fields[structType.Field(i)] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return fields
+}
+
+// assignedValue は代入の i 番目の左辺に対応する右辺を返す。
+// 多値を返す呼び出し（client, err := spanner.NewClient(...)）では先頭の左辺にだけ呼び出しを対応させる
+func assignedValue(lhs, rhs []ast.Expr, i int) ast.Expr {
+	switch {
+	case len(rhs) == len(lhs):
+		return rhs[i]
+	case i == 0 && len(rhs) == 1:
+		return rhs[0]
+	}
+	return nil
+}
+
+// receiverTypeName はレシーバの型（ポインタ・型パラメータを外したもの）の型名を返す
+func receiverTypeName(typeInfo *types.Info, expr ast.Expr) *types.TypeName {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	expr = uninstantiatedFunc(expr)
+	if ident, ok := expr.(*ast.Ident); ok {
+		if typeName, ok := typeInfo.Uses[ident].(*types.TypeName); ok {
+			return typeName
+		}
+	}
+	return nil
+}
+
+// closesField はメソッドのいずれかで recv.field.<解放メソッド>() を呼んでいるかを判定する
+func closesField(typeInfo *types.Info, methods []*ast.FuncDecl, rf resourceField) bool {
+	found := false
+	for _, fn := range methods {
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return !found
+			}
+			sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != rf.cleanupMethod {
+				return true
+			}
+			if fieldSel, ok := ast.Unparen(sel.X).(*ast.SelectorExpr); ok && typeInfo.Uses[fieldSel.Sel] == rf.variable {
+				found = true
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestStructLifecycleAnalyzer_FindUnclosedFields(t *testing.T) {
+	// 生成したリソースを変数経由で複合リテラルに渡すコンストラクタ
+	const newClientRepository = `func NewRepository(ctx context.Context) (*Repository, error) {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{client: client}, nil
+}`
+	// 生成呼び出しの結果をフィールドへ直接代入するコンストラクタ
+	const newBothRepository = `func NewRepository(ctx context.Context) (*Repository, error) {
+	r := &Repository{}
+	var err error
+	if r.client, err = spanner.NewClient(ctx, "db"); err != nil {
+		return nil, err
+	}
+	if r.bucket, err = storage.NewClient(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}`
+
+	tests := []struct {
+		name          string
+		fields        string
+		constructor   string
+		methods       string
+		expectedCount int
+	}{
+		{
+			name:        "Close releases the field",
+			fields:      `client *spanner.Client`,
+			constructor: newClientRepository,
+			methods: `func (r *Repository) Close() {
+	r.client.Close()
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "Embedded and non-resource fields are ignored",
+			fields: `*spanner.Client
+	row *spanner.Row`,
+			expectedCount: 0,
+		},
+		{
+			name: "Close releases the field through errors.Join",
+			fields: `client *spanner.Client
+	bucket *storage.Client`,
+			constructor: newBothRepository,
+			methods: `func (r *Repository) Close() error {
+	r.client.Close()
+	return errors.Join(r.bucket.Close())
+}`,
+			expectedCount: 0,
+		},
+		{
+			name: "Close forgets one field",
+			fields: `client *spanner.Client
+	bucket *storage.Client`,
+			constructor: newBothRepository,
+			methods: `func (r *Repository) Close() {
+	r.client.Close()
+}`,
+			expectedCount: 1,
+		},
+		{
+			name: "No cleanup method",
+			fields: `client *spanner.Client
+	bucket *storage.Client`,
+			constructor:   newBothRepository,
+			expectedCount: 2,
+		},
+		{
+			name:          "Created resource assigned through a variable",
+			fields:        `client *spanner.Client`,
+			constructor:   newClientRepository,
+			expectedCount: 1,
+		},
+		{
+			name: "Injected dependencies are owned by the caller",
+			fields: `client *spanner.Client
+	bucket *storage.Client`,
+			constructor: `func NewRepository(client *spanner.Client, bucket *storage.Client) *Repository {
+	return &Repository{client: client, bucket: bucket}
+}`,
+			expectedCount: 0,
+		},
+		{
+			name:        "Method on another type closes a field of the same name",
+			fields:      `client *spanner.Client`,
+			constructor: newClientRepository,
+			methods: `type other struct{ client *spanner.Client }
+
+func (o *other) Close() {
+	o.client.Close()
+}`,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package test
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+)
+
+var (
+	_ = errors.New
+	_ context.Context
+)
+
+type Repository struct {
+	` + tt.fields + `
+	name string
+}
+
+var _ = storage.NewClient
+
+` + tt.constructor + `
+
+` + tt.methods + `
+`
+			diagnostics := runAnalyzerOnSourceWithOptions(t, "", "test.go", src, Options{StructLifecycle: true})
+			unclosed := diagnosticsWithCategory(diagnostics, CategoryStructResourceNotClosed)
+			if len(unclosed) != tt.expectedCount {
+				t.Errorf("診断数 = %d, want %d: %v", len(unclosed), tt.expectedCount, diagnostics)
+			}
+
+			if off := runAnalyzerOnSource(t, "test.go", src); len(diagnosticsWithCategory(off, CategoryStructResourceNotClosed)) != 0 {
+				t.Errorf("Expected no struct lifecycle diagnostics without -gcpstruct-lifecycle, got %v", off)
+			}
+		})
+	}
+}
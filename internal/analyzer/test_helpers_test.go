@@ -0,0 +1,117 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// fakeGCPPackages はスタブで解決するインポートパスと testdata 内ディレクトリの対応表
+var fakeGCPPackages = map[string]string{
+	"cloud.google.com/go/datastore":                    "testdata/fakegcp/datastore",
+	"cloud.google.com/go/pubsub":                       "testdata/fakegcp/pubsub",
+	"example.com/batcher":                              "testdata/fakegcp/batcher",
+	"cloud.google.com/go/spanner":                      "testdata/fakegcp/spanner",
+	"cloud.google.com/go/spanner/admin/database/apiv1": "testdata/fakegcp/spanneradmin",
+	"cloud.google.com/go/storage":                      "testdata/fakegcp/storage",
+	"golang.org/x/sync/errgroup":                       "testdata/fakegcp/errgroup",
+	"google.golang.org/api/compute/v1":                 "testdata/fakegcp/compute",
+}
+
+// stdImporter は標準ライブラリをソースから解決するインポータ（テスト間で共有してキャッシュを効かせる）
+var stdImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// fakeGCPImporter は GCP パッケージを testdata のスタブで、それ以外を標準インポータで解決する
+type fakeGCPImporter struct {
+	t        testing.TB
+	packages map[string]*types.Package
+}
+
+// Import は types.Importer を実装する
+func (fi *fakeGCPImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := fi.packages[path]; ok {
+		return pkg, nil
+	}
+
+	dir, ok := fakeGCPPackages[path]
+	if !ok {
+		return stdImporter.Import(path)
+	}
+
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var astFiles []*ast.File
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		astFile, err := parser.ParseFile(fset, file, src, 0)
+		if err != nil {
+			return nil, err
+		}
+		astFiles = append(astFiles, astFile)
+	}
+
+	conf := types.Config{Importer: fi}
+	pkg, err := conf.Check(path, fset, astFiles, nil)
+	if err != nil {
+		fi.t.Fatalf("Failed to type check fake package %s: %v", path, err)
+	}
+	fi.packages[path] = pkg
+	return pkg, nil
+}
+
+// runAnalyzerOnSource はスタブ化した GCP パッケージで型チェックしたソースに Analyzer を実行する
+func runAnalyzerOnSource(t *testing.T, filename, src string) []analysis.Diagnostic {
+	t.Helper()
+	return runAnalyzerOnSourceWithOptions(t, "", filename, src, Options{})
+}
+
+// runAnalyzerOnSourceWithOptions はパッケージパスとオプションを指定して Analyzer を実行する
+// pkgPath が空の場合はパッケージ名をパスとして使用する
+func runAnalyzerOnSourceWithOptions(t *testing.T, pkgPath, filename, src string, opts Options) []analysis.Diagnostic {
+	t.Helper()
+
+	imp := &fakeGCPImporter{t: t, packages: make(map[string]*types.Package)}
+	diagnostics, err := analyzeSource(pkgPath, filename, []byte(src), AnalyzerConfig{Options: opts, Importer: imp})
+	if err != nil {
+		t.Fatalf("Failed to analyze %s: %v", filename, err)
+	}
+
+	return diagnostics
+}
+
+// diagnosticsContaining はメッセージに指定文字列を含む診断のみを返す
+func diagnosticsContaining(diagnostics []analysis.Diagnostic, substr string) []analysis.Diagnostic {
+	var matched []analysis.Diagnostic
+	for _, d := range diagnostics {
+		if strings.Contains(d.Message, substr) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+// diagnosticsWithCategory は指定カテゴリの診断のみを返す
+func diagnosticsWithCategory(diagnostics []analysis.Diagnostic, category string) []analysis.Diagnostic {
+	var matched []analysis.Diagnostic
+	for _, d := range diagnostics {
+		if d.Category == category {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,28 @@
+// Package batcher は設定ファイルで追加するサービスを模した単体テスト用スタブ
+package batcher
+
+import (
+	"context"
+)
+
+// Publisher はバッチングするパブリッシャーのモック
+type Publisher struct{}
+
+// NewPublisher creates a new batching publisher (mock)
+func NewPublisher(ctx context.Context, topic string) (*Publisher, error) {
+	return &Publisher{}, nil
+}
+
+// Publish enqueues a message (mock)
+func (p *Publisher) Publish(data []byte) {}
+
+// Drain flushes pending messages and stops the publisher (mock)
+func (p *Publisher) Drain() {}
+
+// NewDefaultPublisher creates a publisher for the default project (mock)
+func NewDefaultPublisher(topic string) (*Publisher, error) {
+	return &Publisher{}, nil
+}
+
+// Shutdown flushes pending messages within the deadline of ctx (mock)
+func (p *Publisher) Shutdown(ctx context.Context) error { return nil }
@@ -0,0 +1,32 @@
+// Package compute は単体テスト用の google.golang.org/api/compute/v1 スタブ
+package compute
+
+import (
+	"context"
+)
+
+// Service は生成された REST クライアントのモック（http.Client を使うため Close を持たない）
+type Service struct {
+	Instances *InstancesService
+}
+
+// NewService creates a new Service (mock)
+func NewService(ctx context.Context, opts ...interface{}) (*Service, error) {
+	return &Service{Instances: &InstancesService{}}, nil
+}
+
+// InstancesService はインスタンス API のモック
+type InstancesService struct{}
+
+// List returns a list call (mock)
+func (s *InstancesService) List(project, zone string) *InstancesListCall {
+	return &InstancesListCall{}
+}
+
+// InstancesListCall はリスト呼び出しのモック
+type InstancesListCall struct{}
+
+// Do executes the call (mock)
+func (c *InstancesListCall) Do() (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,12 @@
+// Package datastore は単体テスト用の cloud.google.com/go/datastore スタブ（サービスルールなし）
+package datastore
+
+import "context"
+
+type Client struct{}
+
+func NewClient(ctx context.Context, projectID string) (*Client, error) {
+	return &Client{}, nil
+}
+
+func (c *Client) Close() error { return nil }
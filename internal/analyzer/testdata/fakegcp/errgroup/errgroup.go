@@ -0,0 +1,30 @@
+// Package errgroup は単体テスト用の golang.org/x/sync/errgroup スタブ
+package errgroup
+
+import (
+	"context"
+)
+
+// Group はゴルーチングループのモック
+type Group struct {
+	cancel func()
+}
+
+// WithContext returns a new group and a derived context (mock)
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go runs f in a new goroutine (mock)
+func (g *Group) Go(f func() error) {
+	_ = f()
+}
+
+// Wait waits for all goroutines and cancels the derived context (mock)
+func (g *Group) Wait() error {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return nil
+}
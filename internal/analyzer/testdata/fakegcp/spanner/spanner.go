@@ -0,0 +1,80 @@
+// Package spanner は単体テスト用の cloud.google.com/go/spanner スタブ
+package spanner
+
+import (
+	"context"
+)
+
+// Client はSpannerクライアントのモック
+type Client struct{}
+
+// ReadWriteTransaction はReadWriteTransactionのモック
+type ReadWriteTransaction struct{}
+
+// ReadOnlyTransaction はReadOnlyTransactionのモック
+type ReadOnlyTransaction struct{}
+
+// Statement はクエリステートメントのモック
+type Statement struct {
+	SQL string
+}
+
+// Row は結果行のモック
+type Row struct{}
+
+// RowIterator は結果イテレータのモック
+type RowIterator struct{}
+
+// NewClient creates a new Spanner client (mock)
+func NewClient(ctx context.Context, database string) (*Client, error) {
+	return &Client{}, nil
+}
+
+// NewStatement creates a new statement (mock)
+func NewStatement(sql string) Statement {
+	return Statement{SQL: sql}
+}
+
+// Close closes the client (mock)
+func (c *Client) Close() {}
+
+// ReadWriteTransaction executes a read-write transaction (mock)
+func (c *Client) ReadWriteTransaction(ctx context.Context, fn func(context.Context, *ReadWriteTransaction) error) (int64, error) {
+	return 0, fn(ctx, &ReadWriteTransaction{})
+}
+
+// ReadOnlyTransaction creates a read-only transaction (mock)
+func (c *Client) ReadOnlyTransaction() *ReadOnlyTransaction {
+	return &ReadOnlyTransaction{}
+}
+
+// Single creates a single-use read-only transaction (mock)
+func (c *Client) Single() *ReadOnlyTransaction {
+	return &ReadOnlyTransaction{}
+}
+
+// Update executes an update statement (mock)
+func (txn *ReadWriteTransaction) Update(ctx context.Context, stmt Statement) (int64, error) {
+	return 0, nil
+}
+
+// Query executes a query (mock)
+func (txn *ReadOnlyTransaction) Query(ctx context.Context, stmt Statement) *RowIterator {
+	return &RowIterator{}
+}
+
+// Close closes the read-only transaction (mock)
+func (txn *ReadOnlyTransaction) Close() {}
+
+// Next returns the next row (mock)
+func (iter *RowIterator) Next() (*Row, error) {
+	return nil, context.Canceled
+}
+
+// Do calls f for each row and stops the iterator (mock)
+func (iter *RowIterator) Do(f func(r *Row) error) error {
+	return nil
+}
+
+// Stop stops the iterator (mock)
+func (iter *RowIterator) Stop() {}
@@ -0,0 +1,25 @@
+// Package database は単体テスト用の cloud.google.com/go/spanner/admin/database/apiv1 スタブ
+package database
+
+import "context"
+
+type DatabaseAdminClient struct{}
+
+type UpdateDatabaseDdlRequest struct {
+	Database   string
+	Statements []string
+}
+
+type UpdateDatabaseDdlOperation struct{}
+
+func NewDatabaseAdminClient(ctx context.Context) (*DatabaseAdminClient, error) {
+	return &DatabaseAdminClient{}, nil
+}
+
+func (c *DatabaseAdminClient) UpdateDatabaseDdl(ctx context.Context, req *UpdateDatabaseDdlRequest) (*UpdateDatabaseDdlOperation, error) {
+	return &UpdateDatabaseDdlOperation{}, nil
+}
+
+func (op *UpdateDatabaseDdlOperation) Wait(ctx context.Context) error { return nil }
+
+func (c *DatabaseAdminClient) Close() error { return nil }
@@ -17,8 +17,14 @@ type ResourceInfo struct {
 	CreationFunction string             // 生成関数名（NewClient, ReadOnlyTransaction 等）
 	CleanupMethod    string             // 解放メソッド名（Close, Stop, Cleanup）
 	IsRequired       bool               // 解放が必須かどうか
+	ErrorSignificant bool               // 解放メソッドのエラーを無視してはならないか
+	RequiresContext  bool               // 解放メソッドが context.Context を引数に取るか
+	RequiredAfter    []string           // これらのメソッドを呼んだ場合のみ解放が必要（空の場合は常に必要）
+	PoolExpr         string             // sync.Pool から取得した場合のプール式（返却先）
 	Scope            *types.Scope       // 変数のスコープ
 	SpannerEscape    *SpannerEscapeInfo // Spannerエスケープ情報（Spannerリソースのみ）
+	// BranchCreationPos は if/else の他の分岐で同じ変数に代入した生成位置（解放はすべての分岐から到達可能である必要がある）
+	BranchCreationPos []token.Pos
 }
 
 // NewResourceInfo は ResourceInfo のコンストラクタ
@@ -180,9 +186,26 @@ type ServiceRule struct {
 
 // CleanupMethod は解放メソッドの詳細情報を表す
 type CleanupMethod struct {
-	Method      string `yaml:"method"`      // メソッド名
-	Required    bool   `yaml:"required"`    // 必須かどうか
-	Description string `yaml:"description"` // 説明
+	Method           string   `yaml:"method"`                      // メソッド名
+	Required         bool     `yaml:"required"`                    // 必須かどうか
+	Description      string   `yaml:"description"`                 // 説明
+	ErrorSignificant bool     `yaml:"error_significant,omitempty"` // 戻り値のエラーを無視してはならないか
+	RequiresContext  bool     `yaml:"requires_context,omitempty"`  // context.Context を引数に取るか（Shutdown(ctx) 等）
+	AppliesTo        []string `yaml:"applies_to,omitempty"`        // 対象とする生成関数（空の場合はサービス全体）
+	RequiredAfter    []string `yaml:"required_after,omitempty"`    // これらのメソッドを呼んだ場合のみ解放が必要（空の場合は常に必要）
+}
+
+// AppliesToFunction は解放メソッドが指定された生成関数のリソースに適用されるかを判定する
+func (c CleanupMethod) AppliesToFunction(funcName string) bool {
+	if len(c.AppliesTo) == 0 {
+		return true
+	}
+	for _, f := range c.AppliesTo {
+		if f == funcName {
+			return true
+		}
+	}
+	return false
 }
 
 // HasCreationFunc は指定された関数名が生成関数に含まれるかチェックする
@@ -206,11 +229,47 @@ func (s *ServiceRule) GetRequiredCleanupMethods() []CleanupMethod {
 	return required
 }
 
+// IsErrorSignificant は指定された生成関数のリソースで解放メソッドのエラーを無視してはならないかを判定する
+func (s *ServiceRule) IsErrorSignificant(creationFunc, method string) bool {
+	for _, cm := range s.CleanupMethods {
+		if cm.Method == method && cm.ErrorSignificant && cm.AppliesToFunction(creationFunc) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresContext は指定された生成関数のリソースで解放メソッドが context.Context を引数に取るかを判定する
+func (s *ServiceRule) RequiresContext(creationFunc, method string) bool {
+	for _, cm := range s.CleanupMethods {
+		if cm.Method == method && cm.RequiresContext && cm.AppliesToFunction(creationFunc) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredAfter は指定された生成関数のリソースで、解放が必要になるメソッド（required_after）を返す。
+// 空の場合は生成した時点から解放が必要
+func (s *ServiceRule) RequiredAfter(creationFunc, method string) []string {
+	for _, cm := range s.CleanupMethods {
+		if cm.Method == method && len(cm.RequiredAfter) > 0 && cm.AppliesToFunction(creationFunc) {
+			return cm.RequiredAfter
+		}
+	}
+	return nil
+}
+
 // EscapeInfo は変数の逃げパス（return/field格納）情報を表す
 type EscapeInfo struct {
-	IsReturned      bool   // 関数戻り値として返されるか
-	IsFieldAssigned bool   // 構造体フィールドに代入されるか
-	EscapeReason    string // 逃げる理由の説明
+	IsReturned         bool   // 関数戻り値として返されるか
+	IsFieldAssigned    bool   // 構造体フィールドに代入されるか
+	IsWrappedAndClosed bool   // ラッパーに包まれ、ラッパーがdeferでCloseされるか
+	IsOutParamAssigned bool   // ポインタ引数の参照先（*out = v）に代入されるか
+	IsUsedWithReflect  bool   // reflect パッケージの関数に渡されるか（解放を静的に確認できない）
+	IsGlobalAssigned   bool   // パッケージ変数に代入されるか（lifetime: app のサービスのみ対象外にする）
+	IsAtomicStored     bool   // sync/atomic.Pointer[T] の Store で格納されるか
+	EscapeReason       string // 逃げる理由の説明
 }
 
 // NewEscapeInfo は EscapeInfo のコンストラクタ
@@ -224,9 +283,15 @@ func NewEscapeInfo(isReturned, isFieldAssigned bool, reason string) *EscapeInfo
 
 // HasEscaped は変数が逃げているかどうかを判定する
 func (e *EscapeInfo) HasEscaped() bool {
-	return e.IsReturned || e.IsFieldAssigned
+	return e.IsReturned || e.IsFieldAssigned || e.IsWrappedAndClosed || e.IsOutParamAssigned
 }
 
+// sync.Pool から借りたリソースの取得・返却メソッド（返却メソッドはサービスごとに pool_return_method で変更できる）
+const (
+	PoolGetMethod    = "Get"
+	PoolReturnMethod = "Put"
+)
+
 // Spannerトランザクション種別定数
 const (
 	ReadWriteTransactionType = "ReadWriteTransaction"
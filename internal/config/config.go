@@ -19,6 +19,57 @@ const (
 	ExceptionTypeTest          = "test"           // テストコード
 )
 
+// init 関数内で生成されたリソースの扱い（analyze_init）
+const (
+	AnalyzeInitWarn  = "warn"  // 警告として報告する（デフォルト）
+	AnalyzeInitSkip  = "skip"  // 報告しない
+	AnalyzeInitError = "error" // 通常の関数と同様にエラーとして報告する
+)
+
+// validAnalyzeInitModes は analyze_init の有効な値のリスト
+var validAnalyzeInitModes = []string{
+	AnalyzeInitWarn,
+	AnalyzeInitSkip,
+	AnalyzeInitError,
+}
+
+// 生成コード（"// Code generated ... DO NOT EDIT." ヘッダを持つファイル）の扱い（generated_file_policy）
+// 未設定の場合は通常のファイルと同様に報告する
+const (
+	GeneratedFilePolicySkip = "skip" // 解析はするが報告しない
+	GeneratedFilePolicyWarn = "warn" // 解放漏れを警告として報告する
+)
+
+// validGeneratedFilePolicies は generated_file_policy の有効な値のリスト
+var validGeneratedFilePolicies = []string{
+	GeneratedFilePolicySkip,
+	GeneratedFilePolicyWarn,
+}
+
+// リソースの生存期間（サービスごとの lifetime）
+// 未設定の場合は field_assignment_exempt に従い、パッケージ変数への代入は対象外にしない
+const (
+	LifetimeApp     = "app"     // アプリケーション全体で共有し終了時に解放する（フィールド・パッケージ変数への代入を対象外にする）
+	LifetimeRequest = "request" // リクエストごとに解放する（フィールドへの代入も報告する）
+)
+
+// validLifetimes は lifetime の有効な値のリスト
+var validLifetimes = []string{
+	LifetimeApp,
+	LifetimeRequest,
+}
+
+// サービスの種類（サービスごとの service_type）
+// 未設定の場合はデータを操作するクライアントとして扱う
+const (
+	ServiceTypeAdmin = "admin" // スキーマ変更やインスタンス管理を行う管理 API のクライアント
+)
+
+// validServiceTypes は service_type の有効な値のリスト
+var validServiceTypes = []string{
+	ServiceTypeAdmin,
+}
+
 // validExceptionTypes は有効な例外タイプのリスト
 var validExceptionTypes = []string{
 	ExceptionTypeShortLived,
@@ -32,13 +83,58 @@ type ServiceRule struct {
 	PackagePath    string          `yaml:"package_path"`       // パッケージパス
 	CreationFuncs  []string        `yaml:"creation_functions"` // 生成関数一覧
 	CleanupMethods []CleanupMethod `yaml:"cleanup_methods"`    // 解放メソッド一覧
+	// FieldAssignmentExempt は構造体フィールドへの代入を逃げパスとして解放漏れの対象外にするか（未設定の場合は true）
+	FieldAssignmentExempt *bool `yaml:"field_assignment_exempt,omitempty"`
+	// AppliesToTestFiles はテストファイル（*_test.go）内のリソースも検査するか（未設定の場合は true）
+	AppliesToTestFiles *bool `yaml:"applies_to_test_files,omitempty"`
+	// Lifetime はリソースの生存期間（app または request）。設定した場合は field_assignment_exempt より優先する
+	Lifetime string `yaml:"lifetime,omitempty"`
+	// ContextCancelledCleanup は生成時に渡した context のキャンセルで解放されるリソース（ストリーミング RPC 等）か。
+	// true の場合、cancel を defer した派生 context で生成したリソースは解放済みとみなす
+	ContextCancelledCleanup bool `yaml:"context_cancelled_cleanup,omitempty"`
+	// PoolReturnMethod は sync.Pool から借りたリソースをプールに返却するメソッド名（未設定の場合は Put）
+	PoolReturnMethod string `yaml:"pool_return_method,omitempty"`
+	// ServiceType はサービスの種類（admin: 管理 API のクライアント）。
+	// admin のクライアントは短命プログラム例外のパッケージでも、クライアントを繰り返し操作する関数では検査する
+	ServiceType string `yaml:"service_type,omitempty"`
+}
+
+// IsFieldAssignmentExempt は構造体フィールドに代入されたリソースを解放漏れの対象外にするかを返す
+func (s *ServiceRule) IsFieldAssignmentExempt() bool {
+	switch s.Lifetime {
+	case LifetimeApp:
+		return true
+	case LifetimeRequest:
+		return false
+	}
+	return s.FieldAssignmentExempt == nil || *s.FieldAssignmentExempt
+}
+
+// IsAppLifetime はアプリケーション全体で共有するリソース（lifetime: app）として、
+// パッケージ変数への代入も解放漏れの対象外にするかを返す
+func (s *ServiceRule) IsAppLifetime() bool {
+	return s.Lifetime == LifetimeApp
+}
+
+// IsAdmin は管理 API のクライアント（service_type: admin）を生成するサービスかを返す
+func (s *ServiceRule) IsAdmin() bool {
+	return s.ServiceType == ServiceTypeAdmin
+}
+
+// IsAppliedToTestFiles はテストファイル内のリソースも検査するかを返す
+func (s *ServiceRule) IsAppliedToTestFiles() bool {
+	return s.AppliesToTestFiles == nil || *s.AppliesToTestFiles
 }
 
 // CleanupMethod は解放メソッドの詳細情報を表す
 type CleanupMethod struct {
-	Method      string `yaml:"method"`      // メソッド名
-	Required    bool   `yaml:"required"`    // 必須かどうか
-	Description string `yaml:"description"` // 説明
+	Method           string   `yaml:"method"`                      // メソッド名
+	Required         bool     `yaml:"required"`                    // 必須かどうか
+	Description      string   `yaml:"description"`                 // 説明
+	ErrorSignificant bool     `yaml:"error_significant,omitempty"` // 戻り値のエラーを無視してはならないか
+	RequiresContext  bool     `yaml:"requires_context,omitempty"`  // context.Context を引数に取るか（Shutdown(ctx) 等）
+	AppliesTo        []string `yaml:"applies_to,omitempty"`        // 対象とする生成関数（空の場合はサービス全体）
+	RequiredAfter    []string `yaml:"required_after,omitempty"`    // これらのメソッドを呼んだ場合のみ解放が必要（空の場合は常に必要）
 }
 
 // ExceptionCondition はパッケージ例外の条件を表す
@@ -57,10 +153,31 @@ type PackageExceptionRule struct {
 	Condition ExceptionCondition `yaml:"condition"` // 例外適用条件
 }
 
+// ClosureManagedHelper はクロージャに渡したリソースを呼び出し後に解放するユーザー定義のヘルパー関数を表す
+// 例: withTxn(client.ReadOnlyTransaction(), func(txn *spanner.ReadOnlyTransaction) error { ... })
+type ClosureManagedHelper struct {
+	Function string `yaml:"function"` // ヘルパー関数名（メソッドの場合はメソッド名）
+	Param    int    `yaml:"param"`    // 管理対象のリソースを受け取るクロージャ引数の位置（0 始まり）
+}
+
 // Config はツール全体の設定を表す
 type Config struct {
+	// SchemaVersion は設定ファイルのスキーマバージョン（未設定の場合はバージョン 1 として扱う）
+	SchemaVersion     int                    `yaml:"schema_version,omitempty"`
 	Services          []ServiceRule          `yaml:"services"`
 	PackageExceptions []PackageExceptionRule `yaml:"package_exceptions,omitempty"`
+	AnalyzeInit       string                 `yaml:"analyze_init,omitempty"` // init 関数内のリソース生成の扱い（warn|skip|error）
+	// GeneratedFilePolicy は生成コード（モック等）内のリソースの扱い（skip|warn）
+	GeneratedFilePolicy string `yaml:"generated_file_policy,omitempty"`
+	// DeferRegistrationMethods は解放関数を登録するメソッド名（stack.Defer(client.Close) 等）
+	DeferRegistrationMethods []string `yaml:"defer_registration_methods,omitempty"`
+	// ClosureManagedHelpers はクロージャ引数のリソースを自動管理するヘルパー関数
+	ClosureManagedHelpers []ClosureManagedHelper `yaml:"closure_managed_helpers,omitempty"`
+	// PackagePathAliases はフォークやミラーのパッケージパスの接頭辞 -> サービス名
+	// （例: example.com/internal/vendored/spanner: spanner）
+	PackagePathAliases map[string]string `yaml:"package_path_aliases,omitempty"`
+	// PurePackages はリソースを所有しない（生成したリソースの解放を常に外部に委ねる）パッケージのパスパターン
+	PurePackages []string `yaml:"pure_packages,omitempty"`
 }
 
 // LoadConfig は指定されたパスから設定ファイルを読み込む
@@ -87,6 +204,12 @@ var defaultRules embed.FS
 
 // LoadDefaultConfig はデフォルトの設定を読み込む
 func LoadDefaultConfig() (*Config, error) {
+	return DefaultConfig()
+}
+
+// DefaultConfig は埋め込まれたデフォルト設定を解析した *Config を返す。
+// 呼び出しごとに新しい値を返すため、呼び出し側で変更・マージしてもよい
+func DefaultConfig() (*Config, error) {
 	data, err := defaultRules.ReadFile("rules.yaml")
 	if err != nil {
 		return nil, fmt.Errorf(messages.DefaultConfigLoadFailed, err)
@@ -100,13 +223,88 @@ func LoadDefaultConfig() (*Config, error) {
 	return &config, nil
 }
 
+// StarterConfig はデフォルト設定を YAML に変換したものを返す（-gcpconfig-init で出力する雛形）
+func StarterConfig() ([]byte, error) {
+	config, err := DefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	return data, nil
+}
+
 // Validate は設定の妥当性を検証する
 func (c *Config) Validate() error {
 	if len(c.Services) == 0 {
 		return errors.New(messages.ServicesListEmpty)
 	}
 
-	for i, service := range c.Services {
+	if err := validateServiceRules(c.Services); err != nil {
+		return err
+	}
+
+	if c.SchemaVersion < 0 {
+		return fmt.Errorf(messages.NegativeSchemaVersion, c.SchemaVersion)
+	}
+	if c.SchemaVersion > CurrentSchemaVersion {
+		return fmt.Errorf(messages.UnsupportedSchemaVersion, c.SchemaVersion, CurrentSchemaVersion)
+	}
+
+	if c.AnalyzeInit != "" && !isValidAnalyzeInitMode(c.AnalyzeInit) {
+		return fmt.Errorf(messages.InvalidAnalyzeInit, c.AnalyzeInit, validAnalyzeInitModes)
+	}
+
+	if c.GeneratedFilePolicy != "" && !isValidGeneratedFilePolicy(c.GeneratedFilePolicy) {
+		return fmt.Errorf(messages.InvalidGeneratedFilePolicy, c.GeneratedFilePolicy, validGeneratedFilePolicies)
+	}
+
+	// パッケージパスの別名は設定されたサービスを指す必要がある
+	for alias, serviceName := range c.PackagePathAliases {
+		if !c.HasService(serviceName) {
+			return fmt.Errorf(messages.PackageAliasServiceUnknown, alias, serviceName)
+		}
+	}
+
+	// クロージャ管理ヘルパーの検証
+	for i, helper := range c.ClosureManagedHelpers {
+		if helper.Function == "" {
+			return fmt.Errorf(messages.ClosureHelperNameEmpty, i)
+		}
+		if helper.Param < 0 {
+			return fmt.Errorf(messages.ClosureHelperParamNegative, i, helper.Function, helper.Param)
+		}
+	}
+
+	return validatePackageExceptions(c.PackageExceptions)
+}
+
+// ValidateRuleYAML は services と package_exceptions だけを含む YAML の断片を検証し、最初のエラーを返す。
+// ファイルを読まないため、ルール編集 UI などに組み込んで保存前の検証に使える
+func ValidateRuleYAML(data []byte) error {
+	var snippet struct {
+		Services          []ServiceRule          `yaml:"services"`
+		PackageExceptions []PackageExceptionRule `yaml:"package_exceptions"`
+	}
+	if err := yaml.Unmarshal(data, &snippet); err != nil {
+		return fmt.Errorf(messages.ConfigYAMLParseFailed, err)
+	}
+	if len(snippet.Services) == 0 && len(snippet.PackageExceptions) == 0 {
+		return errors.New(messages.RuleSnippetEmpty)
+	}
+
+	if err := validateServiceRules(snippet.Services); err != nil {
+		return err
+	}
+	return validatePackageExceptions(snippet.PackageExceptions)
+}
+
+// validateServiceRules はサービスルールの必須項目を検証する
+func validateServiceRules(services []ServiceRule) error {
+	for i, service := range services {
 		if service.ServiceName == "" {
 			return fmt.Errorf(messages.ServiceNameEmpty, i)
 		}
@@ -120,6 +318,13 @@ func (c *Config) Validate() error {
 			return fmt.Errorf(messages.ServiceCleanupMethodsEmpty, i, service.ServiceName)
 		}
 
+		if service.Lifetime != "" && !isValidLifetime(service.Lifetime) {
+			return fmt.Errorf(messages.InvalidServiceLifetime, i, service.ServiceName, service.Lifetime, validLifetimes)
+		}
+		if service.ServiceType != "" && !isValidServiceType(service.ServiceType) {
+			return fmt.Errorf(messages.InvalidServiceType, i, service.ServiceName, service.ServiceType, validServiceTypes)
+		}
+
 		// 解放メソッドの検証
 		for j, method := range service.CleanupMethods {
 			if method.Method == "" {
@@ -127,9 +332,12 @@ func (c *Config) Validate() error {
 			}
 		}
 	}
+	return nil
+}
 
-	// パッケージ例外の検証
-	for i, exception := range c.PackageExceptions {
+// validatePackageExceptions はパッケージ例外ルールを検証する
+func validatePackageExceptions(exceptions []PackageExceptionRule) error {
+	for i, exception := range exceptions {
 		if exception.Name == "" {
 			return fmt.Errorf(messages.PackageExceptionNameEmpty, i)
 		}
@@ -143,10 +351,27 @@ func (c *Config) Validate() error {
 				i, exception.Name, exception.Condition.Type, validExceptionTypes)
 		}
 	}
-
 	return nil
 }
 
+// InitAnalysisMode は init 関数内のリソース生成の扱いを返す（未設定の場合は warn）
+func (c *Config) InitAnalysisMode() string {
+	if c.AnalyzeInit == "" {
+		return AnalyzeInitWarn
+	}
+	return c.AnalyzeInit
+}
+
+// IsDeferRegistrationMethod は解放関数を登録するメソッドとして設定されているかを判定する
+func (c *Config) IsDeferRegistrationMethod(method string) bool {
+	for _, m := range c.DeferRegistrationMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // GetService は指定された名前のサービスを取得する
 func (c *Config) GetService(serviceName string) *ServiceRule {
 	for i := range c.Services {
@@ -167,6 +392,22 @@ func (c *Config) GetServiceByPackagePath(packagePath string) *ServiceRule {
 	return nil
 }
 
+// ServiceForPackageAlias は package_path_aliases の接頭辞に一致するパッケージのサービス名を返す。
+// 接頭辞はパス要素単位で照合し、複数一致する場合は最も長い接頭辞を優先する
+func (c *Config) ServiceForPackageAlias(packagePath string) (string, bool) {
+	bestAlias, bestService := "", ""
+	for alias, serviceName := range c.PackagePathAliases {
+		alias = strings.TrimSuffix(alias, "/")
+		if packagePath != alias && !strings.HasPrefix(packagePath, alias+"/") {
+			continue
+		}
+		if len(alias) > len(bestAlias) {
+			bestAlias, bestService = alias, serviceName
+		}
+	}
+	return bestService, bestService != ""
+}
+
 // HasService は指定された名前のサービスが存在するかチェックする
 func (c *Config) HasService(serviceName string) bool {
 	return c.GetService(serviceName) != nil
@@ -188,6 +429,26 @@ func (c *Config) ShouldExemptPackage(packagePath string) (bool, string) {
 	return false, ""
 }
 
+// IsPurePackage は指定されたパッケージパスが pure_packages のパターンに一致するかチェックする
+func (c *Config) IsPurePackage(packagePath string) bool {
+	for _, pattern := range c.PurePackages {
+		if matchPattern(pattern, packagePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExemptionTypeForPackage は packagePath に適用される有効なパッケージ例外の条件タイプを返す（該当しない場合は空文字列）
+func (c *Config) ExemptionTypeForPackage(packagePath string) string {
+	for _, exception := range c.PackageExceptions {
+		if exception.Condition.Enabled && matchPattern(exception.Pattern, packagePath) {
+			return exception.Condition.Type
+		}
+	}
+	return ""
+}
+
 // ShouldExemptFilePath は指定されたファイルパスが例外対象かチェックする
 func (c *Config) ShouldExemptFilePath(filePath string) (bool, string) {
 	for _, exception := range c.PackageExceptions {
@@ -288,3 +549,43 @@ func isValidExceptionType(exceptionType string) bool {
 	}
 	return false
 }
+
+// isValidLifetime は lifetime の値が有効かチェックする
+func isValidLifetime(lifetime string) bool {
+	for _, valid := range validLifetimes {
+		if lifetime == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidServiceType は service_type の値が有効かチェックする
+func isValidServiceType(serviceType string) bool {
+	for _, valid := range validServiceTypes {
+		if serviceType == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidGeneratedFilePolicy は generated_file_policy の値が有効かチェックする
+func isValidGeneratedFilePolicy(policy string) bool {
+	for _, valid := range validGeneratedFilePolicies {
+		if policy == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidAnalyzeInitMode は analyze_init の値が有効かチェックする
+func isValidAnalyzeInitMode(mode string) bool {
+	for _, valid := range validAnalyzeInitModes {
+		if mode == valid {
+			return true
+		}
+	}
+	return false
+}
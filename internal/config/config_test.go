@@ -102,6 +102,162 @@ func TestLoadDefaultConfig(t *testing.T) {
 			t.Errorf("Expected exception %s not found", exceptionName)
 		}
 	}
+
+	// Cleanup methods with the same name are only listed twice when they apply to different creation functions
+	for _, service := range config.Services {
+		seen := make(map[string]bool)
+		for _, method := range service.CleanupMethods {
+			key := method.Method + "/" + strings.Join(method.AppliesTo, ",")
+			if seen[key] {
+				t.Errorf("Service %s lists cleanup method %s more than once", service.ServiceName, method.Method)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func TestStarterConfig(t *testing.T) {
+	data, err := StarterConfig()
+	if err != nil {
+		t.Fatalf("Failed to generate starter configuration: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write starter configuration: %v", err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Starter configuration should load: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Starter configuration should be valid: %v", err)
+	}
+
+	defaults, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default configuration: %v", err)
+	}
+	if len(config.Services) != len(defaults.Services) || len(config.PackageExceptions) != len(defaults.PackageExceptions) {
+		t.Errorf("Starter configuration should match the defaults, got %d services and %d exceptions",
+			len(config.Services), len(config.PackageExceptions))
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	config, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default configuration: %v", err)
+	}
+
+	for _, serviceName := range []string{"spanner", "storage", "pubsub", "vision"} {
+		if findServiceByName(config.Services, serviceName) == nil {
+			t.Errorf("Expected service %s not found", serviceName)
+		}
+	}
+	for _, exceptionName := range []string{"cmd_short_lived", "cloud_functions", "test_files"} {
+		if findExceptionByName(config.PackageExceptions, exceptionName) == nil {
+			t.Errorf("Expected exception %s not found", exceptionName)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Default configuration should be valid: %v", err)
+	}
+
+	// Each call returns an independent value that callers may modify
+	config.Services = config.Services[:1]
+	fresh, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default configuration: %v", err)
+	}
+	if len(fresh.Services) <= 1 {
+		t.Errorf("Modifying one default configuration should not affect another, got %d services", len(fresh.Services))
+	}
+}
+
+func TestLoadConfigErrorSignificance(t *testing.T) {
+	configContent := `
+services:
+  - service_name: "storage"
+    package_path: "cloud.google.com/go/storage"
+    creation_functions: ["NewClient", "NewWriter"]
+    cleanup_methods:
+      - method: "Close"
+        required: true
+        description: "Client close"
+      - method: "Close"
+        required: true
+        description: "Writer close"
+        error_significant: true
+        applies_to: ["NewWriter"]
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	methods := config.Services[0].CleanupMethods
+	if len(methods) != 2 {
+		t.Fatalf("Expected 2 cleanup methods, got %d", len(methods))
+	}
+	if methods[0].ErrorSignificant || len(methods[0].AppliesTo) != 0 {
+		t.Errorf("Client close should not be error significant: %+v", methods[0])
+	}
+	if !methods[1].ErrorSignificant || len(methods[1].AppliesTo) != 1 || methods[1].AppliesTo[0] != "NewWriter" {
+		t.Errorf("Writer close should be error significant for NewWriter: %+v", methods[1])
+	}
+}
+
+func TestConfigAnalyzeInit(t *testing.T) {
+	base := func(mode string) *Config {
+		return &Config{
+			Services: []ServiceRule{{
+				ServiceName:    "spanner",
+				PackagePath:    "cloud.google.com/go/spanner",
+				CreationFuncs:  []string{"NewClient"},
+				CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}},
+			}},
+			AnalyzeInit: mode,
+		}
+	}
+
+	tests := []struct {
+		mode     string
+		wantMode string
+		wantErr  bool
+	}{
+		{"", AnalyzeInitWarn, false},
+		{"warn", AnalyzeInitWarn, false},
+		{"skip", AnalyzeInitSkip, false},
+		{"error", AnalyzeInitError, false},
+		{"ignore", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			cfg := base(tt.mode)
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.InitAnalysisMode() != tt.wantMode {
+				t.Errorf("InitAnalysisMode() = %q, want %q", cfg.InitAnalysisMode(), tt.wantMode)
+			}
+		})
+	}
+
+	defaultConfig, err := LoadDefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to load default configuration: %v", err)
+	}
+	if defaultConfig.InitAnalysisMode() != AnalyzeInitWarn {
+		t.Errorf("Default analyze_init = %q, want %q", defaultConfig.InitAnalysisMode(), AnalyzeInitWarn)
+	}
 }
 
 func TestConfigValidation(t *testing.T) {
@@ -565,6 +721,74 @@ func TestConfigValidationMessagesIntegration(t *testing.T) {
 			},
 			expectedMsg: "service[0](test): package path is empty",
 		},
+		{
+			name: "negative_closure_helper_param",
+			config: Config{
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}},
+				},
+				ClosureManagedHelpers: []ClosureManagedHelper{{Function: "withTxn", Param: -1}},
+			},
+			expectedMsg: "closure managed helper[0](withTxn): param must not be negative: -1",
+		},
+		{
+			name: "invalid_generated_file_policy",
+			config: Config{
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}},
+				},
+				GeneratedFilePolicy: "ignore",
+			},
+			expectedMsg: "generated_file_policy: invalid value: ignore (valid values: [skip warn])",
+		},
+		{
+			name: "invalid_service_lifetime",
+			config: Config{
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}, Lifetime: "session"},
+				},
+			},
+			expectedMsg: "service[0](test): invalid lifetime: session (valid values: [app request])",
+		},
+		{
+			name: "invalid_service_type",
+			config: Config{
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}, ServiceType: "management"},
+				},
+			},
+			expectedMsg: "service[0](test): invalid service_type: management (valid values: [admin])",
+		},
+		{
+			name: "package_alias_unknown_service",
+			config: Config{
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}},
+				},
+				PackagePathAliases: map[string]string{"example.com/vendored/spanner": "spanner"},
+			},
+			expectedMsg: `package_path_aliases[example.com/vendored/spanner]: unknown service "spanner"`,
+		},
+		{
+			name: "unsupported_schema_version",
+			config: Config{
+				SchemaVersion: CurrentSchemaVersion + 1,
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}},
+				},
+			},
+			expectedMsg: "schema_version: unsupported version 3 (latest supported: 2)",
+		},
+		{
+			name: "negative_schema_version",
+			config: Config{
+				SchemaVersion: -1,
+				Services: []ServiceRule{
+					{ServiceName: "test", PackagePath: "test", CreationFuncs: []string{"test"}, CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}}},
+				},
+			},
+			expectedMsg: "schema_version: must not be negative: -1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -587,3 +811,97 @@ func containsString(s, substr string) bool {
 			(len(s) > len(substr) &&
 				(strings.Contains(s, substr))))
 }
+
+func TestValidateRuleYAML(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		expectedMsg string // empty means valid
+	}{
+		{
+			name: "valid_service",
+			yaml: `
+services:
+  - service_name: "datastore"
+    package_path: "cloud.google.com/go/datastore"
+    creation_functions: ["NewClient"]
+    cleanup_methods:
+      - method: "Close"
+        required: true
+`,
+		},
+		{
+			name: "valid_exception_only",
+			yaml: `
+package_exceptions:
+  - name: "migrations"
+    pattern: "*/cmd/*"
+    condition:
+      type: "short_lived"
+      enabled: true
+`,
+		},
+		{
+			name:        "empty_snippet",
+			yaml:        "",
+			expectedMsg: "rule snippet defines no services or package exceptions",
+		},
+		{
+			name:        "malformed_yaml",
+			yaml:        "services: [",
+			expectedMsg: "failed to parse YAML configuration",
+		},
+		{
+			name: "empty_service_name",
+			yaml: `
+services:
+  - service_name: ""
+    package_path: "cloud.google.com/go/datastore"
+    creation_functions: ["NewClient"]
+    cleanup_methods:
+      - method: "Close"
+`,
+			expectedMsg: "service[0]: service name is empty",
+		},
+		{
+			name: "empty_pattern",
+			yaml: `
+package_exceptions:
+  - name: "migrations"
+    pattern: ""
+    condition:
+      type: "short_lived"
+`,
+			expectedMsg: "package exception[0](migrations): pattern is empty",
+		},
+		{
+			name: "invalid_condition_type",
+			yaml: `
+package_exceptions:
+  - name: "migrations"
+    pattern: "*/cmd/*"
+    condition:
+      type: "forever"
+`,
+			expectedMsg: "package exception[0](migrations): invalid condition type: forever",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRuleYAML([]byte(tt.yaml))
+			if tt.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("Expected valid snippet, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q", tt.expectedMsg)
+			}
+			if !strings.Contains(err.Error(), tt.expectedMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.expectedMsg, err.Error())
+			}
+		})
+	}
+}
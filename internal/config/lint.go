@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+)
+
+// lintSampleSegment はパターンのワイルドカードを置き換えて代表的なパスを作るときの埋め草のセグメント。
+// 他のパターンのリテラル部分と偶然一致しない名前にする
+const lintSampleSegment = "gcpclosecheck-lint-sample"
+
+// Lint は設定として有効だが適用されることのないルールを警告として返す（-gcpconfig-lint）。
+// サービス名・パッケージパスは先に定義したサービスが優先されるため後の重複は使われず、
+// パッケージ例外は最初に一致したものが適用されるため、先の有効な例外に包含されるパターンは使われない
+func (c *Config) Lint() []string {
+	var warnings []string
+
+	serviceNames := make(map[string]int)
+	packagePaths := make(map[string]int)
+	for i, service := range c.Services {
+		if first, ok := serviceNames[service.ServiceName]; ok {
+			warnings = append(warnings, fmt.Sprintf(messages.DuplicateServiceName, i, service.ServiceName, first))
+			continue
+		}
+		serviceNames[service.ServiceName] = i
+
+		if first, ok := packagePaths[service.PackagePath]; ok {
+			warnings = append(warnings, fmt.Sprintf(messages.DuplicateServicePackagePath,
+				i, service.ServiceName, service.PackagePath, first, c.Services[first].ServiceName))
+			continue
+		}
+		packagePaths[service.PackagePath] = i
+	}
+
+	// 無効な例外はもともと適用されないため、有効な例外同士でのみ包含を確認する
+	for i, exception := range c.PackageExceptions {
+		if !exception.Condition.Enabled {
+			continue
+		}
+		for j, earlier := range c.PackageExceptions[:i] {
+			if earlier.Condition.Enabled && patternShadows(earlier.Pattern, exception.Pattern) {
+				warnings = append(warnings, fmt.Sprintf(messages.ShadowedPackageException,
+					i, exception.Name, exception.Pattern, j, earlier.Name, earlier.Pattern))
+				break
+			}
+		}
+	}
+
+	return warnings
+}
+
+// patternShadows は narrower に一致するパスがすべて broader にも一致するかを判定する。
+// glob の実装（matchPattern）で両方のパターンを評価するため、narrower のワイルドカードを展開した代表的なパスを使い、
+// narrower 自身が一致するパスがすべて broader にも一致する場合に包含されているとみなす
+func patternShadows(broader, narrower string) bool {
+	matched := 0
+	for _, sample := range patternSamples(narrower) {
+		if !matchPattern(narrower, sample) {
+			continue
+		}
+		if !matchPattern(broader, sample) {
+			return false
+		}
+		matched++
+	}
+	return matched > 0
+}
+
+// patternSamples はパターンのワイルドカードを展開した代表的なパスを返す。
+// ** は 0〜2 個のセグメントに、* だけのセグメントは埋め草に、セグメント内の * は空文字列または埋め草に置き換える
+func patternSamples(pattern string) []string {
+	samples := [][]string{nil}
+	for _, segment := range strings.Split(pattern, "/") {
+		var alternatives [][]string
+		switch {
+		case segment == "**":
+			alternatives = [][]string{nil, {lintSampleSegment}, {lintSampleSegment, lintSampleSegment}}
+		case segment == "*":
+			alternatives = [][]string{{lintSampleSegment}}
+		case strings.Contains(segment, "*"):
+			alternatives = [][]string{
+				{strings.ReplaceAll(segment, "*", "")},
+				{strings.ReplaceAll(segment, "*", lintSampleSegment)},
+			}
+		default:
+			alternatives = [][]string{{segment}}
+		}
+
+		var expanded [][]string
+		for _, prefix := range samples {
+			for _, alternative := range alternatives {
+				path := append(append([]string{}, prefix...), alternative...)
+				expanded = append(expanded, path)
+			}
+		}
+		samples = expanded
+	}
+
+	paths := make([]string, 0, len(samples))
+	for _, segments := range samples {
+		if path := strings.Join(segments, "/"); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigLint(t *testing.T) {
+	service := func(name, packagePath string) ServiceRule {
+		return ServiceRule{
+			ServiceName:    name,
+			PackagePath:    packagePath,
+			CreationFuncs:  []string{"NewClient"},
+			CleanupMethods: []CleanupMethod{{Method: "Close", Required: true}},
+		}
+	}
+	exception := func(name, pattern string, enabled bool) PackageExceptionRule {
+		return PackageExceptionRule{
+			Name:      name,
+			Pattern:   pattern,
+			Condition: ExceptionCondition{Type: ExceptionTypeShortLived, Enabled: enabled},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string // 警告に含まれる文字列（順序どおり）
+	}{
+		{
+			name: "no_warnings",
+			config: Config{
+				Services:          []ServiceRule{service("spanner", "cloud.google.com/go/spanner"), service("storage", "cloud.google.com/go/storage")},
+				PackageExceptions: []PackageExceptionRule{exception("cmd", "*/cmd/*", true), exception("functions", "**/function/**", true)},
+			},
+		},
+		{
+			name: "duplicate_package_path",
+			config: Config{
+				Services: []ServiceRule{service("spanner", "cloud.google.com/go/spanner"), service("spanner-admin", "cloud.google.com/go/spanner")},
+			},
+			expected: []string{"service[1](spanner-admin): package path cloud.google.com/go/spanner is already used by service[0](spanner)"},
+		},
+		{
+			name: "duplicate_service_name",
+			config: Config{
+				Services: []ServiceRule{service("storage", "cloud.google.com/go/storage"), service("storage", "example.com/storage")},
+			},
+			expected: []string{"service[1](storage): service name is already defined by service[0]"},
+		},
+		{
+			name: "shadowed_pattern",
+			config: Config{
+				Services: []ServiceRule{service("spanner", "cloud.google.com/go/spanner")},
+				PackageExceptions: []PackageExceptionRule{
+					exception("internal", "**/internal/**", true),
+					exception("internal-functions", "**/internal/function/**", true),
+					exception("cmd", "*/cmd/*", true),
+					exception("tool", "example.com/cmd/tool", true),
+				},
+			},
+			expected: []string{
+				`package exception[1](internal-functions): pattern "**/internal/function/**" is shadowed by package exception[0](internal)`,
+				`package exception[3](tool): pattern "example.com/cmd/tool" is shadowed by package exception[2](cmd)`,
+			},
+		},
+		{
+			name: "disabled_exceptions_do_not_shadow",
+			config: Config{
+				Services: []ServiceRule{service("spanner", "cloud.google.com/go/spanner")},
+				PackageExceptions: []PackageExceptionRule{
+					exception("internal", "**/internal/**", false),
+					exception("internal-functions", "**/internal/function/**", true),
+					exception("broader-later", "**/internal/**", true),
+				},
+			},
+		},
+		{
+			name: "narrower_pattern_first",
+			config: Config{
+				Services: []ServiceRule{service("spanner", "cloud.google.com/go/spanner")},
+				PackageExceptions: []PackageExceptionRule{
+					exception("tool", "example.com/cmd/tool", true),
+					exception("cmd", "*/cmd/*", true),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := tt.config.Lint()
+			if len(warnings) != len(tt.expected) {
+				t.Fatalf("Expected %d warnings, got %d: %v", len(tt.expected), len(warnings), warnings)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(warnings[i], expected) {
+					t.Errorf("Warning %d = %q, want it to contain %q", i, warnings[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultConfigLint(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("DefaultConfig() failed: %v", err)
+	}
+	if warnings := cfg.Lint(); len(warnings) != 0 {
+		t.Errorf("Default configuration should have no lint warnings, got %v", warnings)
+	}
+}
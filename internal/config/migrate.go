@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentSchemaVersion は現在の設定ファイルのスキーマバージョン
+const CurrentSchemaVersion = 2
+
+// migrations[i] はスキーマバージョン i+1 の設定をバージョン i+2 に変換する
+var migrations = []func(*Config){
+	migrateToV2,
+}
+
+// EffectiveSchemaVersion は設定のスキーマバージョンを返す（schema_version 導入前の設定はバージョン 1）
+func (c *Config) EffectiveSchemaVersion() int {
+	if c.SchemaVersion == 0 {
+		return 1
+	}
+	return c.SchemaVersion
+}
+
+// Migrate は設定を現在のスキーマバージョンに変換する。
+// 古いバージョンに存在しなかったフィールドには、未設定時と同じ動作になるデフォルト値を明示的に設定する
+func (c *Config) Migrate() error {
+	version := c.EffectiveSchemaVersion()
+	if version < 0 {
+		return fmt.Errorf(messages.NegativeSchemaVersion, version)
+	}
+	if version > CurrentSchemaVersion {
+		return fmt.Errorf(messages.UnsupportedSchemaVersion, version, CurrentSchemaVersion)
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		migrations[version-1](c)
+	}
+	c.SchemaVersion = CurrentSchemaVersion
+	return nil
+}
+
+// migrateToV2 は analyze_init とサービスごとの field_assignment_exempt / applies_to_test_files を明示する
+func migrateToV2(c *Config) {
+	if c.AnalyzeInit == "" {
+		c.AnalyzeInit = AnalyzeInitWarn
+	}
+	for i := range c.Services {
+		service := &c.Services[i]
+		if service.FieldAssignmentExempt == nil {
+			exempt := true
+			service.FieldAssignmentExempt = &exempt
+		}
+		if service.AppliesToTestFiles == nil {
+			applies := true
+			service.AppliesToTestFiles = &applies
+		}
+	}
+}
+
+// MigrateConfigFile は oldPath の設定を現在のスキーマバージョンに変換し、newPath に書き出す（-gcpconfig-migrate）
+func MigrateConfigFile(oldPath, newPath string) error {
+	config, err := LoadConfig(oldPath)
+	if err != nil {
+		return err
+	}
+	if err := config.Migrate(); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf(messages.ConfigYAMLMarshalFailed, err)
+	}
+	if err := os.WriteFile(filepath.Clean(newPath), data, 0o600); err != nil {
+		return fmt.Errorf(messages.MigratedConfigWriteFailed, err)
+	}
+	return nil
+}
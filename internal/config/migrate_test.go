@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.yaml")
+	newPath := filepath.Join(dir, "new.yaml")
+	old := `services:
+  - service_name: spanner
+    package_path: cloud.google.com/go/spanner
+    creation_functions:
+      - NewClient
+    cleanup_methods:
+      - method: Close
+        required: true
+        description: Close client
+`
+	if err := os.WriteFile(oldPath, []byte(old), 0600); err != nil {
+		t.Fatalf("Failed to write old configuration: %v", err)
+	}
+
+	if err := MigrateConfigFile(oldPath, newPath); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	config, err := LoadConfig(newPath)
+	if err != nil {
+		t.Fatalf("Migrated configuration should load: %v", err)
+	}
+
+	if config.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", config.SchemaVersion, CurrentSchemaVersion)
+	}
+	if config.AnalyzeInit != AnalyzeInitWarn {
+		t.Errorf("AnalyzeInit = %q, want %q", config.AnalyzeInit, AnalyzeInitWarn)
+	}
+	service := config.GetService("spanner")
+	if service == nil {
+		t.Fatal("Migrated configuration lost the spanner service")
+	}
+	if service.FieldAssignmentExempt == nil || !*service.FieldAssignmentExempt {
+		t.Errorf("FieldAssignmentExempt should default to true, got %v", service.FieldAssignmentExempt)
+	}
+	if service.AppliesToTestFiles == nil || !*service.AppliesToTestFiles {
+		t.Errorf("AppliesToTestFiles should default to true, got %v", service.AppliesToTestFiles)
+	}
+	if len(service.CreationFuncs) != 1 || service.CleanupMethods[0].Method != "Close" {
+		t.Errorf("Migration should keep the existing rules, got %+v", service)
+	}
+}
+
+func TestConfig_Migrate(t *testing.T) {
+	t.Run("current version is unchanged", func(t *testing.T) {
+		config := &Config{SchemaVersion: CurrentSchemaVersion, Services: []ServiceRule{{ServiceName: "spanner"}}}
+		if err := config.Migrate(); err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if config.Services[0].AppliesToTestFiles != nil || config.AnalyzeInit != "" {
+			t.Errorf("A current configuration should not be rewritten, got %+v", config)
+		}
+	})
+
+	t.Run("newer version is rejected", func(t *testing.T) {
+		config := &Config{SchemaVersion: CurrentSchemaVersion + 1}
+		err := config.Migrate()
+		if err == nil || !strings.Contains(err.Error(), "schema_version") {
+			t.Errorf("Expected an unsupported schema_version error, got %v", err)
+		}
+	})
+
+	t.Run("negative version is rejected", func(t *testing.T) {
+		config := &Config{SchemaVersion: -1, Services: []ServiceRule{{ServiceName: "spanner"}}}
+		err := config.Migrate()
+		if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+			t.Errorf("Expected a negative schema_version error, got %v", err)
+		}
+	})
+}
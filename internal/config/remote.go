@@ -0,0 +1,207 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yukia3e/gcpclosecheck/internal/messages"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRemoteTimeout はリモート設定取得のタイムアウト
+const DefaultRemoteTimeout = 10 * time.Second
+
+// HTTPDoer は HTTP リクエストを送信するインターフェース（*http.Client が満たす）
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RemoteLoader は HTTP(S) で配布される設定ファイルを ETag 付きキャッシュを使って読み込む
+type RemoteLoader struct {
+	Client   HTTPDoer      // HTTP クライアント
+	CacheDir string        // キャッシュディレクトリ（空の場合はキャッシュしない）
+	Timeout  time.Duration // 1回の取得のタイムアウト（0 の場合はタイムアウトなし）
+}
+
+// NewRemoteLoader はユーザーキャッシュディレクトリを使う RemoteLoader を作成する
+func NewRemoteLoader() *RemoteLoader {
+	cacheDir := ""
+	if userCacheDir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(userCacheDir, "gcpclosecheck", "config")
+	}
+	return &RemoteLoader{
+		Client:   http.DefaultClient,
+		CacheDir: cacheDir,
+		Timeout:  DefaultRemoteTimeout,
+	}
+}
+
+// IsRemoteConfig は設定パスが HTTP(S) の URL かを判定する
+func IsRemoteConfig(configPath string) bool {
+	return strings.HasPrefix(configPath, "https://") || strings.HasPrefix(configPath, "http://")
+}
+
+// remoteConfigs は URL ごとの取得結果（*remoteConfigEntry）。
+// パッケージごとに設定を読み込むため、プロセス内では URL ごとに1回だけ取得する
+var remoteConfigs sync.Map
+
+// remoteConfigEntry は1つの URL の取得結果
+type remoteConfigEntry struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// LoadRemoteConfig はデフォルトの RemoteLoader で URL から設定を読み込む。
+// 取得はプロセス内で URL ごとに1回だけ行い、以降は同じ内容から新しい Config を返す
+func LoadRemoteConfig(url string) (*Config, error) {
+	value, _ := remoteConfigs.LoadOrStore(url, &remoteConfigEntry{})
+	entry := value.(*remoteConfigEntry)
+	entry.once.Do(func() {
+		entry.data, entry.err = NewRemoteLoader().loadData(context.Background(), url)
+	})
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return parseRemoteConfig(entry.data)
+}
+
+// Load は URL から設定を読み込む。
+// キャッシュがあれば If-None-Match で検証し、取得に失敗した場合はキャッシュを使う。
+// キャッシュもない場合はエラーを返す（フェイルクローズ）
+func (rl *RemoteLoader) Load(ctx context.Context, url string) (*Config, error) {
+	data, err := rl.loadData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return parseRemoteConfig(data)
+}
+
+// loadData は URL から YAML として解析できる設定の内容を取得する（取得できなければキャッシュを使う）
+func (rl *RemoteLoader) loadData(ctx context.Context, url string) ([]byte, error) {
+	cachedData, cachedETag := rl.readCache(url)
+
+	data, etag, err := rl.fetch(ctx, url, cachedETag, cachedData != nil)
+	fresh := err == nil && data != nil
+	if !fresh {
+		if cachedData == nil {
+			return nil, err
+		}
+		data, etag = cachedData, cachedETag
+	}
+
+	if _, err := parseRemoteConfig(data); err != nil {
+		return nil, err
+	}
+
+	// 解析できた設定のみキャッシュする（書き込み失敗は無視）
+	if fresh {
+		rl.writeCache(url, data, etag)
+	}
+
+	return data, nil
+}
+
+// parseRemoteConfig は取得した設定の内容を解析する
+func parseRemoteConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf(messages.ConfigYAMLParseFailed, err)
+	}
+	return &config, nil
+}
+
+// fetch は URL を取得する。304 Not Modified の場合は data と err がともに nil になる
+func (rl *RemoteLoader) fetch(ctx context.Context, url, etag string, hasCache bool) ([]byte, string, error) {
+	if rl.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rl.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf(messages.RemoteConfigFetchFailed, url, err)
+	}
+	if hasCache && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := rl.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf(messages.RemoteConfigFetchFailed, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf(messages.RemoteConfigFetchFailed, url, err)
+		}
+		return data, resp.Header.Get("ETag"), nil
+	case http.StatusNotModified:
+		if hasCache {
+			return nil, "", nil
+		}
+	}
+	return nil, "", fmt.Errorf(messages.RemoteConfigUnexpectedStatus, url, resp.Status)
+}
+
+// cachePath は URL に対応するキャッシュファイルのパスを返す
+func (rl *RemoteLoader) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(rl.CacheDir, hex.EncodeToString(sum[:])+".cache")
+}
+
+// readCache はキャッシュされた設定と ETag を読み込む（キャッシュがない場合は nil）。
+// キャッシュファイルは1行目が ETag、2行目以降が設定の内容
+func (rl *RemoteLoader) readCache(url string) ([]byte, string) {
+	if rl.CacheDir == "" {
+		return nil, ""
+	}
+	cached, err := os.ReadFile(rl.cachePath(url)) // #nosec G304 -- path is derived from a hash of the URL
+	if err != nil {
+		return nil, ""
+	}
+	etag, data, ok := strings.Cut(string(cached), "\n")
+	if !ok {
+		return nil, ""
+	}
+	return []byte(data), etag
+}
+
+// writeCache は取得した設定と ETag を1つのキャッシュファイルに保存する。
+// 並行して読み込まれても設定と ETag の組が食い違わないよう、一時ファイルから置き換える
+func (rl *RemoteLoader) writeCache(url string, data []byte, etag string) {
+	if rl.CacheDir == "" || strings.ContainsAny(etag, "\r\n") {
+		return
+	}
+	if err := os.MkdirAll(rl.CacheDir, 0o750); err != nil {
+		return
+	}
+
+	path := rl.cachePath(url)
+	tmp, err := os.CreateTemp(rl.CacheDir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(append([]byte(etag+"\n"), data...))
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const remoteTestYAML = `
+services:
+  - service_name: "spanner"
+    package_path: "cloud.google.com/go/spanner"
+    creation_functions: ["NewClient"]
+    cleanup_methods:
+      - method: "Close"
+        required: true
+        description: "Client connection close"
+`
+
+func TestIsRemoteConfig(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/rules.yaml": true,
+		"http://example.com/rules.yaml":  true,
+		"rules.yaml":                     false,
+		"/etc/gcpclosecheck/rules.yaml":  false,
+		"":                               false,
+	}
+	for path, expected := range tests {
+		if got := IsRemoteConfig(path); got != expected {
+			t.Errorf("IsRemoteConfig(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+}
+
+func TestRemoteLoader_Load(t *testing.T) {
+	var notModified int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rules.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(remoteTestYAML))
+	})
+	mux.HandleFunc("/invalid.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("services: [unterminated"))
+	})
+	mux.HandleFunc("/missing.yaml", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loader := &RemoteLoader{Client: server.Client(), CacheDir: t.TempDir(), Timeout: DefaultRemoteTimeout}
+	ctx := context.Background()
+
+	t.Run("valid config", func(t *testing.T) {
+		cfg, err := loader.Load(ctx, server.URL+"/rules.yaml")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Loaded config is invalid: %v", err)
+		}
+		if !cfg.HasService("spanner") {
+			t.Error("Expected spanner service in remote config")
+		}
+	})
+
+	t.Run("cached config revalidated with ETag", func(t *testing.T) {
+		if _, err := loader.Load(ctx, server.URL+"/rules.yaml"); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if atomic.LoadInt32(&notModified) != 1 {
+			t.Errorf("Expected a 304 revalidation, got %d", notModified)
+		}
+	})
+
+	t.Run("ETag and config cached in a single file", func(t *testing.T) {
+		entries, err := os.ReadDir(loader.CacheDir)
+		if err != nil {
+			t.Fatalf("Failed to read cache directory: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("Expected a single cache file without temporary files, got %v", entries)
+		}
+		data, etag := loader.readCache(server.URL + "/rules.yaml")
+		if etag != `"v1"` || string(data) != remoteTestYAML {
+			t.Errorf("readCache() = (%q, %q), want the fetched config and its ETag", data, etag)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		if _, err := loader.Load(ctx, server.URL+"/invalid.yaml"); err == nil {
+			t.Error("Expected error for invalid YAML")
+		}
+	})
+
+	t.Run("unexpected status without cache", func(t *testing.T) {
+		if _, err := loader.Load(ctx, server.URL+"/missing.yaml"); err == nil {
+			t.Error("Expected error for 404 response")
+		}
+	})
+
+	t.Run("unreachable server falls back to cache", func(t *testing.T) {
+		url := server.URL + "/rules.yaml"
+		server.Close()
+
+		cfg, err := loader.Load(ctx, url)
+		if err != nil {
+			t.Fatalf("Expected cached config, got error: %v", err)
+		}
+		if !cfg.HasService("spanner") {
+			t.Error("Expected spanner service in cached config")
+		}
+
+		uncached := &RemoteLoader{Client: http.DefaultClient, CacheDir: t.TempDir(), Timeout: DefaultRemoteTimeout}
+		if _, err := uncached.Load(ctx, url); err == nil {
+			t.Error("Expected error when server is unreachable and no cache exists")
+		}
+	})
+}
+
+func TestLoadRemoteConfig_FetchesOncePerURL(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(remoteTestYAML))
+	}))
+	defer server.Close()
+
+	url := server.URL + "/fetch-once.yaml"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg, err := LoadRemoteConfig(url)
+			if err != nil {
+				t.Errorf("LoadRemoteConfig failed: %v", err)
+				return
+			}
+			if !cfg.HasService("spanner") {
+				t.Error("Expected spanner service in remote config")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected the remote config to be fetched once, got %d requests", got)
+	}
+}
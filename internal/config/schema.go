@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaID はスキーマの識別子
+const SchemaID = "https://github.com/yukia3e/gcpclosecheck/config.schema.json"
+
+// schemaDescriptions は YAML フィールド名ごとの説明
+var schemaDescriptions = map[string]string{
+	"schema_version":             "Version of the configuration schema; files without it are treated as version 1 (upgrade with -gcpconfig-migrate)",
+	"services":                   "GCP service rules describing which calls create resources and how they are released",
+	"service_name":               "Short name of the GCP service (e.g. spanner, storage)",
+	"package_path":               "Go import path of the client package",
+	"creation_functions":         "Functions or methods whose results must be released",
+	"cleanup_methods":            "Methods that release resources created by this service",
+	"method":                     "Name of the cleanup method (e.g. Close, Stop)",
+	"required":                   "Whether a missing call to this method is reported",
+	"description":                "Human readable description",
+	"error_significant":          "Whether the error returned by the cleanup method must not be ignored",
+	"applies_to":                 "Creation functions this method applies to (empty means the whole service)",
+	"required_after":             "Methods that make the cleanup necessary once called on the resource (e.g. Publish on a pubsub Topic); empty means always necessary",
+	"requires_context":           "Whether the cleanup method takes a context.Context argument (e.g. Shutdown(ctx)); suggested fixes pass a context in scope",
+	"field_assignment_exempt":    "Whether assigning a resource to a struct field exempts it from cleanup checks (default true)",
+	"applies_to_test_files":      "Whether resources created in _test.go files are checked for this service (default true)",
+	"lifetime":                   "Resource lifetime: app (shared and closed on shutdown; struct field and package variable assignments are exempt) or request (released per request; struct field assignments are reported). Overrides field_assignment_exempt",
+	"context_cancelled_cleanup":  "Whether a resource created with a derived context whose cancel is deferred counts as released (e.g. streaming RPCs torn down on cancellation)",
+	"pool_return_method":         "Method of the pool that returns a resource borrowed from a sync.Pool (default Put)",
+	"service_type":               "Kind of client: admin for admin API clients (e.g. Spanner DatabaseAdminClient), which are still checked in short_lived packages when the creating function operates on them in a loop or many times",
+	"package_exceptions":         "Package path patterns excluded from analysis",
+	"name":                       "Identifier of the exception rule",
+	"pattern":                    "Glob pattern matched against package paths (e.g. **/cmd/**)",
+	"condition":                  "Condition under which the exception applies",
+	"type":                       "Exception type",
+	"enabled":                    "Whether this exception is active",
+	"analyze_init":               "How resources created in init functions are reported",
+	"generated_file_policy":      "How files with a generated-code header (e.g. mocks) are reported; unset reports them like any other file",
+	"defer_registration_methods": "Methods that register a cleanup func to run later (e.g. Defer for stack.Defer(client.Close))",
+	"package_path_aliases":       "Package path prefixes of forks or mirrors mapped to a service name (e.g. example.com/vendored/spanner: spanner)",
+	"pure_packages":              "Package path patterns of packages that never own resources; creations there are tracked but not reported",
+	"closure_managed_helpers":    "User-defined helpers that release the resource they pass to a closure (e.g. withTxn)",
+	"function":                   "Name of the helper function or method",
+	"param":                      "Position (0-based) of the closure parameter that receives the managed resource",
+}
+
+// schemaEnums は YAML フィールド名ごとの許容値
+var schemaEnums = map[string][]string{
+	"type":                  validExceptionTypes,
+	"analyze_init":          validAnalyzeInitModes,
+	"generated_file_policy": validGeneratedFilePolicies,
+	"lifetime":              validLifetimes,
+	"service_type":          validServiceTypes,
+}
+
+// Schema は Config 構造体をリフレクションで走査し、設定ファイルの JSON Schema を返す
+func Schema() ([]byte, error) {
+	schema := schemaForType(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "gcpclosecheck configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaForType は型に対応するスキーマを生成する
+func schemaForType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, omitempty := yamlFieldName(field)
+			if name == "" {
+				continue
+			}
+			prop := schemaForType(field.Type)
+			if desc, ok := schemaDescriptions[name]; ok {
+				prop["description"] = desc
+			}
+			if enum, ok := schemaEnums[name]; ok {
+				prop["enum"] = enum
+			}
+			properties[name] = prop
+			if !omitempty {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaForType(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem()),
+		}
+	case reflect.Ptr:
+		return schemaForType(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// yamlFieldName は yaml タグからフィールド名と omitempty の有無を取得する
+func yamlFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() failed: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v\n%s", err, data)
+	}
+
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Schema should have top-level properties: %s", data)
+	}
+	for _, key := range []string{"services", "package_exceptions", "analyze_init"} {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("Schema should describe %q", key)
+		}
+	}
+
+	services := properties["services"].(map[string]interface{})
+	service := services["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, key := range []string{"service_name", "package_path", "creation_functions", "cleanup_methods", "lifetime"} {
+		if _, ok := service[key]; !ok {
+			t.Errorf("Service schema should describe %q", key)
+		}
+	}
+
+	cleanup := service["cleanup_methods"].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, key := range []string{"method", "required", "description", "error_significant", "requires_context", "applies_to"} {
+		prop, ok := cleanup[key].(map[string]interface{})
+		if !ok {
+			t.Errorf("Cleanup method schema should describe %q", key)
+			continue
+		}
+		if prop["description"] == "" || prop["description"] == nil {
+			t.Errorf("Cleanup method field %q should have a description", key)
+		}
+	}
+
+	aliases := properties["package_path_aliases"].(map[string]interface{})
+	if aliases["type"] != "object" {
+		t.Errorf("package_path_aliases should be an object, got %v", aliases["type"])
+	}
+
+	analyzeInit := properties["analyze_init"].(map[string]interface{})
+	if enum, ok := analyzeInit["enum"].([]interface{}); !ok || len(enum) != len(validAnalyzeInitModes) {
+		t.Errorf("analyze_init should list %v as enum, got %v", validAnalyzeInitModes, analyzeInit["enum"])
+	}
+}
@@ -0,0 +1,77 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DiagnosticDiff は同じパッケージを2つの設定で解析した結果の差分（-gcpdiff）
+type DiagnosticDiff struct {
+	// Added は新しい設定でのみ報告された診断
+	Added []Diagnostic
+	// Removed は古い設定でのみ報告された診断
+	Removed []Diagnostic
+}
+
+// Diff は before と after の解析結果を比較する。診断は位置とメッセージで照合する
+func Diff(before, after []PackageResult) DiagnosticDiff {
+	beforeSet := diagnosticsByKey(before)
+	afterSet := diagnosticsByKey(after)
+
+	var diff DiagnosticDiff
+	for key, diag := range afterSet {
+		if _, ok := beforeSet[key]; !ok {
+			diff.Added = append(diff.Added, diag)
+		}
+	}
+	for key, diag := range beforeSet {
+		if _, ok := afterSet[key]; !ok {
+			diff.Removed = append(diff.Removed, diag)
+		}
+	}
+	sortDiagnostics(diff.Added)
+	sortDiagnostics(diff.Removed)
+	return diff
+}
+
+// Write は差分を "+"（追加）、"-"（削除）付きの行と件数で出力する
+func (d DiagnosticDiff) Write(w io.Writer) error {
+	for _, diag := range d.Added {
+		if _, err := fmt.Fprintf(w, "+ %s\n", diag); err != nil {
+			return err
+		}
+	}
+	for _, diag := range d.Removed {
+		if _, err := fmt.Fprintf(w, "- %s\n", diag); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d removed\n", len(d.Added), len(d.Removed))
+	return err
+}
+
+// diagnosticsByKey は全パッケージの診断を照合用のキーで索引する
+func diagnosticsByKey(results []PackageResult) map[string]Diagnostic {
+	set := make(map[string]Diagnostic)
+	for _, result := range results {
+		for _, diag := range result.Diagnostics {
+			set[diag.String()] = diag
+		}
+	}
+	return set
+}
+
+// sortDiagnostics は診断をファイル・行・列の順に並べる
+func sortDiagnostics(diags []Diagnostic) {
+	sort.Slice(diags, func(i, j int) bool {
+		a, b := diags[i].Position, diags[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
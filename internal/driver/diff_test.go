@@ -0,0 +1,37 @@
+package driver
+
+import (
+	"bytes"
+	"go/token"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	diag := func(file string, line int, message string) Diagnostic {
+		return Diagnostic{Position: token.Position{Filename: file, Line: line, Column: 2}, Message: message}
+	}
+	before := []PackageResult{
+		{PkgPath: "example.com/a", Diagnostics: []Diagnostic{diag("a.go", 3, "spanner leak"), diag("a.go", 9, "stale")}},
+	}
+	after := []PackageResult{
+		{PkgPath: "example.com/a", Diagnostics: []Diagnostic{diag("a.go", 3, "spanner leak"), diag("a.go", 12, "db leak")}},
+		{PkgPath: "example.com/b", Diagnostics: []Diagnostic{diag("b.go", 1, "db leak")}},
+	}
+
+	diff := Diff(before, after)
+	if len(diff.Added) != 2 || diff.Added[0].Position.Filename != "a.go" || diff.Added[1].Position.Filename != "b.go" {
+		t.Errorf("Added = %v, want the two db leaks sorted by file", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Message != "stale" {
+		t.Errorf("Removed = %v, want the stale diagnostic", diff.Removed)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "+ a.go:12:2: db leak\n+ b.go:1:2: db leak\n- a.go:9:2: stale\n2 added, 1 removed\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf.String(), want)
+	}
+}
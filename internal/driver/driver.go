@@ -0,0 +1,250 @@
+// Package driver は singlechecker を使わずに複数パッケージを解析するドライバ。
+// パッケージ横断の集計など、singlechecker では扱えない処理が必要な場合に使用する
+package driver
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadMode はパッケージ読み込み時に必要な情報
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedTypesSizes |
+	packages.NeedImports | packages.NeedDeps | packages.NeedModule
+
+// Options はドライバの動作オプション
+type Options struct {
+	// Tests はテストファイルも解析対象に含めるか
+	Tests bool
+	// Concurrency は同時に解析するパッケージ数（0 以下の場合は GOMAXPROCS）
+	Concurrency int
+	// Summary が nil でない場合、解析結果を集計する（複数のゴルーチンから更新される）
+	Summary *Summary
+	// FactsOnly が nil でない場合、解析対象が import するパッケージには analyzer の代わりにこれを実行し、Fact のみを得る
+	// （nil の場合は analyzer を実行して診断を捨てる。集計など analyzer の副作用は依存パッケージにも及ぶ）
+	FactsOnly func(*analysis.Pass) error
+}
+
+// Diagnostic は位置を解決済みの診断
+type Diagnostic struct {
+	Position token.Position
+	Category string
+	Message  string
+}
+
+// String は go vet と同じ "file:line:col: message" 形式の文字列を返す
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Position, d.Message)
+}
+
+// PackageResult は1パッケージの解析結果
+type PackageResult struct {
+	PkgPath     string
+	Files       []string
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+// Run は patterns に一致するパッケージを読み込み、analyzer を並行に実行する
+func Run(patterns []string, a *analysis.Analyzer, opts Options) ([]PackageResult, error) {
+	cfg := &packages.Config{Mode: loadMode, Tests: opts.Tests}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	// テストバイナリ用に生成されたパッケージは解析しない
+	var targets []*packages.Package
+	for _, pkg := range pkgs {
+		if !strings.HasSuffix(pkg.PkgPath, ".test") {
+			targets = append(targets, pkg)
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	// 解析対象が import するメインモジュールのパッケージは、Fact を得るためだけに解析する（診断は報告しない）。
+	// 標準ライブラリやサードパーティのモジュールは解析しない
+	isTarget := make(map[*packages.Package]bool, len(targets))
+	for _, pkg := range targets {
+		isTarget[pkg] = true
+	}
+	var deps []*packages.Package
+	packages.Visit(targets, nil, func(pkg *packages.Package) {
+		if !isTarget[pkg] && pkg.Module != nil && pkg.Module.Main && len(pkg.Syntax) > 0 && len(pkg.Errors) == 0 {
+			deps = append(deps, pkg)
+		}
+	})
+
+	// 依存先の Fact を参照できるよう、import するパッケージの解析の完了を待ってから解析する
+	done := make(map[*packages.Package]chan struct{}, len(targets)+len(deps))
+	for _, pkg := range append(deps, targets...) {
+		done[pkg] = make(chan struct{})
+	}
+
+	results := make([]PackageResult, len(targets))
+	dedup := newDiagnosticSet()
+	facts := newFactStore()
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	schedule := func(pkg *packages.Package, analyze func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[pkg])
+			for _, imp := range pkg.Imports {
+				if ch, ok := done[imp]; ok {
+					<-ch
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			analyze()
+		}()
+	}
+	for _, pkg := range deps {
+		schedule(pkg, func() {
+			pass := newPass(pkg, a, facts, func(analysis.Diagnostic) {})
+			if opts.FactsOnly != nil {
+				_ = opts.FactsOnly(pass)
+				return
+			}
+			_, _ = a.Run(pass)
+		})
+	}
+	for i, pkg := range targets {
+		schedule(pkg, func() {
+			results[i] = runPackage(pkg, a, dedup, facts, opts.Summary)
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].PkgPath < results[j].PkgPath })
+	return results, nil
+}
+
+// runPackage は1パッケージに analyzer を実行する
+func runPackage(pkg *packages.Package, a *analysis.Analyzer, dedup *diagnosticSet, facts *factStore, summary *Summary) PackageResult {
+	result := PackageResult{PkgPath: pkg.PkgPath, Files: pkg.GoFiles}
+	if summary != nil {
+		summary.AddPackage(pkg.PkgPath, pkg.GoFiles)
+	}
+
+	if len(pkg.Errors) > 0 {
+		result.Err = pkg.Errors[0]
+		return result
+	}
+
+	pass := newPass(pkg, a, facts, func(d analysis.Diagnostic) {
+		diag := Diagnostic{
+			Position: pkg.Fset.Position(d.Pos),
+			Category: d.Category,
+			Message:  d.Message,
+		}
+		// テスト有無でパッケージが重複して読み込まれるため、同一の診断は一度だけ報告する
+		if !dedup.add(diag) {
+			return
+		}
+		result.Diagnostics = append(result.Diagnostics, diag)
+		if summary != nil {
+			summary.AddDiagnostic(diag)
+		}
+	})
+
+	if _, err := a.Run(pass); err != nil {
+		result.Err = err
+	}
+	return result
+}
+
+// newPass は1パッケージを解析する Pass を作成する。Fact は facts を通じてパッケージ間で共有する
+func newPass(pkg *packages.Package, a *analysis.Analyzer, facts *factStore, report func(analysis.Diagnostic)) *analysis.Pass {
+	return &analysis.Pass{
+		Analyzer:         a,
+		Fset:             pkg.Fset,
+		Files:            pkg.Syntax,
+		Pkg:              pkg.Types,
+		TypesInfo:        pkg.TypesInfo,
+		TypesSizes:       pkg.TypesSizes,
+		TypeErrors:       pkg.TypeErrors,
+		ResultOf:         make(map[*analysis.Analyzer]interface{}),
+		ImportObjectFact: facts.importObjectFact,
+		ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+			if obj.Pkg() != pkg.Types {
+				panic(fmt.Sprintf("%s: fact exported for object %s of another package", a.Name, obj))
+			}
+			facts.exportObjectFact(obj, fact)
+		},
+		Report: report,
+	}
+}
+
+// diagnosticSet はパッケージ間で共有される報告済み診断の集合
+type diagnosticSet struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newDiagnosticSet() *diagnosticSet {
+	return &diagnosticSet{seen: make(map[string]bool)}
+}
+
+// add は未報告の診断であれば記録して true を返す
+func (s *diagnosticSet) add(d Diagnostic) bool {
+	key := d.String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+// factStore はパッケージ間で共有されるオブジェクトの Fact
+type factStore struct {
+	mu    sync.Mutex
+	facts map[types.Object][]analysis.Fact
+}
+
+func newFactStore() *factStore {
+	return &factStore{facts: make(map[types.Object][]analysis.Fact)}
+}
+
+// importObjectFact は obj に fact と同じ型の Fact があれば fact にコピーして true を返す
+func (s *factStore) importObjectFact(obj types.Object, fact analysis.Fact) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.facts[obj] {
+		if reflect.TypeOf(stored) == reflect.TypeOf(fact) {
+			reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(stored).Elem())
+			return true
+		}
+	}
+	return false
+}
+
+// exportObjectFact は obj の Fact を記録する（同じ型の Fact は上書きする）
+func (s *factStore) exportObjectFact(obj types.Object, fact analysis.Fact) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, stored := range s.facts[obj] {
+		if reflect.TypeOf(stored) == reflect.TypeOf(fact) {
+			s.facts[obj][i] = fact
+			return
+		}
+	}
+	s.facts[obj] = append(s.facts[obj], fact)
+}
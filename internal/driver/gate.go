@@ -0,0 +1,27 @@
+package driver
+
+// Gate は全パッケージの診断数に上限を設ける CI 用のゲート
+type Gate struct {
+	// MaxTotal は許容する診断数の上限（これを超えると失敗とする）
+	MaxTotal int
+	// Include が nil でない場合、true を返した診断のみを数える（重大度による絞り込み等）
+	Include func(Diagnostic) bool
+}
+
+// Count は全パッケージの結果からゲートの対象となる診断数を数える
+func (g Gate) Count(results []PackageResult) int {
+	count := 0
+	for _, result := range results {
+		for _, diag := range result.Diagnostics {
+			if g.Include == nil || g.Include(diag) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Exceeded は診断数が上限を超えているかを判定する
+func (g Gate) Exceeded(count int) bool {
+	return count > g.MaxTotal
+}
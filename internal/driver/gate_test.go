@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestGate_Threshold(t *testing.T) {
+	diag := func(category string, line int) Diagnostic {
+		return Diagnostic{Position: token.Position{Filename: "a.go", Line: line}, Category: category}
+	}
+	// 5件の診断（うち error 3件、warning 2件）
+	results := []PackageResult{
+		{PkgPath: "example.com/a", Diagnostics: []Diagnostic{diag("error", 1), diag("warning", 2), diag("error", 3)}},
+		{PkgPath: "example.com/b", Diagnostics: []Diagnostic{diag("error", 1), diag("warning", 2)}},
+		{PkgPath: "example.com/c"},
+	}
+	errorsOnly := func(d Diagnostic) bool { return d.Category == "error" }
+
+	tests := []struct {
+		name      string
+		gate      Gate
+		wantCount int
+		wantFail  bool
+	}{
+		{"below limit", Gate{MaxTotal: 6}, 5, false},
+		{"at limit", Gate{MaxTotal: 5}, 5, false},
+		{"above limit", Gate{MaxTotal: 4}, 5, true},
+		{"zero tolerance", Gate{MaxTotal: 0}, 5, true},
+		{"errors only at limit", Gate{MaxTotal: 3, Include: errorsOnly}, 3, false},
+		{"errors only above limit", Gate{MaxTotal: 2, Include: errorsOnly}, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := tt.gate.Count(results)
+			if count != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", count, tt.wantCount)
+			}
+			if got := tt.gate.Exceeded(count); got != tt.wantFail {
+				t.Errorf("Exceeded(%d) = %v, want %v", count, got, tt.wantFail)
+			}
+		})
+	}
+}
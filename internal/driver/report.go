@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// 診断の出力形式（-gcpreport）
+const (
+	ReportText = "text" // go vet と同じ "file:line:col: message" 形式
+	ReportJSON = "json" // 診断の JSON 配列
+)
+
+// ReportFormats は有効な出力形式のリスト
+var ReportFormats = []string{ReportText, ReportJSON}
+
+// IsValidReportFormat は出力形式として有効な値かを判定する
+func IsValidReportFormat(format string) bool {
+	for _, f := range ReportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonDiagnostic は JSON 形式で出力する1件の診断
+type jsonDiagnostic struct {
+	Package  string `json:"package"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Category string `json:"category,omitempty"`
+	Message  string `json:"message"`
+}
+
+// WriteReport は全パッケージの診断を format の形式で w に書き出す
+func WriteReport(w io.Writer, format string, results []PackageResult) error {
+	switch format {
+	case ReportText:
+		for _, result := range results {
+			for _, diag := range result.Diagnostics {
+				if _, err := fmt.Fprintln(w, diag); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case ReportJSON:
+		// 診断がない場合も null ではなく空の配列を出力する
+		diagnostics := []jsonDiagnostic{}
+		for _, result := range results {
+			for _, diag := range result.Diagnostics {
+				diagnostics = append(diagnostics, jsonDiagnostic{
+					Package:  result.PkgPath,
+					File:     diag.Position.Filename,
+					Line:     diag.Position.Line,
+					Column:   diag.Position.Column,
+					Category: diag.Category,
+					Message:  diag.Message,
+				})
+			}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diagnostics)
+	}
+	return fmt.Errorf("unknown report format %q (valid formats: %v)", format, ReportFormats)
+}
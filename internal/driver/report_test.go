@@ -0,0 +1,60 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	results := []PackageResult{
+		{PkgPath: "example.com/a", Diagnostics: []Diagnostic{{
+			Position: token.Position{Filename: "a.go", Line: 3, Column: 2},
+			Category: "resource-leak",
+			Message:  "spanner leak",
+		}}},
+		{PkgPath: "example.com/b"},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, ReportText, results); err != nil {
+			t.Fatalf("WriteReport() error = %v", err)
+		}
+		if want := "a.go:3:2: spanner leak\n"; buf.String() != want {
+			t.Errorf("WriteReport() = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, ReportJSON, results); err != nil {
+			t.Fatalf("WriteReport() error = %v", err)
+		}
+		var got []jsonDiagnostic
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+		}
+		want := jsonDiagnostic{Package: "example.com/a", File: "a.go", Line: 3, Column: 2, Category: "resource-leak", Message: "spanner leak"}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("WriteReport() = %+v, want [%+v]", got, want)
+		}
+	})
+
+	t.Run("json without diagnostics", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, ReportJSON, nil); err != nil {
+			t.Fatalf("WriteReport() error = %v", err)
+		}
+		if want := "[]\n"; buf.String() != want {
+			t.Errorf("WriteReport() = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := WriteReport(&bytes.Buffer{}, "sarif", results); err == nil {
+			t.Error("Expected an error for an unknown format")
+		}
+	})
+}
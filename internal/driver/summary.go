@@ -0,0 +1,119 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// DefaultTopFiles はサマリーに表示する診断の多いファイル数の既定値
+const DefaultTopFiles = 10
+
+// FileCount はファイルごとの診断数
+type FileCount struct {
+	File  string
+	Count int
+}
+
+// Summary は複数パッケージにまたがる解析結果の集計。
+// パッケージは並行に解析されるため、全メソッドはゴルーチンセーフ
+type Summary struct {
+	mu          sync.Mutex
+	packages    map[string]bool
+	files       map[string]bool
+	fileCounts  map[string]int
+	diagnostics int
+}
+
+// NewSummary は空の Summary を作成する
+func NewSummary() *Summary {
+	return &Summary{
+		packages:   make(map[string]bool),
+		files:      make(map[string]bool),
+		fileCounts: make(map[string]int),
+	}
+}
+
+// AddPackage は解析したパッケージとそのファイルを記録する
+// テスト有無で同じパッケージ・ファイルが複数回渡されても一度だけ数える
+func (s *Summary) AddPackage(pkgPath string, files []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packages[pkgPath] = true
+	for _, f := range files {
+		s.files[f] = true
+	}
+}
+
+// AddDiagnostic は診断を1件記録する
+func (s *Summary) AddDiagnostic(d Diagnostic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.diagnostics++
+	s.fileCounts[d.Position.Filename]++
+}
+
+// Packages は解析したパッケージ数を返す
+func (s *Summary) Packages() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.packages)
+}
+
+// Files は解析したファイル数を返す
+func (s *Summary) Files() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// Diagnostics は診断の総数を返す
+func (s *Summary) Diagnostics() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.diagnostics
+}
+
+// TopFiles は診断数の多い順に最大 n 件のファイルを返す（同数の場合はファイル名順）
+func (s *Summary) TopFiles(n int) []FileCount {
+	s.mu.Lock()
+	counts := make([]FileCount, 0, len(s.fileCounts))
+	for file, count := range s.fileCounts {
+		counts = append(counts, FileCount{File: file, Count: count})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].File < counts[j].File
+	})
+	if n >= 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Write は集計結果を人が読める形式で出力する
+func (s *Summary) Write(w io.Writer, top int) error {
+	if _, err := fmt.Fprintf(w, "gcpclosecheck summary: %d packages, %d files, %d diagnostics\n",
+		s.Packages(), s.Files(), s.Diagnostics()); err != nil {
+		return err
+	}
+
+	files := s.TopFiles(top)
+	if len(files) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "Top files:"); err != nil {
+		return err
+	}
+	for _, fc := range files {
+		if _, err := fmt.Fprintf(w, "  %4d  %s\n", fc.Count, fc.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
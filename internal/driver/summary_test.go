@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSummary_TwoPackagesConcurrently(t *testing.T) {
+	summary := NewSummary()
+
+	packages := map[string]map[string]int{
+		"example.com/a": {"/src/a/a.go": 1, "/src/a/a_test.go": 0},
+		"example.com/b": {"/src/b/b.go": 3, "/src/b/util.go": 2},
+	}
+
+	var wg sync.WaitGroup
+	for pkgPath, files := range packages {
+		// テスト有無のバリアントを模して同じパッケージを2回記録する
+		for variant := 0; variant < 2; variant++ {
+			wg.Add(1)
+			go func(pkgPath string, files map[string]int) {
+				defer wg.Done()
+				var names []string
+				for name := range files {
+					names = append(names, name)
+				}
+				summary.AddPackage(pkgPath, names)
+			}(pkgPath, files)
+		}
+		for file, count := range files {
+			for i := 0; i < count; i++ {
+				wg.Add(1)
+				go func(file string, line int) {
+					defer wg.Done()
+					summary.AddDiagnostic(Diagnostic{
+						Position: token.Position{Filename: file, Line: line},
+						Message:  fmt.Sprintf("leak %d", line),
+					})
+				}(file, i+1)
+			}
+		}
+	}
+	wg.Wait()
+
+	if got := summary.Packages(); got != 2 {
+		t.Errorf("Packages() = %d, want 2", got)
+	}
+	if got := summary.Files(); got != 4 {
+		t.Errorf("Files() = %d, want 4", got)
+	}
+	if got := summary.Diagnostics(); got != 6 {
+		t.Errorf("Diagnostics() = %d, want 6", got)
+	}
+
+	top := summary.TopFiles(2)
+	want := []FileCount{{"/src/b/b.go", 3}, {"/src/b/util.go", 2}}
+	if len(top) != len(want) {
+		t.Fatalf("TopFiles(2) = %v, want %v", top, want)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("TopFiles(2)[%d] = %v, want %v", i, top[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := summary.Write(&buf, DefaultTopFiles); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	out := buf.String()
+	for _, expected := range []string{"2 packages, 4 files, 6 diagnostics", "/src/b/b.go", "/src/a/a.go"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Summary output should contain %q, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "a_test.go") {
+		t.Errorf("Files without diagnostics should not be listed, got:\n%s", out)
+	}
+}
+
+func TestDiagnosticSet_Dedup(t *testing.T) {
+	set := newDiagnosticSet()
+	d := Diagnostic{Position: token.Position{Filename: "a.go", Line: 1, Column: 2}, Message: "leak"}
+	if !set.add(d) {
+		t.Error("First diagnostic should be added")
+	}
+	if set.add(d) {
+		t.Error("Duplicate diagnostic should not be added")
+	}
+}
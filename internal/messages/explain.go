@@ -0,0 +1,375 @@
+package messages
+
+import "sort"
+
+// Rule explanations - printed by -gcpexplain, keyed by the diagnostic category (rule ID)
+var ruleExplanations = map[string]string{
+	"resource-leak": `resource-leak: GCP resource is never released
+
+GCP clients, transactions and iterators hold connections, sessions or
+goroutines until their cleanup method (Close, Stop, ...) is called. If the
+call is missing, those are leaked for the life of the process.
+
+Fix: call the cleanup method with defer right after the error check.
+
+    client, err := spanner.NewClient(ctx, db)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+
+    iter := client.Single().Query(ctx, stmt)
+    defer iter.Stop()
+`,
+	"context-leak": `context-leak: context cancel function is never called
+
+context.WithCancel, WithTimeout and WithDeadline return a cancel function
+that releases the timer and the reference from the parent context. Not
+calling it leaks them until the parent is cancelled.
+
+Fix: defer the cancel function immediately.
+
+    ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+`,
+	"defer-in-loop": `defer-in-loop: a resource created in a loop is released by a defer in the loop
+
+Deferred calls run when the surrounding function returns, not at the end
+of each iteration. Every iteration therefore leaves another writer, reader
+or client open until the whole loop has finished, which can exhaust
+connections or memory for long loops. Enabled with -gcpdefer-in-loop.
+
+Fix: release the resource within the iteration, for example by moving the
+loop body into its own function.
+
+    for _, name := range names {
+        if err := upload(ctx, bucket, name); err != nil {
+            return err
+        }
+    }
+
+    func upload(ctx context.Context, bucket *storage.BucketHandle, name string) error {
+        w := bucket.Object(name).NewWriter(ctx)
+        defer w.Close()
+        ...
+    }
+`,
+	"cleanup-error-ignored": `cleanup-error-ignored: the error of a significant cleanup method is discarded
+
+For some resources the cleanup method reports whether the work succeeded:
+storage.Writer.Close returns the error of the upload, and the object is not
+written if it fails. Cleanup methods marked error_significant in the
+configuration must not be deferred in a way that discards their error
+(defer w.Close() or defer func() { _ = w.Close() }()). Client Close errors
+are not significant by default. Enabled with -gcpcleanup-error.
+
+Fix: check the error, or join it into the named result in a deferred closure.
+
+    func upload(ctx context.Context, bucket *storage.BucketHandle, name string) (err error) {
+        w := bucket.Object(name).NewWriter(ctx)
+        defer func() {
+            if closeErr := w.Close(); closeErr != nil && err == nil {
+                err = closeErr
+            }
+        }()
+        ...
+    }
+`,
+	"exit-skips-defer": `exit-skips-defer: os.Exit or log.Fatal prevents a deferred cleanup from running
+
+os.Exit terminates the process immediately, and log.Fatal calls it after
+logging. Deferred calls registered before that point never run, so a
+storage writer is never committed and buffered Pub/Sub messages are never
+published. Enabled with -gcpexit-skips-defer. Packages under cmd/ are
+excluded by the default package_exceptions; use -gcpstrict to check them.
+TestMain is always checked because it normally ends with os.Exit(m.Run());
+there, close shared resources explicitly before calling os.Exit.
+
+Fix: return the error to main and exit only after the defers have run.
+
+    func main() {
+        if err := run(context.Background()); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    func run(ctx context.Context) error {
+        client, err := storage.NewClient(ctx)
+        if err != nil {
+            return err
+        }
+        defer client.Close()
+        ...
+    }
+`,
+	"creation-error-ignored": `creation-error-ignored: a resource is used before the error from its creation is checked
+
+Constructors such as storage.NewClient return a nil client together with a
+non-nil error. Discarding the error with _ or using the resource before
+checking it turns a failed creation into a nil pointer panic, often inside
+the deferred Close. Enabled with -gcpcreation-error.
+
+Fix: check the error before using or deferring the cleanup of the resource.
+
+    client, err := storage.NewClient(ctx)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+`,
+	"conditional-cleanup": `conditional-cleanup: a cleanup is deferred only under a boolean guard
+
+A defer inside if !closed { ... } registers the cleanup only when the flag
+allows it; on the other path the resource is never released. The same
+applies to a deferred closure whose body closes the resource only under
+such a condition (defer func() { if !closed { c.Close() } }()). This is often
+intentional in idempotent-close wrappers, so it is reported as a warning
+instead of a resource leak. Conditions that compare values, such as
+err == nil, are not reported. Enabled with -gcpconditional-defer.
+
+Fix: defer the cleanup unconditionally and make the close itself idempotent.
+
+    client, err := storage.NewClient(ctx)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+`,
+	"struct-resource-not-closed": `struct-resource-not-closed: a struct field holds a resource no method closes
+
+Resources stored in a struct field by a constructor are treated as escaped,
+so the constructor is not reported. The struct then owns the resource, and
+one of its methods (usually Close) must release the field; otherwise every
+instance leaks it. Only fields that some function in the package assigns a
+created resource to are checked; injected dependencies are owned by the
+caller. Embedded fields are not checked because their cleanup method is
+promoted to the struct. Enabled with -gcpstruct-lifecycle.
+
+Fix: release the field in the struct's cleanup method.
+
+    type Repository struct {
+        client *spanner.Client
+    }
+
+    func (r *Repository) Close() {
+        r.client.Close()
+    }
+`,
+	"resource-slice-not-closed": `resource-slice-not-closed: a slice of resources is created but no element is closed
+
+A call such as makeClients(ctx, projects...) returning []*spanner.Client
+hands over one resource per element. Elements cannot be tracked one by one,
+so this heuristic warns when the function neither closes an element (in a
+range loop or by index) nor hands the slice off by returning it, passing it
+to another function or storing it. Enabled with -gcpresource-slice.
+
+Fix: release every element, typically in a ranged defer.
+
+    clients, err := makeClients(ctx, projects...)
+    if err != nil {
+        return err
+    }
+    for _, c := range clients {
+        defer c.Close()
+    }
+`,
+	"panic-skips-cleanup": `panic-skips-cleanup: a direct cleanup call can be skipped by a recovered panic
+
+In a function that recovers panics with a deferred recover(), a panic
+between the creation of a resource and a plain (non-deferred) call to its
+cleanup method skips that call, and execution continues with the resource
+still open. Only deferred calls run while a panic unwinds. The calls treated
+as panicking are panic, log.Panic/Panicf/Panicln and Must/must helpers such
+as regexp.MustCompile.
+
+Fix: defer the cleanup right after checking the creation error.
+
+    client, err := spanner.NewClient(ctx, db)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+`,
+	"reassigned-element-cleanup": `reassigned-element-cleanup: a deferred cleanup closes a reassigned map or slice element
+
+When a resource is stored in an element with a constant index (m["k"] = client
+or clients[0], _ = storage.NewClient(ctx)) and released through that element
+(defer m["k"].Close()), reassigning the element or its container before the
+cleanup is evaluated makes the defer close the later value, and the resource
+stored first leaks. A plain defer evaluates the element when the defer
+statement runs; a deferred closure evaluates it when the function returns.
+This check is conservative and reported as a warning.
+
+Fix: defer the cleanup of the resource variable itself.
+
+    client, err := spanner.NewClient(ctx, db)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+    m["k"] = client
+`,
+	"unknown-gcp-package": `unknown-gcp-package: an imported GCP package has no service rule
+
+The package is under cloud.google.com/go but no rule in the configuration
+matches it, so resources it creates are not checked for leaks. Protobuf
+message packages (*pb) and packages without closable resources are not
+reported. Enabled with -gcpwarn-unknown; informational only.
+
+Fix: add a service rule for the package to the configuration.
+
+    services:
+      - service_name: datastore
+        package_path: cloud.google.com/go/datastore
+        creation_functions:
+          - NewClient
+        cleanup_methods:
+          - method: Close
+            required: true
+`,
+	"generated-code-leak": `generated-code-leak: GCP resource in a generated file is never released
+
+The file starts with the standard "// Code generated ... DO NOT EDIT."
+header, and generated_file_policy: warn reports leaks there as warnings.
+Generated mocks and fakes often create clients that need no cleanup; set
+generated_file_policy: skip to stop reporting them while the files are
+still parsed. Otherwise fix the generator or its template, since edits to
+the generated file are overwritten.
+
+    generated_file_policy: skip
+`,
+	"init-resource-leak": `init-resource-leak: GCP resource created in init() is never released
+
+Resources created in init() usually live for the whole process, so this is
+reported as a warning (analyze_init: warn). If the resource really is meant
+to be package-lifetime, set analyze_init: skip in the configuration file.
+Otherwise move creation out of init() and release it explicitly.
+
+    var client *spanner.Client
+
+    func setup(ctx context.Context) (func(), error) {
+        c, err := spanner.NewClient(ctx, db)
+        if err != nil {
+            return nil, err
+        }
+        client = c
+        return func() { client.Close() }, nil
+    }
+`,
+	"analysis-timeout": `analysis-timeout: analysis stopped at the -gcptimeout limit
+
+The package took longer to analyze than the -gcptimeout value, so the rest
+of it was skipped and may contain unreported issues. This usually happens
+with large generated files.
+
+Fix: raise the limit (e.g. -gcptimeout=2m), disable it with -gcptimeout=0,
+or exclude generated packages with package_exceptions.
+`,
+	"duplicate-cancel": `duplicate-cancel: cancel function deferred more than once
+
+The same cancel function is deferred twice. The second call is a no-op, but
+it often means a refactor left a stale defer behind or that the wrong
+variable is being cancelled.
+
+Fix: keep a single defer per cancel function.
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+`,
+	"context-not-propagated": `context-not-propagated: derived context is not passed downstream
+
+The context returned by WithCancel, WithTimeout or WithDeadline is never
+passed on, while its parent is. Calls made with the parent ignore the
+timeout and cancellation, so deferring cancel has no effect on them.
+Enabled with -gcpcontext-propagation.
+
+Fix: pass the derived context instead of the parent.
+
+    ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+    defer cancel()
+    return doWork(ctx)
+`,
+	"afterfunc-not-stopped": `afterfunc-not-stopped: stop function of context.AfterFunc is not used
+
+context.AfterFunc(ctx, f) arranges for f to run in its own goroutine once
+ctx is done, and returns a stop function. If f is no longer needed, calling
+stop removes the registration; otherwise it stays attached to ctx (and f
+still runs) until ctx is cancelled, which for long-lived contexts may be
+never. Enabled with -gcpafterfunc-stop.
+
+Fix: keep the stop function and call it once f is no longer needed.
+
+    stop := context.AfterFunc(ctx, func() { conn.Close() })
+    defer stop()
+`,
+	"finalizer-cleanup": `finalizer-cleanup: resource is released only by a finalizer
+
+runtime.SetFinalizer runs at an unspecified time after the object becomes
+unreachable, and may never run before the program exits. Connections and
+goroutines held by the resource stay open until then. Enabled with
+-gcpfinalizer, which also reports finalizer-registered resources that are
+returned or stored and would otherwise be treated as escaping.
+
+Fix: release the resource explicitly and drop the finalizer.
+
+    client, err := spanner.NewClient(ctx, db)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+`,
+	"cleanup-order": `cleanup-order: a resource is released before the resources derived from it
+
+Deferred calls run in last-in, first-out order. When a transaction is
+obtained from a client and an iterator from the transaction, the defers
+must be written client first and iterator last, so that the iterator is
+stopped before the transaction and the client are closed. Enabled with
+-gcpcleanup-order.
+
+Fix: write the defer for the resource you created first, first.
+
+    client, err := spanner.NewClient(ctx, db)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+    txn := client.ReadOnlyTransaction()
+    defer txn.Close()
+    iter := txn.Query(ctx, stmt)
+    defer iter.Stop()
+`,
+	"message-not-acked": `message-not-acked: a Pub/Sub message is neither acked nor nacked
+
+Every message delivered to a Receive handler must be acknowledged with
+Ack or rejected with Nack. A message that is neither keeps its lease
+until the ack deadline expires and is then redelivered, over and over,
+while holding flow-control quota. Passing the message to another
+function is treated as handing it off. Enabled with -gcppubsub-ack.
+
+Fix: Ack or Nack on every path out of the handler.
+
+    err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+        if err := process(ctx, msg.Data); err != nil {
+            msg.Nack()
+            return
+        }
+        msg.Ack()
+    })
+`,
+}
+
+// Explain returns the explanation for the given rule ID
+func Explain(ruleID string) (string, bool) {
+	explanation, ok := ruleExplanations[ruleID]
+	return explanation, ok
+}
+
+// ExplainedRules returns the sorted list of rule IDs that have an explanation
+func ExplainedRules() []string {
+	rules := make([]string, 0, len(ruleExplanations))
+	for rule := range ruleExplanations {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
@@ -12,8 +12,33 @@ package messages
 
 const (
 	// Diagnostic Messages - used in analyzer package for issue reporting
-	MissingResourceCleanup = "GCP resource client '%s' missing cleanup method (%s)"
-	MissingContextCancel   = "Context.WithCancel missing cancel function call '%s'"
+	MissingResourceCleanup  = "GCP resource client '%s' missing cleanup method (%s)"
+	MissingContextCancel    = "Context.WithCancel missing cancel function call '%s'"
+	CleanupErrorIgnored     = "error of deferred %s.%s() is discarded; check it because the cleanup error is significant for this resource"
+	CreationErrorIgnored    = "creation error ignored; resource may be nil ('%s' is used without checking the error returned by %s)"
+	UnusedResource          = "resource created but never used or closed (GCP resource '%s' is neither used nor released by %s)"
+	ExitSkipsDefer          = "deferred cleanup will not run due to %s ('%s' defers %s)"
+	ExitSkipsDeferTestMain  = "deferred cleanup will not run due to %s in TestMain; call %s.%s() explicitly before %s"
+	ConditionalDefer        = "%s.%s() is deferred only when '%s' holds; it is not released on the other paths"
+	ConditionalDeferCall    = "%s.%s() is called in the deferred closure only when '%s' holds; it is not released on the other paths"
+	PanicSkipsCleanup       = "direct cleanup may be skipped by a panic in %s; use defer %s.%s()"
+	FinalizerCleanup        = "relying on finalizer for cleanup is unreliable; prefer explicit %s"
+	ReassignedElement       = "deferred cleanup closes a reassigned element ('%s' is reassigned before %s is evaluated, so the resource created first is not released)"
+	ReassignedHeldElement   = "deferred cleanup closes a reassigned element ('%s' stored in '%s' is reassigned before %s is evaluated, so '%s' is not released)"
+	LoopOverwrite           = "GCP resource '%s' is reassigned in a loop but its cleanup (%s) is deferred outside the loop; only the last resource is released"
+	DeferInLoop             = "GCP resource '%s' is created in a loop and its cleanup (%s) is deferred in the loop, so it accumulates until the function returns; release it per iteration or move the loop body into a function"
+	CleanupOrder            = "defer %s runs before defer %s because it is written later; '%s' depends on '%s', so write the defer of '%s' first"
+	ResourceSliceNotClosed  = "slice of resources created; ensure each is closed ('%s': for _, c := range %s { defer c.%s() })"
+	StructResourceNotClosed = "struct %s holds resource '%s' but has no method closing it (%s.%s())"
+	DuplicateCancelDefer    = "cancel deferred multiple times"
+	ContextNotPropagated    = "derived context not propagated; timeout ineffective"
+	AfterFuncStopDiscarded  = "context.AfterFunc stop function is discarded"
+	AfterFuncStopNotCalled  = "context.AfterFunc stop function is never called"
+	MessageNotAcked         = "message neither acked nor nacked on this path"
+	UnknownGCPPackage       = "no service rule matches %s; add a rule to services in the configuration file to check its cleanup"
+	AnalysisTimeout         = "analysis time limit (%s) exceeded; skipped the rest of package %s"
+	InitLeakSuffix          = " (created in init function)"
+	GeneratedLeakSuffix     = " (in generated code)"
 
 	// Configuration Errors - used in config package for setup validation (lowercase for Go error convention)
 	ConfigFileEmpty              = "configuration file path is empty"
@@ -21,6 +46,10 @@ const (
 	ConfigYAMLParseFailed        = "failed to parse YAML configuration: %w"
 	DefaultConfigLoadFailed      = "failed to load default configuration file: %w"
 	DefaultConfigYAMLParseFailed = "failed to parse default YAML configuration: %w"
+	RemoteConfigFetchFailed      = "failed to fetch remote configuration %s: %w"
+	RemoteConfigUnexpectedStatus = "failed to fetch remote configuration %s: unexpected status %s"
+	ConfigYAMLMarshalFailed      = "failed to marshal config to YAML: %w"
+	MigratedConfigWriteFailed    = "failed to write migrated config: %w"
 
 	// Validation Errors - used for data structure validation (lowercase for Go error convention)
 	ServicesListEmpty            = "services definition is empty"
@@ -29,9 +58,26 @@ const (
 	ServiceCreationFuncsEmpty    = "service[%d](%s): creation functions not defined"
 	ServiceCleanupMethodsEmpty   = "service[%d](%s): cleanup methods not defined"
 	CleanupMethodNameEmpty       = "service[%d](%s): cleanup method[%d] method name is empty"
+	InvalidServiceLifetime       = "service[%d](%s): invalid lifetime: %s (valid values: %v)"
+	InvalidServiceType           = "service[%d](%s): invalid service_type: %s (valid values: %v)"
 	PackageExceptionNameEmpty    = "package exception[%d]: exception name is empty"
 	PackageExceptionPatternEmpty = "package exception[%d](%s): pattern is empty"
 	InvalidExceptionType         = "package exception[%d](%s): invalid condition type: %s (valid types: %v)"
+	InvalidAnalyzeInit           = "analyze_init: invalid value: %s (valid values: %v)"
+	InvalidGeneratedFilePolicy   = "generated_file_policy: invalid value: %s (valid values: %v)"
+	ClosureHelperNameEmpty       = "closure managed helper[%d]: function name is empty"
+	ClosureHelperParamNegative   = "closure managed helper[%d](%s): param must not be negative: %d"
+	PackageAliasServiceUnknown   = "package_path_aliases[%s]: unknown service %q"
+	UnsupportedSchemaVersion     = "schema_version: unsupported version %d (latest supported: %d)"
+	NegativeSchemaVersion        = "schema_version: must not be negative: %d"
+	RuleSnippetEmpty             = "rule snippet defines no services or package exceptions"
+	UnknownIncludedService       = "-gcpinclude: unknown service %q (known services: %s)"
+	InvalidDiagnosticPosition    = "-gcpdiagpos: invalid value: %s (valid values: %v)"
+
+	// Lint Warnings - used by -gcpconfig-lint for rules that are valid but never applied
+	DuplicateServiceName        = "service[%d](%s): service name is already defined by service[%d]; this rule is never applied"
+	DuplicateServicePackagePath = "service[%d](%s): package path %s is already used by service[%d](%s); this rule is never applied"
+	ShadowedPackageException    = "package exception[%d](%s): pattern %q is shadowed by package exception[%d](%s) pattern %q; this exception is never applied"
 
 	// Type Validation Errors - used in analyzer/types.go (lowercase for Go error convention)
 	VariableCannotBeNil          = "variable cannot be nil"
@@ -47,6 +93,7 @@ const (
 	ToolDescription      = "Detects missing Close/Stop/Cancel calls for GCP resource clients."
 	UsageExamples        = "Usage Examples"
 	RecommendedPractices = "Best Practices"
+	UnknownRuleID        = "Unknown rule ID: %q (known rules: %s)"
 
 	// Suggested Fix Messages - used for automated fix suggestions
 	AddDeferStatement  = "Add defer %s()"
@@ -17,6 +17,31 @@ func TestMessageConstantsNotEmpty(t *testing.T) {
 		// Diagnostic Messages
 		{"MissingResourceCleanup", MissingResourceCleanup},
 		{"MissingContextCancel", MissingContextCancel},
+		{"CleanupErrorIgnored", CleanupErrorIgnored},
+		{"CreationErrorIgnored", CreationErrorIgnored},
+		{"UnusedResource", UnusedResource},
+		{"ExitSkipsDefer", ExitSkipsDefer},
+		{"ExitSkipsDeferTestMain", ExitSkipsDeferTestMain},
+		{"ConditionalDefer", ConditionalDefer},
+		{"ConditionalDeferCall", ConditionalDeferCall},
+		{"PanicSkipsCleanup", PanicSkipsCleanup},
+		{"FinalizerCleanup", FinalizerCleanup},
+		{"ReassignedElement", ReassignedElement},
+		{"ReassignedHeldElement", ReassignedHeldElement},
+		{"LoopOverwrite", LoopOverwrite},
+		{"DeferInLoop", DeferInLoop},
+		{"CleanupOrder", CleanupOrder},
+		{"ResourceSliceNotClosed", ResourceSliceNotClosed},
+		{"StructResourceNotClosed", StructResourceNotClosed},
+		{"DuplicateCancelDefer", DuplicateCancelDefer},
+		{"ContextNotPropagated", ContextNotPropagated},
+		{"AfterFuncStopDiscarded", AfterFuncStopDiscarded},
+		{"AfterFuncStopNotCalled", AfterFuncStopNotCalled},
+		{"MessageNotAcked", MessageNotAcked},
+		{"UnknownGCPPackage", UnknownGCPPackage},
+		{"AnalysisTimeout", AnalysisTimeout},
+		{"InitLeakSuffix", InitLeakSuffix},
+		{"GeneratedLeakSuffix", GeneratedLeakSuffix},
 
 		// Configuration Errors
 		{"ConfigFileEmpty", ConfigFileEmpty},
@@ -24,6 +49,19 @@ func TestMessageConstantsNotEmpty(t *testing.T) {
 		{"ConfigYAMLParseFailed", ConfigYAMLParseFailed},
 		{"DefaultConfigLoadFailed", DefaultConfigLoadFailed},
 		{"DefaultConfigYAMLParseFailed", DefaultConfigYAMLParseFailed},
+		{"RemoteConfigFetchFailed", RemoteConfigFetchFailed},
+		{"RemoteConfigUnexpectedStatus", RemoteConfigUnexpectedStatus},
+		{"ConfigYAMLMarshalFailed", ConfigYAMLMarshalFailed},
+		{"MigratedConfigWriteFailed", MigratedConfigWriteFailed},
+		{"ClosureHelperNameEmpty", ClosureHelperNameEmpty},
+		{"ClosureHelperParamNegative", ClosureHelperParamNegative},
+		{"UnknownIncludedService", UnknownIncludedService},
+		{"InvalidDiagnosticPosition", InvalidDiagnosticPosition},
+
+		// Lint Warnings
+		{"DuplicateServiceName", DuplicateServiceName},
+		{"DuplicateServicePackagePath", DuplicateServicePackagePath},
+		{"ShadowedPackageException", ShadowedPackageException},
 
 		// Validation Errors
 		{"ServicesListEmpty", ServicesListEmpty},
@@ -32,9 +70,16 @@ func TestMessageConstantsNotEmpty(t *testing.T) {
 		{"ServiceCreationFuncsEmpty", ServiceCreationFuncsEmpty},
 		{"ServiceCleanupMethodsEmpty", ServiceCleanupMethodsEmpty},
 		{"CleanupMethodNameEmpty", CleanupMethodNameEmpty},
+		{"InvalidServiceLifetime", InvalidServiceLifetime},
 		{"PackageExceptionNameEmpty", PackageExceptionNameEmpty},
 		{"PackageExceptionPatternEmpty", PackageExceptionPatternEmpty},
 		{"InvalidExceptionType", InvalidExceptionType},
+		{"InvalidAnalyzeInit", InvalidAnalyzeInit},
+		{"InvalidGeneratedFilePolicy", InvalidGeneratedFilePolicy},
+		{"PackageAliasServiceUnknown", PackageAliasServiceUnknown},
+		{"UnsupportedSchemaVersion", UnsupportedSchemaVersion},
+		{"NegativeSchemaVersion", NegativeSchemaVersion},
+		{"RuleSnippetEmpty", RuleSnippetEmpty},
 
 		// Type Validation Errors
 		{"VariableCannotBeNil", VariableCannotBeNil},
@@ -50,6 +95,7 @@ func TestMessageConstantsNotEmpty(t *testing.T) {
 		{"ToolDescription", ToolDescription},
 		{"UsageExamples", UsageExamples},
 		{"RecommendedPractices", RecommendedPractices},
+		{"UnknownRuleID", UnknownRuleID},
 
 		// Suggested Fix Messages
 		{"AddDeferStatement", AddDeferStatement},
@@ -701,6 +747,7 @@ func getAllMessageConstants() map[string]string {
 		"ToolDescription":              ToolDescription,
 		"UsageExamples":                UsageExamples,
 		"RecommendedPractices":         RecommendedPractices,
+		"UnknownRuleID":                UnknownRuleID,
 		"AddDeferStatement":            AddDeferStatement,
 		"AddDeferMethodCall":           AddDeferMethodCall,
 	}
@@ -721,3 +768,32 @@ func countPlaceholders(message string) []string {
 	placeholderPattern := regexp.MustCompile(`%[a-zA-Z]`)
 	return placeholderPattern.FindAllString(message, -1)
 }
+
+// TestRuleExplanations verifies every explained rule has English guidance headed by its rule ID
+func TestRuleExplanations(t *testing.T) {
+	japanesePattern := regexp.MustCompile("[\u3040-\u309F\u30A0-\u30FF\u4E00-\u9FAF]")
+
+	rules := ExplainedRules()
+	if len(rules) == 0 {
+		t.Fatal("ExplainedRules should not be empty")
+	}
+
+	for _, rule := range rules {
+		t.Run(rule, func(t *testing.T) {
+			explanation, ok := Explain(rule)
+			if !ok || strings.TrimSpace(explanation) == "" {
+				t.Fatalf("Explain(%q) should return a non-empty explanation", rule)
+			}
+			if !strings.HasPrefix(explanation, rule+":") {
+				t.Errorf("Explanation should start with the rule ID %q, got: %s", rule, explanation)
+			}
+			if japanesePattern.MatchString(explanation) {
+				t.Errorf("Explanation for %s contains Japanese characters", rule)
+			}
+		})
+	}
+
+	if _, ok := Explain("no-such-rule"); ok {
+		t.Error("Explain should report unknown rule IDs")
+	}
+}